@@ -3,11 +3,14 @@ module github.com/librun/ha-backup-tool
 go 1.25.0
 
 require (
+	github.com/aymanbagabas/go-udiff v0.4.1
 	github.com/hashicorp/go-version v1.9.0
 	github.com/minio/blake2b-simd v0.0.0-20160723061019-3f5f724cb5b1
 	github.com/openziti/secretstream v0.1.49
 	github.com/urfave/cli/v3 v3.8.0
+	go.etcd.io/bbolt v1.4.3
+	go.yaml.in/yaml/v3 v3.0.4
 	golang.org/x/crypto v0.49.0
+	golang.org/x/sys v0.42.0
+	golang.org/x/term v0.41.0
 )
-
-require golang.org/x/sys v0.42.0 // indirect
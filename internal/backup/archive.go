@@ -0,0 +1,241 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/librun/ha-backup-tool/internal/decryptor"
+	"github.com/librun/ha-backup-tool/internal/entity"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+	"github.com/librun/ha-backup-tool/internal/key"
+)
+
+const (
+	ExtTarGz = ".tar.gz"
+)
+
+var (
+	ErrKeyNotMatch = errors.New("no key opens archive")
+)
+
+// Backup - opened backup tar, archives inside are read from backup file without extract.
+type Backup struct {
+	Path     string
+	JSON     *entity.HomeAssistantBackup
+	Archives []*Archive
+	f        *os.File
+}
+
+// Archive - archive inside backup, e.g. homeassistant.tar.gz.
+type Archive struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+	offset  int64
+	f       *os.File
+}
+
+// ArchiveReader - reader of files in archive, archive is decrypted and decompressed on read.
+type ArchiveReader struct {
+	*tar.Reader
+	Key key.Key
+	r   io.Reader
+	rc  io.ReadCloser
+}
+
+// Open - open backup and find backup.json and archives in it.
+func Open(p string) (*Backup, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+
+	b := Backup{Path: p, f: f}
+	if err = b.scan(); err != nil {
+		return nil, errors.Join(err, f.Close())
+	}
+
+	if b.JSON == nil {
+		return nil, errors.Join(exitcode.Wrap(exitcode.Corrupt, ErrJSONNotFound), f.Close())
+	}
+
+	return &b, nil
+}
+
+// scan - read headers of backup tar, content of archives is skipped by seek.
+func (b *Backup) scan() error {
+	tr := tar.NewReader(b.f)
+
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return exitcode.Wrap(exitcode.Corrupt, err)
+		}
+
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		n := path.Clean(h.Name)
+
+		if n == JSONName {
+			var e entity.HomeAssistantBackup
+			if err = json.NewDecoder(io.LimitReader(tr, maxJSONSize)).Decode(&e); err != nil {
+				return exitcode.Wrap(exitcode.Corrupt, fmt.Errorf("%w: %w", ErrJSONNotValid, err))
+			}
+
+			b.JSON = &e
+
+			continue
+		}
+
+		if !strings.HasSuffix(n, ExtTar) && !strings.HasSuffix(n, ExtTarGz) {
+			continue
+		}
+
+		// tar reader reads only headers, so file is at start of content
+		off, err := b.f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+
+		b.Archives = append(b.Archives, &Archive{Name: n, Size: h.Size, ModTime: h.ModTime, offset: off, f: b.f})
+	}
+}
+
+// Close - close backup file.
+func (b *Backup) Close() error {
+	return b.f.Close()
+}
+
+// Decryptor - decryptor of protected archives, not auto decryptor d is used as is.
+func (b *Backup) Decryptor(d decryptor.Decryptor) (decryptor.Decryptor, error) {
+	return decryptor.ParseFromBackupJSON(b.JSON, d)
+}
+
+// BaseName - name of archive without extension, e.g. homeassistant.
+func (a *Archive) BaseName() string {
+	n, _ := strings.CutSuffix(a.Name, ExtTarGz)
+	n, _ = strings.CutSuffix(n, ExtTar)
+
+	return n
+}
+
+// Reader - reader of raw content of archive, readers are independent.
+func (a *Archive) Reader() *io.SectionReader {
+	return io.NewSectionReader(a.f, a.offset, a.Size)
+}
+
+// OpenArchive - open archive for read files, for protected backup key of backup instance is found in keys.
+func (b *Backup) OpenArchive(a *Archive, ks *key.Storage, d decryptor.Decryptor) (*ArchiveReader, error) {
+	ar := ArchiveReader{rc: io.NopCloser(a.Reader())}
+
+	if b.JSON.Protected {
+		decr, err := b.Decryptor(d)
+		if err != nil {
+			return nil, exitcode.Wrap(exitcode.UnsupportedCrypto, err)
+		}
+
+		open := func() (io.ReadCloser, error) { return io.NopCloser(a.Reader()), nil }
+		if ar.Key, err = FindKey(ks, b.JSON.Extra.InstanceID, decr, open, nil); err != nil {
+			return nil, err
+		}
+
+		if ar.rc, err = decryptor.New(a.Reader(), decr, ar.Key.Value, ks); err != nil {
+			return nil, err
+		}
+	}
+
+	var r io.Reader = ar.rc
+
+	if strings.HasSuffix(a.Name, ExtTarGz) {
+		gr, err := gzip.NewReader(ar.rc)
+		if err != nil {
+			if b.JSON.Protected && errors.Is(err, gzip.ErrHeader) {
+				err = exitcode.Wrap(exitcode.WrongKey, err)
+			}
+
+			return nil, errors.Join(err, ar.rc.Close())
+		}
+
+		// SecureTar v2 has padding after gzip, it is not next gzip stream
+		gr.Multistream(false)
+		r = gr
+	}
+
+	ar.r = r
+	ar.Reader = tar.NewReader(r)
+
+	return &ar, nil
+}
+
+// FindKey - find key which opens protected archive, key of backup instance from key store is tried first,
+// then last used key. Single key is not checked, wrong key is found on read. Archive is opened from start
+// for every tried key, logger can be nil.
+func FindKey(ks *key.Storage, instanceID string, decr decryptor.Decryptor, open func() (io.ReadCloser, error),
+	log *slog.Logger) (key.Key, error) {
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
+
+	kl, err := ks.KeysFor(instanceID)
+	if err != nil {
+		return key.Key{}, err
+	}
+
+	if len(kl) == 1 {
+		return kl[0], nil
+	}
+
+	for _, k := range kl {
+		err = probeKey(open, decr, k.Value, ks)
+		if err == nil {
+			ks.Use(k)
+			log.Info("Archive opened by key", "key", k.Source)
+
+			return k, nil
+		}
+
+		if exitcode.Get(err) != exitcode.WrongKey {
+			return key.Key{}, err
+		}
+
+		log.Debug("Key not opens archive", "key", k.Source)
+	}
+
+	return key.Key{}, exitcode.Wrap(exitcode.WrongKey, fmt.Errorf("%w: tried %d keys", ErrKeyNotMatch, len(kl)))
+}
+
+// probeKey - check key by start of archive.
+func probeKey(open func() (io.ReadCloser, error), decr decryptor.Decryptor, passwd string,
+	c decryptor.KeyCache) error {
+	r, err := open()
+	if err != nil {
+		return err
+	}
+
+	err = decryptor.Probe(r, decr, passwd, c)
+	if errC := r.Close(); err == nil {
+		err = errC
+	}
+
+	return err
+}
+
+// Close - close decryptor of archive.
+func (r *ArchiveReader) Close() error {
+	return r.rc.Close()
+}
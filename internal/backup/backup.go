@@ -0,0 +1,91 @@
+package backup
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/librun/ha-backup-tool/internal/entity"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+)
+
+const (
+	JSONName = "backup.json"
+	ExtTar   = ".tar"
+
+	maxJSONSize = 16 << 20 // 16MiB
+)
+
+var (
+	ErrJSONNotFound = errors.New("backup not have " + JSONName)
+	ErrJSONNotValid = errors.New("error unmarshal " + JSONName)
+)
+
+// ReadJSON - read backup.json from backup tar without extract other files.
+func ReadJSON(p string) (*entity.HomeAssistantBackup, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// tar reader skips content of other files by seek
+	tr := tar.NewReader(f)
+
+	for {
+		h, errN := tr.Next()
+		if errors.Is(errN, io.EOF) {
+			return nil, exitcode.Wrap(exitcode.Corrupt, ErrJSONNotFound)
+		}
+
+		if errN != nil {
+			return nil, exitcode.Wrap(exitcode.Corrupt, errN)
+		}
+
+		if h.Typeflag != tar.TypeReg || path.Clean(h.Name) != JSONName {
+			continue
+		}
+
+		var e entity.HomeAssistantBackup
+		if err = json.NewDecoder(io.LimitReader(tr, maxJSONSize)).Decode(&e); err != nil {
+			return nil, exitcode.Wrap(exitcode.Corrupt, fmt.Errorf("%w: %w", ErrJSONNotValid, err))
+		}
+
+		return &e, nil
+	}
+}
+
+// Find - find backup tar files in dir and its sub dirs, hidden dirs are skipped.
+func Find(dir string) ([]string, error) {
+	var ps []string
+
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			// staging dirs of extract are hidden
+			if p != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if d.Type().IsRegular() && strings.EqualFold(filepath.Ext(p), ExtTar) {
+			ps = append(ps, p)
+		}
+
+		return nil
+	})
+
+	return ps, err
+}
@@ -0,0 +1,577 @@
+package backup_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/librun/ha-backup-tool/internal/backup"
+	"github.com/librun/ha-backup-tool/internal/decryptor"
+	v2 "github.com/librun/ha-backup-tool/internal/decryptor/v2"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+	"github.com/librun/ha-backup-tool/internal/key"
+)
+
+const testJSON = `{"slug":"abc","name":"Full","type":"full","extra":{"instance_id":"home"},
+"homeassistant":{"version":"2025.5.1"}}`
+
+const testPassword = "XXXX-XXXX-XXXX-XXXX-XXXX-XXXX-XXXX"
+
+// writeTar - write tar file with files by name.
+func writeTar(t *testing.T, p string, files map[string]string) {
+	t.Helper()
+
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = writeTarTo(f, files); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tarGz - make tar.gz with files by name.
+func tarGz(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	var b bytes.Buffer
+
+	gw := gzip.NewWriter(&b)
+	if err := writeTarTo(gw, files); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return b.String()
+}
+
+// encryptTarGz - make tar.gz with files by name encrypted by test password,
+// SecureTar v2 archive is with header as in new backups or without header as in old backups.
+func encryptTarGz(t *testing.T, decr decryptor.Decryptor, header bool, files map[string]string) string {
+	t.Helper()
+
+	plain := tarGz(t, files)
+
+	var (
+		b   bytes.Buffer
+		w   io.WriteCloser
+		err error
+	)
+
+	if decr == decryptor.DecryptorSecureTarV2 {
+		w, err = v2.NewWriter(&b, testPassword, int64(len(plain)), header)
+	} else {
+		w, err = decryptor.Format{Decryptor: decr}.NewWriter(&b, testPassword, int64(len(plain)))
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = w.Write([]byte(plain)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return b.String()
+}
+
+func writeTarTo(w io.Writer, files map[string]string) error {
+	tw := tar.NewWriter(w)
+
+	for _, n := range slices.Sorted(maps.Keys(files)) {
+		if err := tw.WriteHeader(&tar.Header{Name: n, Mode: 0o644, Size: int64(len(files[n]))}); err != nil {
+			return err
+		}
+
+		if _, err := tw.Write([]byte(files[n])); err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+func TestReadJSON(t *testing.T) {
+	dir := t.TempDir()
+
+	p := filepath.Join(dir, "a.tar")
+	writeTar(t, p, map[string]string{"./backup.json": testJSON, "homeassistant.tar.gz": "data"})
+
+	e, err := backup.ReadJSON(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.Slug != "abc" || e.Type != "full" || e.Extra.InstanceID != "home" || e.Homeassistant.Version != "2025.5.1" {
+		t.Errorf("ReadJSON() = %+v", *e)
+	}
+
+	p = filepath.Join(dir, "b.tar")
+	writeTar(t, p, map[string]string{"homeassistant.tar.gz": "data"})
+
+	if _, err = backup.ReadJSON(p); !errors.Is(err, backup.ErrJSONNotFound) {
+		t.Errorf("ReadJSON() without backup.json error = %v, want %v", err, backup.ErrJSONNotFound)
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, p := range []string{"a.tar", "sub/b.TAR", ".staging/c.tar", "d.tar.gz"} {
+		p = filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(p, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := backup.Find(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{filepath.Join(dir, "a.tar"), filepath.Join(dir, "sub", "b.TAR")}
+	if !slices.Equal(got, want) {
+		t.Errorf("Find() = %v, want %v", got, want)
+	}
+}
+
+func TestOpenArchive(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "a.tar")
+	writeTar(t, p, map[string]string{
+		"backup.json":           testJSON,
+		"homeassistant.tar.gz":  tarGz(t, map[string]string{"./data/automations.yaml": "- id: 1", "./data/x.db": "db"}),
+		"core_mosquitto.tar.gz": tarGz(t, map[string]string{"./options.json": "{}"}),
+	})
+
+	b, err := backup.Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if len(b.Archives) != 2 || b.JSON.Slug != "abc" {
+		t.Fatalf("Open() archives = %d, slug = %q", len(b.Archives), b.JSON.Slug)
+	}
+
+	// archives are read in any order, reader of one archive not moves other
+	var got []string
+
+	for _, a := range slices.Backward(b.Archives) {
+		ar, errO := b.OpenArchive(a, key.NewStorage(key.Sources{PasswordFD: key.NoFD}), 0)
+		if errO != nil {
+			t.Fatal(errO)
+		}
+
+		for {
+			h, errN := ar.Next()
+			if errors.Is(errN, io.EOF) {
+				break
+			}
+
+			if errN != nil {
+				t.Fatal(errN)
+			}
+
+			got = append(got, a.FilePath(h.Name))
+		}
+
+		if err = ar.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{"homeassistant/data/automations.yaml", "homeassistant/data/x.db", "core_mosquitto/options.json"}
+	if !slices.Equal(got, want) {
+		t.Errorf("files = %v, want %v", got, want)
+	}
+}
+
+func TestFindKey(t *testing.T) {
+	const wrong = "AAAA-AAAA-AAAA-AAAA-AAAA-AAAA-AAAA"
+
+	b, err := backup.Open("../../test_data/test_protected.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	decr, err := b.Decryptor(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	open := func() (io.ReadCloser, error) { return io.NopCloser(b.Archives[0].Reader()), nil }
+
+	tests := []struct {
+		name      string
+		passwords []string
+		want      string
+		code      exitcode.Code
+	}{
+		{name: "valid key after wrong", passwords: []string{wrong, testPassword}, want: testPassword},
+		{name: "all keys wrong", passwords: []string{wrong, "BBBB-BBBB-BBBB-BBBB-BBBB-BBBB-BBBB"},
+			code: exitcode.WrongKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ks := key.NewStorage(key.Sources{Password: tt.passwords, PasswordFD: key.NoFD})
+
+			k, errF := backup.FindKey(ks, b.JSON.Extra.InstanceID, decr, open, nil)
+			if exitcode.Get(errF) != tt.code {
+				t.Fatalf("FindKey() error = %v, want code %d", errF, tt.code)
+			}
+
+			if k.Value != tt.want {
+				t.Errorf("FindKey() key = %q, want %q", k.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "a.tar")
+	writeTar(t, p, map[string]string{
+		"backup.json":          testJSON,
+		"homeassistant.tar.gz": tarGz(t, map[string]string{"./data/automations.yaml": "- id: 1"}),
+	})
+
+	b, err := backup.Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		maxSize int64
+		want    string
+		wantErr error
+	}{
+		{name: "found", path: "homeassistant/data/automations.yaml", want: "- id: 1"},
+		{name: "not found", path: "homeassistant/data/scripts.yaml", wantErr: backup.ErrFileNotFound},
+		{name: "archive not found", path: "core_ssh/options.json", wantErr: backup.ErrFileNotFound},
+		{name: "too big", path: "homeassistant/data/automations.yaml", maxSize: 3, wantErr: backup.ErrFileTooBig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _, errR := b.ReadFile(tt.path, key.NewStorage(key.Sources{PasswordFD: key.NoFD}), 0, tt.maxSize)
+			if !errors.Is(errR, tt.wantErr) {
+				t.Fatalf("ReadFile() error = %v, want %v", errR, tt.wantErr)
+			}
+
+			if string(c) != tt.want {
+				t.Errorf("ReadFile() = %q, want %q", c, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	gz := tarGz(t, map[string]string{"./data/automations.yaml": "- id: 1"})
+	// byte of crc32 in trailer of gzip
+	bad := []byte(gz)
+	bad[len(bad)-5] ^= 0xff
+
+	tests := []struct {
+		name    string
+		archive string
+		wantErr error
+	}{
+		{name: "valid", archive: gz},
+		{name: "checksum not valid", archive: string(bad), wantErr: gzip.ErrChecksum},
+		{name: "truncated", archive: gz[:len(gz)/2], wantErr: io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := filepath.Join(t.TempDir(), "a.tar")
+			writeTar(t, p, map[string]string{"backup.json": testJSON, "homeassistant.tar.gz": tt.archive})
+
+			b, err := backup.Open(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer b.Close()
+
+			err = b.Verify(key.NewStorage(key.Sources{PasswordFD: key.NoFD}), 0)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerify_SecureTarV2Padding(t *testing.T) {
+	// PKCS7 padding is left after gzip stream by SecureTar v2 reader, it must not be read as next gzip stream
+	p := filepath.Join(t.TempDir(), "a.tar")
+	writeTar(t, p, map[string]string{
+		"backup.json": `{"slug":"abc","name":"Full","type":"full","protected":true,"crypto":"aes128",
+"supervisor_version":"2025.05.1","extra":{"instance_id":"home"},"homeassistant":{"version":"2025.5.1"}}`,
+		"homeassistant.tar.gz": encryptTarGz(t, decryptor.DecryptorSecureTarV2, true,
+			map[string]string{"./data/automations.yaml": "- id: 1"}),
+	})
+
+	b, err := backup.Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	ks := key.NewStorage(key.Sources{Password: []string{testPassword}, PasswordFD: key.NoFD})
+	if err = b.Verify(ks, 0); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	c, _, err := b.ReadFile("homeassistant/data/automations.yaml", ks, 0, 0)
+	if err != nil || string(c) != "- id: 1" {
+		t.Errorf("ReadFile() = %q, %v", c, err)
+	}
+}
+
+func TestRepack(t *testing.T) {
+	tests := []struct {
+		name       string
+		decr       decryptor.Decryptor
+		header     bool
+		supervisor string
+		core       string
+	}{
+		{name: "SecureTar v2 without header", decr: decryptor.DecryptorSecureTarV2, supervisor: "2025.05.1",
+			core: "2025.5.1"},
+		{name: "SecureTar v2", decr: decryptor.DecryptorSecureTarV2, header: true, supervisor: "2025.05.1",
+			core: "2025.5.1"},
+		{name: "SecureTar v3", decr: decryptor.DecryptorSecureTarV3, supervisor: "2026.3.1", core: "2026.3.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepack(t, tt.decr, tt.header, fmt.Sprintf(`{"slug":"abc","name":"Full","type":"full","protected":true,
+"crypto":"aes128","supervisor_version":%q,"extra":{"instance_id":"home","custom":1},"folders":["share"],
+"homeassistant":{"version":%q,"exclude_database":false,"size":1.5},
+"addons":[{"slug":"core_ssh","name":"Terminal & SSH","version":"9.0"},{"slug":"core_mqtt","version":"6.4"}]}`,
+				tt.supervisor, tt.core))
+		})
+	}
+}
+
+func TestRepack_HardLinks(t *testing.T) {
+	b, err := backup.Open("../../test_data/test_unprotected_with_links.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	p, err := backup.NewPattern("test/test2.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	op := filepath.Join(dir, "b.tar")
+
+	f, err := os.Create(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks := key.NewStorage(key.Sources{PasswordFD: key.NoFD})
+
+	res, err := b.Repack(f, &backup.Repack{Files: []*backup.Pattern{p}, TempDir: dir}, ks, 0)
+	if errC := f.Close(); err == nil {
+		err = errC
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// hard links to removed file and to removed link are removed with it
+	want := []string{"test/test2.txt", "test/test2-hard-link.txt", "test/a-test-hard-link-to-test2-hard-link.txt"}
+	if !slices.Equal(res.Files, want) {
+		t.Errorf("Repack() files = %v, want %v", res.Files, want)
+	}
+
+	nb, err := backup.Open(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nb.Close()
+
+	ar, err := nb.OpenArchive(nb.Archives[0], ks, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ar.Close()
+
+	for {
+		h, errN := ar.Next()
+		if errors.Is(errN, io.EOF) {
+			break
+		}
+
+		if errN != nil {
+			t.Fatal(errN)
+		}
+
+		if h.Typeflag == tar.TypeLink || slices.Contains(want, nb.Archives[0].FilePath(h.Name)) {
+			t.Errorf("entry %s -> %s is left after repack", h.Name, h.Linkname)
+		}
+	}
+}
+
+func testRepack(t *testing.T, decr decryptor.Decryptor, header bool, protected string) {
+	t.Helper()
+
+	encrypt := func(files map[string]string) string { return encryptTarGz(t, decr, header, files) }
+
+	dir := t.TempDir()
+	p := filepath.Join(dir, "a.tar")
+	writeTar(t, p, map[string]string{
+		"./backup.json": protected,
+		"./homeassistant.tar.gz": encrypt(map[string]string{"./data/automations.yaml": "- id: 1",
+			"./data/home-assistant_v2.db": "db", "./data/home-assistant_v2.db-wal": "wal"}),
+		"./core_ssh.tar.gz":  encrypt(map[string]string{"./options.json": "{}"}),
+		"./core_mqtt.tar.gz": encrypt(map[string]string{"./options.json": "{}"}),
+		"./share.tar.gz":     encrypt(map[string]string{"./file": "x"}),
+	})
+
+	b, err := backup.Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	ks := key.NewStorage(key.Sources{Password: []string{testPassword}, PasswordFD: key.NoFD})
+	op := filepath.Join(dir, "b.tar")
+
+	f, err := os.Create(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := b.Repack(f, &backup.Repack{Addons: []string{"core_ssh"}, Folders: []string{"share"}, Database: true,
+		TempDir: dir}, ks, 0)
+	if errC := f.Close(); err == nil {
+		err = errC
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"core_ssh.tar.gz", "share.tar.gz"}; !slices.Equal(res.Archives, want) {
+		t.Errorf("Repack() archives = %v, want %v", res.Archives, want)
+	}
+
+	want := []string{"homeassistant/data/home-assistant_v2.db", "homeassistant/data/home-assistant_v2.db-wal"}
+	if !slices.Equal(res.Files, want) {
+		t.Errorf("Repack() files = %v, want %v", res.Files, want)
+	}
+
+	nb, err := backup.Open(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nb.Close()
+
+	e := nb.JSON
+	if e.Type != "partial" || !e.Homeassistant.ExcludeDatabase || len(e.Addons) != 1 || e.Addons[0].Slug != "core_mqtt" ||
+		len(e.Folders) != 0 || e.Slug != "abc" || !e.Protected {
+		t.Errorf("backup.json = %+v", e)
+	}
+
+	if err = nb.Verify(ks, 0); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	c, _, err := nb.ReadFile("homeassistant/data/automations.yaml", ks, 0, 0)
+	if err != nil || string(c) != "- id: 1" {
+		t.Errorf("ReadFile() = %q, %v", c, err)
+	}
+
+	if _, _, err = nb.ReadFile("homeassistant/data/home-assistant_v2.db", ks, 0, 0); !errors.Is(err,
+		backup.ErrFileNotFound) {
+		t.Errorf("ReadFile() error = %v, want %v", err, backup.ErrFileNotFound)
+	}
+
+	if decr == decryptor.DecryptorSecureTarV2 {
+		checkV2Layout(t, nb, "homeassistant.tar.gz", header)
+	}
+}
+
+// checkV2Layout - check bytes of rewritten SecureTar v2 archive: magic, big endian plaintext size and zero reserved
+// bytes in header, then salt and ciphertext of plaintext with PKCS7 padding.
+func checkV2Layout(t *testing.T, b *backup.Backup, name string, header bool) {
+	t.Helper()
+
+	i := slices.IndexFunc(b.Archives, func(a *backup.Archive) bool { return a.Name == name })
+	if i < 0 {
+		t.Fatalf("archive %s not found", name)
+	}
+
+	raw, err := io.ReadAll(b.Archives[i].Reader())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := v2.NewReader(bytes.NewReader(raw), testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain, err := io.ReadAll(r)
+	if err != nil || len(plain) == 0 {
+		t.Fatalf("decrypt %s = %d bytes, %v", name, len(plain), err)
+	}
+
+	size := len(plain) - int(plain[len(plain)-1])
+	if !bytes.HasPrefix(plain[:size], []byte{0x1f, 0x8b}) {
+		t.Errorf("plaintext of %s is not gzip", name)
+	}
+
+	// salt, ciphertext and header in new backups
+	want := aes.BlockSize + len(plain)
+	if header {
+		want += 2 * aes.BlockSize
+	}
+
+	switch {
+	case len(raw) != want:
+		t.Errorf("size of %s = %d, want %d", name, len(raw), want)
+	case v2.HasHeader(raw[:aes.BlockSize]) != header:
+		t.Errorf("header of %s = %x, want header %v", name, raw[:aes.BlockSize], header)
+	case header && binary.BigEndian.Uint64(raw[16:24]) != uint64(size):
+		t.Errorf("plaintext size in header of %s = %d, want %d", name, binary.BigEndian.Uint64(raw[16:24]), size)
+	case header && !bytes.Equal(raw[24:32], make([]byte, 8)):
+		t.Errorf("reserved bytes in header of %s = %x", name, raw[24:32])
+	}
+}
@@ -0,0 +1,372 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aymanbagabas/go-udiff"
+
+	"github.com/librun/ha-backup-tool/internal/decryptor"
+	"github.com/librun/ha-backup-tool/internal/entity"
+	"github.com/librun/ha-backup-tool/internal/key"
+)
+
+const (
+	diffTextDir    = "homeassistant/data/"
+	diffStorageDir = "homeassistant/data/.storage/"
+)
+
+// Diff - options of compare of two backups, with metadata only files of archives are not read.
+// With text content of text files not bigger than max size is kept for unified diff.
+type Diff struct {
+	MetadataOnly bool
+	Text         bool
+	MaxFileSize  int64
+}
+
+// DiffResult - differences of two backups, added is only in new backup, removed is only in old backup.
+// Compared is count of archives which are in both backups, failed are archives which are not read.
+type DiffResult struct {
+	Metadata []*DiffField
+	Addons   DiffAddons
+	Folders  DiffList
+	Archives DiffList
+	Files    DiffFiles
+	Compared int
+	Failed   []*DiffArchiveError
+}
+
+// DiffField - changed field of backup.json.
+type DiffField struct {
+	Name string `json:"name"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// DiffAddons - addons added, removed and with changed version or size.
+type DiffAddons struct {
+	Added   []entity.Addon `json:"added"`
+	Removed []entity.Addon `json:"removed"`
+	Changed []*DiffAddon   `json:"changed"`
+}
+
+// DiffAddon - addon with other version or size in new backup, size is in MB as in backup.json.
+type DiffAddon struct {
+	Slug    string  `json:"slug"`
+	Name    string  `json:"name"`
+	Old     string  `json:"old"`
+	New     string  `json:"new"`
+	OldSize float64 `json:"old_size"`
+	NewSize float64 `json:"new_size"`
+}
+
+// DiffList - names added and removed.
+type DiffList struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+// DiffFiles - files of archives which are in both backups.
+type DiffFiles struct {
+	Added   []*DiffFile       `json:"added"`
+	Removed []*DiffFile       `json:"removed"`
+	Changed []*DiffFileChange `json:"changed"`
+}
+
+// DiffFile - file in archive, path starts with name of archive.
+type DiffFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	Hash string `json:"sha256"`
+}
+
+// DiffFileChange - file with other content in new backup.
+type DiffFileChange struct {
+	Path    string `json:"path"`
+	OldSize int64  `json:"old_size"`
+	NewSize int64  `json:"new_size"`
+	OldHash string `json:"old_sha256"`
+	NewHash string `json:"new_sha256"`
+	Diff    string `json:"diff,omitempty"`
+}
+
+// DiffArchiveError - archive of backup which is not compared.
+type DiffArchiveError struct {
+	Backup  *Backup
+	Archive *Archive
+	Err     error
+}
+
+func (e *DiffArchiveError) Error() string {
+	return fmt.Sprintf("%s in %s: %v", e.Archive.Name, e.Backup.Path, e.Err)
+}
+
+func (e *DiffArchiveError) Unwrap() error {
+	return e.Err
+}
+
+// diffEntry - file in archive, content is kept only for text diff.
+type diffEntry struct {
+	size    int64
+	hash    string
+	content []byte
+}
+
+// Diff - compare backup with new backup. Files are compared by size and SHA-256 only in archives which are
+// in both backups, archive which is not read is added to failed and other archives are compared.
+func (b *Backup) Diff(nb *Backup, d *Diff, ks *key.Storage, decr decryptor.Decryptor) (*DiffResult, error) {
+	var (
+		r   = DiffResult{Failed: make([]*DiffArchiveError, 0)}
+		err error
+	)
+
+	if r.Metadata, err = DiffMetadata(b, nb); err != nil {
+		return nil, err
+	}
+
+	r.Addons = DiffAddonList(b.JSON.Addons, nb.JSON.Addons)
+	r.Folders = DiffNames(b.JSON.Folders, nb.JSON.Folders)
+	r.Archives = DiffNames(b.archiveNames(), nb.archiveNames())
+	r.Files = DiffFiles{Added: make([]*DiffFile, 0), Removed: make([]*DiffFile, 0),
+		Changed: make([]*DiffFileChange, 0)}
+
+	if d.MetadataOnly {
+		return &r, nil
+	}
+
+	for _, oa := range b.Archives {
+		i := slices.IndexFunc(nb.Archives, func(a *Archive) bool { return a.Name == oa.Name })
+		if i < 0 {
+			continue
+		}
+
+		r.Compared++
+
+		if errA := r.diffArchive(b, nb, oa, nb.Archives[i], d, ks, decr); errA != nil {
+			r.Failed = append(r.Failed, errA)
+		}
+	}
+
+	slices.SortFunc(r.Files.Added, func(a, b *DiffFile) int { return strings.Compare(a.Path, b.Path) })
+	slices.SortFunc(r.Files.Removed, func(a, b *DiffFile) int { return strings.Compare(a.Path, b.Path) })
+	slices.SortFunc(r.Files.Changed, func(a, b *DiffFileChange) int { return strings.Compare(a.Path, b.Path) })
+
+	return &r, nil
+}
+
+// DiffMetadata - changed fields of backup.json and size of backup file.
+func DiffMetadata(ob, nb *Backup) ([]*DiffField, error) {
+	oi, err := os.Stat(ob.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	ni, err := os.Stat(nb.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	o, n := ob.JSON, nb.JSON
+	fs := []DiffField{
+		{Name: "name", Old: o.Name, New: n.Name},
+		{Name: "date", Old: o.Date.Local().Format(time.DateTime), New: n.Date.Local().Format(time.DateTime)},
+		{Name: "type", Old: o.Type, New: n.Type},
+		{Name: "instance_id", Old: o.Extra.InstanceID, New: n.Extra.InstanceID},
+		{Name: "homeassistant_version", Old: o.Homeassistant.Version, New: n.Homeassistant.Version},
+		{Name: "supervisor_version", Old: o.SupervisorVersion, New: n.SupervisorVersion},
+		{Name: "protected", Old: strconv.FormatBool(o.Protected), New: strconv.FormatBool(n.Protected)},
+		{Name: "crypto", Old: o.Crypto, New: n.Crypto},
+		{Name: "compressed", Old: strconv.FormatBool(o.Compressed), New: strconv.FormatBool(n.Compressed)},
+		{Name: "exclude_database", Old: strconv.FormatBool(o.Homeassistant.ExcludeDatabase),
+			New: strconv.FormatBool(n.Homeassistant.ExcludeDatabase)},
+		{Name: "homeassistant_size", Old: formatSize(o.Homeassistant.Size), New: formatSize(n.Homeassistant.Size)},
+		{Name: "size", Old: strconv.FormatInt(oi.Size(), 10), New: strconv.FormatInt(ni.Size(), 10)},
+	}
+
+	dfs := make([]*DiffField, 0, len(fs))
+
+	for _, f := range fs {
+		if f.Old != f.New {
+			dfs = append(dfs, &f)
+		}
+	}
+
+	return dfs, nil
+}
+
+// DiffAddonList - addons are compared by slug, addon is changed if version or size is other.
+func DiffAddonList(o, n []entity.Addon) DiffAddons {
+	d := DiffAddons{Added: make([]entity.Addon, 0), Removed: make([]entity.Addon, 0), Changed: make([]*DiffAddon, 0)}
+
+	for _, oa := range o {
+		i := slices.IndexFunc(n, func(a entity.Addon) bool { return a.Slug == oa.Slug })
+
+		switch {
+		case i < 0:
+			d.Removed = append(d.Removed, oa)
+		case n[i].Version != oa.Version || n[i].Size != oa.Size:
+			d.Changed = append(d.Changed, &DiffAddon{Slug: oa.Slug, Name: n[i].Name, Old: oa.Version,
+				New: n[i].Version, OldSize: oa.Size, NewSize: n[i].Size})
+		}
+	}
+
+	for _, na := range n {
+		if !slices.ContainsFunc(o, func(a entity.Addon) bool { return a.Slug == na.Slug }) {
+			d.Added = append(d.Added, na)
+		}
+	}
+
+	return d
+}
+
+// DiffNames - names which are only in one list.
+func DiffNames(o, n []string) DiffList {
+	d := DiffList{Added: make([]string, 0), Removed: make([]string, 0)}
+
+	for _, s := range o {
+		if !slices.Contains(n, s) {
+			d.Removed = append(d.Removed, s)
+		}
+	}
+
+	for _, s := range n {
+		if !slices.Contains(o, s) {
+			d.Added = append(d.Added, s)
+		}
+	}
+
+	return d
+}
+
+// DiffText - unified diff of text files, binary files are only reported as different.
+func DiffText(oldLabel, newLabel string, o, n []byte) string {
+	if bytes.IndexByte(o, 0) >= 0 || bytes.IndexByte(n, 0) >= 0 {
+		return fmt.Sprintf("Binary files %s and %s differ\n", oldLabel, newLabel)
+	}
+
+	return udiff.Unified(oldLabel, newLabel, string(o), string(n))
+}
+
+func formatSize(s float64) string {
+	return strconv.FormatFloat(s, 'f', -1, 64)
+}
+
+func (b *Backup) archiveNames() []string {
+	ns := make([]string, 0, len(b.Archives))
+	for _, a := range b.Archives {
+		ns = append(ns, a.Name)
+	}
+
+	return ns
+}
+
+// diffArchive - compare files of archive which is in both backups.
+func (r *DiffResult) diffArchive(ob, nb *Backup, oa, na *Archive, d *Diff, ks *key.Storage,
+	decr decryptor.Decryptor) *DiffArchiveError {
+	of, err := ob.indexArchive(oa, d, ks, decr)
+	if err != nil {
+		return &DiffArchiveError{Backup: ob, Archive: oa, Err: err}
+	}
+
+	nf, err := nb.indexArchive(na, d, ks, decr)
+	if err != nil {
+		return &DiffArchiveError{Backup: nb, Archive: na, Err: err}
+	}
+
+	for p, o := range of {
+		n, ok := nf[p]
+
+		switch {
+		case !ok:
+			r.Files.Removed = append(r.Files.Removed, &DiffFile{Path: p, Size: o.size, Hash: o.hash})
+		case o.hash != n.hash:
+			fc := DiffFileChange{Path: p, OldSize: o.size, NewSize: n.size, OldHash: o.hash, NewHash: n.hash}
+			if o.content != nil && n.content != nil {
+				fc.Diff = DiffText(filepath.Base(ob.Path)+":"+p, filepath.Base(nb.Path)+":"+p, o.content, n.content)
+			}
+
+			r.Files.Changed = append(r.Files.Changed, &fc)
+		}
+	}
+
+	for p, n := range nf {
+		if _, ok := of[p]; !ok {
+			r.Files.Added = append(r.Files.Added, &DiffFile{Path: p, Size: n.size, Hash: n.hash})
+		}
+	}
+
+	return nil
+}
+
+// indexArchive - read files of archive and hash their content.
+func (b *Backup) indexArchive(a *Archive, d *Diff, ks *key.Storage, decr decryptor.Decryptor) (map[string]*diffEntry,
+	error) {
+	ar, err := b.OpenArchive(a, ks, decr)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := make(map[string]*diffEntry)
+
+	for {
+		h, errN := ar.Next()
+		if errors.Is(errN, io.EOF) {
+			break
+		}
+
+		if errN != nil {
+			return nil, errors.Join(errN, ar.Close())
+		}
+
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		fp := a.FilePath(h.Name)
+		e := diffEntry{size: h.Size}
+		s := sha256.New()
+
+		if d.Text && h.Size <= d.MaxFileSize && isTextFile(fp) {
+			if e.content, errN = io.ReadAll(ar); errN == nil {
+				_, errN = s.Write(e.content)
+			}
+		} else {
+			_, errN = io.Copy(s, ar)
+		}
+
+		if errN != nil {
+			return nil, errors.Join(errN, ar.Close())
+		}
+
+		e.hash = hex.EncodeToString(s.Sum(nil))
+		fs[fp] = &e
+	}
+
+	return fs, ar.Close()
+}
+
+// isTextFile - config files of Home Assistant, files in .storage are JSON without extension.
+func isTextFile(fp string) bool {
+	if strings.HasPrefix(fp, diffStorageDir) {
+		return true
+	}
+
+	switch path.Ext(fp) {
+	case ".yaml", ".yml", ".json":
+		return strings.HasPrefix(fp, diffTextDir)
+	}
+
+	return false
+}
@@ -0,0 +1,263 @@
+package backup_test
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/librun/ha-backup-tool/internal/backup"
+	"github.com/librun/ha-backup-tool/internal/entity"
+	"github.com/librun/ha-backup-tool/internal/key"
+)
+
+// openDiffBackup - write backup with archives by name and open it.
+func openDiffBackup(t *testing.T, name, json string, archives map[string]string) *backup.Backup {
+	t.Helper()
+
+	p := filepath.Join(t.TempDir(), name)
+	files := map[string]string{"backup.json": json}
+
+	for n, c := range archives {
+		files[n] = c
+	}
+
+	writeTar(t, p, files)
+
+	b, err := backup.Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = b.Close() })
+
+	return b
+}
+
+func TestDiffMetadata(t *testing.T) {
+	ob := openDiffBackup(t, "old.tar", testJSON, nil)
+	nb := openDiffBackup(t, "new.tar", `{"slug":"abc","name":"Full","type":"partial","extra":{"instance_id":"home"},
+"homeassistant":{"version":"2025.6.0","exclude_database":true}}`, nil)
+
+	got, err := backup.DiffMetadata(ob, nb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []backup.DiffField{
+		{Name: "type", Old: "full", New: "partial"},
+		{Name: "homeassistant_version", Old: "2025.5.1", New: "2025.6.0"},
+		{Name: "exclude_database", Old: "false", New: "true"},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("DiffMetadata() = %v, want %v", got, want)
+	}
+
+	for i, f := range got {
+		if *f != want[i] {
+			t.Errorf("DiffMetadata()[%d] = %+v, want %+v", i, *f, want[i])
+		}
+	}
+}
+
+func TestDiffAddonList(t *testing.T) {
+	ssh := entity.Addon{Slug: "core_ssh", Name: "SSH", Version: "9.0", Size: 1.5}
+
+	tests := []struct {
+		name    string
+		o, n    []entity.Addon
+		added   []string
+		removed []string
+		changed []backup.DiffAddon
+	}{
+		{name: "same", o: []entity.Addon{ssh}, n: []entity.Addon{ssh}},
+		{name: "added", n: []entity.Addon{ssh}, added: []string{"core_ssh"}},
+		{name: "removed", o: []entity.Addon{ssh}, removed: []string{"core_ssh"}},
+		{
+			name: "version changed",
+			o:    []entity.Addon{ssh},
+			n:    []entity.Addon{{Slug: "core_ssh", Name: "SSH", Version: "9.1", Size: 1.5}},
+			changed: []backup.DiffAddon{{Slug: "core_ssh", Name: "SSH", Old: "9.0", New: "9.1", OldSize: 1.5,
+				NewSize: 1.5}},
+		},
+		{
+			name: "size changed",
+			o:    []entity.Addon{ssh},
+			n:    []entity.Addon{{Slug: "core_ssh", Name: "SSH", Version: "9.0", Size: 20.25}},
+			changed: []backup.DiffAddon{{Slug: "core_ssh", Name: "SSH", Old: "9.0", New: "9.0", OldSize: 1.5,
+				NewSize: 20.25}},
+		},
+	}
+
+	slugs := func(as []entity.Addon) []string {
+		s := make([]string, 0, len(as))
+		for _, a := range as {
+			s = append(s, a.Slug)
+		}
+
+		return s
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := backup.DiffAddonList(tt.o, tt.n)
+
+			if got := slugs(d.Added); !slices.Equal(got, tt.added) {
+				t.Errorf("Added = %v, want %v", got, tt.added)
+			}
+
+			if got := slugs(d.Removed); !slices.Equal(got, tt.removed) {
+				t.Errorf("Removed = %v, want %v", got, tt.removed)
+			}
+
+			if len(d.Changed) != len(tt.changed) {
+				t.Fatalf("Changed = %v, want %v", d.Changed, tt.changed)
+			}
+
+			for i, a := range d.Changed {
+				if *a != tt.changed[i] {
+					t.Errorf("Changed[%d] = %+v, want %+v", i, *a, tt.changed[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDiffNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		o, n    []string
+		added   []string
+		removed []string
+	}{
+		{name: "empty"},
+		{name: "same", o: []string{"share", "ssl"}, n: []string{"ssl", "share"}},
+		{name: "changed", o: []string{"share", "ssl"}, n: []string{"ssl", "media"}, added: []string{"media"},
+			removed: []string{"share"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := backup.DiffNames(tt.o, tt.n)
+			if !slices.Equal(d.Added, tt.added) || !slices.Equal(d.Removed, tt.removed) {
+				t.Errorf("DiffNames() = %+v, want added %v removed %v", d, tt.added, tt.removed)
+			}
+		})
+	}
+}
+
+func TestDiffText(t *testing.T) {
+	tests := []struct {
+		name string
+		o, n string
+		want string
+	}{
+		{name: "text", o: "a: 1\n", n: "a: 2\n", want: "-a: 1\n+a: 2\n"},
+		{name: "binary", o: "a\x00", n: "b\x00", want: "Binary files old and new differ\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := backup.DiffText("old", "new", []byte(tt.o), []byte(tt.n)); !strings.Contains(got, tt.want) {
+				t.Errorf("DiffText() = %q, want with %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackup_Diff(t *testing.T) {
+	ob := openDiffBackup(t, "old.tar", testJSON, map[string]string{
+		"homeassistant.tar.gz": tarGz(t, map[string]string{
+			"./data/automations.yaml":      "- id: 1\n",
+			"./data/.storage/core.config":  `{"a":1}`,
+			"./data/home-assistant_v2.db":  "db1",
+			"./data/scripts.yaml":          "x: 1\n",
+			"./data/blueprints/a/old.yaml": "old",
+		}),
+		"share.tar.gz":          tarGz(t, map[string]string{"./file": "x"}),
+		"core_mosquitto.tar.gz": tarGz(t, map[string]string{"./options.json": "{}"}),
+	})
+	nb := openDiffBackup(t, "new.tar", testJSON, map[string]string{
+		"homeassistant.tar.gz": tarGz(t, map[string]string{
+			"./data/automations.yaml":      "- id: 2\n",
+			"./data/.storage/core.config":  `{"a":2}`,
+			"./data/home-assistant_v2.db":  "db2",
+			"./data/scripts.yaml":          "x: 1\n",
+			"./data/blueprints/a/new.yaml": "new",
+		}),
+		"share.tar.gz": "not gzip",
+		"ssl.tar.gz":   tarGz(t, map[string]string{"./cert.pem": "pem"}),
+	})
+
+	changed := []string{"homeassistant/data/.storage/core.config", "homeassistant/data/automations.yaml",
+		"homeassistant/data/home-assistant_v2.db"}
+
+	tests := []struct {
+		name     string
+		diff     backup.Diff
+		compared int
+		changed  []string
+		withDiff []string
+	}{
+		{name: "metadata only", diff: backup.Diff{MetadataOnly: true, Text: true, MaxFileSize: 1 << 20}},
+		{name: "files", diff: backup.Diff{MaxFileSize: 1 << 20}, compared: 2, changed: changed},
+		{
+			name:     "text",
+			diff:     backup.Diff{Text: true, MaxFileSize: 1 << 20},
+			compared: 2,
+			changed:  changed,
+			withDiff: []string{"homeassistant/data/.storage/core.config", "homeassistant/data/automations.yaml"},
+		},
+		{name: "text bigger than max size", diff: backup.Diff{Text: true, MaxFileSize: 1}, compared: 2,
+			changed: changed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ob.Diff(nb, &tt.diff, key.NewStorage(key.Sources{PasswordFD: key.NoFD}), 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(r.Archives.Added, []string{"ssl.tar.gz"}) ||
+				!slices.Equal(r.Archives.Removed, []string{"core_mosquitto.tar.gz"}) {
+				t.Errorf("Archives = %+v", r.Archives)
+			}
+
+			if r.Compared != tt.compared {
+				t.Errorf("Compared = %d, want %d", r.Compared, tt.compared)
+			}
+
+			// archive which is not read is failed, other archives are compared
+			if tt.compared > 0 && (len(r.Failed) != 1 || r.Failed[0].Archive.Name != "share.tar.gz" ||
+				r.Failed[0].Backup != nb) {
+				t.Errorf("Failed = %v, want share.tar.gz of new backup", r.Failed)
+			}
+
+			var got, withDiff []string
+
+			for _, f := range r.Files.Changed {
+				got = append(got, f.Path)
+
+				if f.Diff != "" {
+					withDiff = append(withDiff, f.Path)
+				}
+			}
+
+			if !slices.Equal(got, tt.changed) {
+				t.Errorf("Changed = %v, want %v", got, tt.changed)
+			}
+
+			if !slices.Equal(withDiff, tt.withDiff) {
+				t.Errorf("Changed with diff = %v, want %v", withDiff, tt.withDiff)
+			}
+
+			if tt.compared > 0 && (len(r.Files.Added) != 1 || r.Files.Added[0].Path !=
+				"homeassistant/data/blueprints/a/new.yaml" || len(r.Files.Removed) != 1 ||
+				r.Files.Removed[0].Path != "homeassistant/data/blueprints/a/old.yaml") {
+				t.Errorf("Added = %v, removed = %v", r.Files.Added, r.Files.Removed)
+			}
+		})
+	}
+}
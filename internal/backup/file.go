@@ -0,0 +1,70 @@
+package backup
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/librun/ha-backup-tool/internal/decryptor"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+	"github.com/librun/ha-backup-tool/internal/key"
+)
+
+var (
+	ErrFileNotFound = errors.New("file not found in backup")
+	ErrFileTooBig   = errors.New("file in backup is bigger than max size")
+)
+
+// ReadFile - read content of file in backup by path, e.g. homeassistant/data/automations.yaml.
+// Only archive with name from first dir of path is read.
+func (b *Backup) ReadFile(fp string, ks *key.Storage, d decryptor.Decryptor, maxSize int64) ([]byte, *tar.Header,
+	error) {
+	an, _, _ := strings.Cut(fp, "/")
+
+	for _, a := range b.Archives {
+		if a.BaseName() != an {
+			continue
+		}
+
+		ar, err := b.OpenArchive(a, ks, d)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		c, h, err := readFile(ar, a, fp, maxSize)
+		if errC := ar.Close(); err == nil {
+			err = errC
+		}
+
+		return c, h, err
+	}
+
+	return nil, nil, exitcode.Wrap(exitcode.BadArgs, fmt.Errorf("%w: %s", ErrFileNotFound, fp))
+}
+
+func readFile(ar *ArchiveReader, a *Archive, fp string, maxSize int64) ([]byte, *tar.Header, error) {
+	for {
+		h, err := ar.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, nil, exitcode.Wrap(exitcode.BadArgs, fmt.Errorf("%w: %s", ErrFileNotFound, fp))
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if h.Typeflag != tar.TypeReg || a.FilePath(h.Name) != fp {
+			continue
+		}
+
+		if maxSize > 0 && h.Size > maxSize {
+			return nil, h, exitcode.Wrap(exitcode.BadArgs, fmt.Errorf("%w: %s %d bytes", ErrFileTooBig, fp, h.Size))
+		}
+
+		c, err := io.ReadAll(ar)
+
+		return c, h, err
+	}
+}
@@ -0,0 +1,113 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+var (
+	ErrJSONNotObject = errors.New("json value is not object")
+)
+
+// jsonObject - JSON object with order of keys, fields not known by tool are kept as is.
+type jsonObject []jsonField
+
+type jsonField struct {
+	key   string
+	value json.RawMessage
+}
+
+// UnmarshalJSON - read fields of object in order, null is empty object.
+func (o *jsonObject) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(bytes.TrimSpace(b), []byte("null")) {
+		*o = nil
+
+		return nil
+	}
+
+	d := json.NewDecoder(bytes.NewReader(b))
+
+	if t, err := d.Token(); err != nil || t != json.Delim('{') {
+		return errors.Join(ErrJSONNotObject, err)
+	}
+
+	*o = make(jsonObject, 0)
+
+	for d.More() {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		k, _ := t.(string)
+
+		var v json.RawMessage
+		if err = d.Decode(&v); err != nil {
+			return err
+		}
+
+		*o = append(*o, jsonField{key: k, value: v})
+	}
+
+	_, err := d.Token()
+
+	return err
+}
+
+// MarshalJSON - write fields in order.
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+
+	b.WriteByte('{')
+
+	for i, f := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		k, err := json.Marshal(f.key)
+		if err != nil {
+			return nil, err
+		}
+
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(f.value)
+	}
+
+	b.WriteByte('}')
+
+	return b.Bytes(), nil
+}
+
+// get - decode value of field, missing field is not decoded.
+func (o jsonObject) get(k string, v any) error {
+	for _, f := range o {
+		if f.key == k {
+			return json.Unmarshal(f.value, v)
+		}
+	}
+
+	return nil
+}
+
+// set - replace value of field, new field is added to end.
+func (o *jsonObject) set(k string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	for i, f := range *o {
+		if f.key == k {
+			(*o)[i].value = b
+
+			return nil
+		}
+	}
+
+	*o = append(*o, jsonField{key: k, value: b})
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package backup
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Pattern - glob for path of file in backup, e.g. homeassistant/data/*.yaml.
+// "*" and "?" not match "/", "**" matches any part of path, pattern without "/" matches name of file.
+type Pattern struct {
+	re      *regexp.Regexp
+	archive *regexp.Regexp
+	name    bool
+}
+
+// NewPattern - compile glob.
+func NewPattern(s string) (*Pattern, error) {
+	re, err := compileGlob(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := Pattern{re: re, name: !strings.Contains(s, "/")}
+
+	// first dir of path is name of archive, so not matched archives are not read
+	if a, _, ok := strings.Cut(s, "/"); ok && !strings.Contains(a, "**") {
+		if p.archive, err = compileGlob(a); err != nil {
+			return nil, err
+		}
+	}
+
+	return &p, nil
+}
+
+// compileGlob - convert glob to regexp.
+func compileGlob(s string) (*regexp.Regexp, error) {
+	var b strings.Builder
+
+	b.WriteString("^")
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case s[i] == '*':
+			b.WriteString("[^/]*")
+		case s[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(s[i : i+1]))
+		}
+	}
+
+	b.WriteString("$")
+
+	return regexp.Compile(b.String())
+}
+
+// MatchArchive - check files of archive can be matched by pattern.
+func (p *Pattern) MatchArchive(a *Archive) bool {
+	return p.archive == nil || p.archive.MatchString(a.BaseName())
+}
+
+// Match - check path of file in backup.
+func (p *Pattern) Match(fp string) bool {
+	if p.name {
+		fp = path.Base(fp)
+	}
+
+	return p.re.MatchString(fp)
+}
+
+// FilePath - path of file of archive in backup, name of archive is first dir, e.g. homeassistant/data/x.yaml.
+func (a *Archive) FilePath(name string) string {
+	return path.Join(a.BaseName(), path.Clean("/" + name)[1:])
+}
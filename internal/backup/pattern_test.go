@@ -0,0 +1,36 @@
+package backup_test
+
+import (
+	"testing"
+
+	"github.com/librun/ha-backup-tool/internal/backup"
+)
+
+func TestPattern_Match(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"automations.yaml", "homeassistant/data/automations.yaml", true},
+		{"*.yaml", "homeassistant/data/packages/lights.yaml", true},
+		{"homeassistant/data/*.yaml", "homeassistant/data/automations.yaml", true},
+		{"homeassistant/data/*.yaml", "homeassistant/data/packages/lights.yaml", false},
+		{"homeassistant/**.yaml", "homeassistant/data/packages/lights.yaml", true},
+		{"homeassistant/data/?.db", "homeassistant/data/a.db", true},
+		{"homeassistant/data/a.db", "homeassistant/data/a_db", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			p, err := backup.NewPattern(tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := p.Match(tt.path); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
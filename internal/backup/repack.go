@@ -0,0 +1,431 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/librun/ha-backup-tool/internal/decryptor"
+	"github.com/librun/ha-backup-tool/internal/entity"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+	"github.com/librun/ha-backup-tool/internal/key"
+)
+
+const (
+	// DatabasePattern - recorder database with wal and shm files, they are not in backup with exclude_database.
+	DatabasePattern = "homeassistant/data/home-assistant_v2.db*"
+
+	homeassistantArchive = "homeassistant"
+	typePartial          = "partial"
+	jsonIndent           = "    "
+	bytesInMB            = 1 << 20
+	sizePrecision        = 100
+)
+
+var (
+	ErrAddonNotFound  = errors.New("addon not found in backup")
+	ErrFolderNotFound = errors.New("folder not found in backup")
+)
+
+// Repack - parts of backup which are removed on repack.
+type Repack struct {
+	Addons   []string
+	Folders  []string
+	Files    []*Pattern
+	Database bool
+	TempDir  string
+}
+
+// RepackResult - removed parts of backup, files are with name of archive.
+type RepackResult struct {
+	Archives []string
+	Files    []string
+}
+
+// repackRun - state of repack of one backup.
+type repackRun struct {
+	b       *Backup
+	rp      *Repack
+	ks      *key.Storage
+	d       decryptor.Decryptor
+	files   []*Pattern
+	removed []string
+	sizes   map[string]int64
+	res     *RepackResult
+}
+
+// Repack - write copy of backup without removed addons, folders and files into w.
+// Archives with removed files are written again and encrypted by same key in same SecureTar version,
+// other archives are copied as is. Fields of backup.json not changed by repack are kept.
+func (b *Backup) Repack(w io.Writer, rp *Repack, ks *key.Storage, d decryptor.Decryptor) (*RepackResult, error) {
+	r := repackRun{b: b, rp: rp, ks: ks, d: d, files: rp.Files, sizes: make(map[string]int64),
+		res: &RepackResult{Archives: make([]string, 0), Files: make([]string, 0)}}
+
+	if err := r.check(); err != nil {
+		return nil, err
+	}
+
+	if rp.Database {
+		p, err := NewPattern(DatabasePattern)
+		if err != nil {
+			return nil, err
+		}
+
+		r.files = append(slices.Clip(r.files), p)
+	}
+
+	fi, err := b.f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	tw := tar.NewWriter(w)
+	tr := tar.NewReader(io.NewSectionReader(b.f, 0, fi.Size()))
+
+	var (
+		jh  *tar.Header
+		raw []byte
+	)
+
+	for {
+		h, errN := tr.Next()
+		if errors.Is(errN, io.EOF) {
+			break
+		}
+
+		if errN != nil {
+			return nil, exitcode.Wrap(exitcode.Corrupt, errN)
+		}
+
+		if h.Typeflag == tar.TypeReg && path.Clean(h.Name) == JSONName {
+			// backup.json is written after archives, when sizes of archives are known
+			if raw, err = io.ReadAll(io.LimitReader(tr, maxJSONSize)); err != nil {
+				return nil, err
+			}
+
+			jh = h
+
+			continue
+		}
+
+		if err = r.entry(tw, tr, h); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = r.writeJSON(tw, jh, raw); err != nil {
+		return nil, err
+	}
+
+	return r.res, tw.Close()
+}
+
+// check - removed addons and folders must be in backup.
+func (r *repackRun) check() error {
+	for _, s := range r.rp.Addons {
+		if !slices.ContainsFunc(r.b.JSON.Addons, func(a entity.Addon) bool { return a.Slug == s }) {
+			return exitcode.Wrap(exitcode.BadArgs, fmt.Errorf("%w: %s", ErrAddonNotFound, s))
+		}
+
+		r.removed = append(r.removed, s)
+	}
+
+	for _, f := range r.rp.Folders {
+		if !slices.Contains(r.b.JSON.Folders, f) {
+			return exitcode.Wrap(exitcode.BadArgs, fmt.Errorf("%w: %s", ErrFolderNotFound, f))
+		}
+
+		// archive of folder addons/local is addons_local.tar.gz
+		r.removed = append(r.removed, strings.ReplaceAll(f, "/", "_"))
+	}
+
+	return nil
+}
+
+// entry - copy entry of backup tar, archive is removed, written again without files or copied.
+func (r *repackRun) entry(tw *tar.Writer, tr *tar.Reader, h *tar.Header) error {
+	n := path.Clean(h.Name)
+
+	i := slices.IndexFunc(r.b.Archives, func(a *Archive) bool { return a.Name == n })
+	if h.Typeflag == tar.TypeReg && i >= 0 {
+		a := r.b.Archives[i]
+
+		if slices.Contains(r.removed, a.BaseName()) {
+			r.res.Archives = append(r.res.Archives, a.Name)
+
+			return nil
+		}
+
+		if slices.ContainsFunc(r.files, func(p *Pattern) bool { return p.MatchArchive(a) }) {
+			return r.rewrite(tw, h, a)
+		}
+	}
+
+	if err := tw.WriteHeader(h); err != nil {
+		return err
+	}
+
+	_, err := io.Copy(tw, tr)
+
+	return err
+}
+
+// rewrite - write archive without removed files into temp file, then encrypt it into backup tar.
+func (r *repackRun) rewrite(tw *tar.Writer, h *tar.Header, a *Archive) error {
+	t, err := os.CreateTemp(r.rp.TempDir, ".repack-*")
+	if err != nil {
+		return exitcode.Wrap(exitcode.IO, err)
+	}
+
+	defer os.Remove(t.Name())
+	defer t.Close()
+
+	ar, err := r.b.OpenArchive(a, r.ks, r.d)
+	if err != nil {
+		return err
+	}
+
+	if err = r.filter(t, ar, a); err != nil {
+		return errors.Join(err, ar.Close())
+	}
+
+	if err = errors.Join(ar.drain(), ar.Close()); err != nil {
+		return err
+	}
+
+	size, err := t.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	if _, err = t.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	nh := *h
+	nh.Size = size
+	nh.ModTime = time.Now()
+
+	if !r.b.JSON.Protected {
+		r.sizes[a.BaseName()] = nh.Size
+		if err = tw.WriteHeader(&nh); err != nil {
+			return err
+		}
+
+		_, err = io.Copy(tw, t)
+
+		return err
+	}
+
+	return r.encrypt(tw, &nh, a, t, ar.Key.Value)
+}
+
+// encrypt - encrypt archive by key in format of source archive.
+func (r *repackRun) encrypt(tw *tar.Writer, h *tar.Header, a *Archive, t io.Reader, passwd string) error {
+	decr, err := r.b.Decryptor(r.d)
+	if err != nil {
+		return exitcode.Wrap(exitcode.UnsupportedCrypto, err)
+	}
+
+	f, err := decryptor.DetectFormat(a.Reader(), decr)
+	if err != nil {
+		return err
+	}
+
+	size := h.Size
+	h.Size = f.EncryptedSize(size)
+	r.sizes[a.BaseName()] = h.Size
+
+	if err = tw.WriteHeader(h); err != nil {
+		return err
+	}
+
+	ew, err := f.NewWriter(tw, passwd, size)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(ew, t); err != nil {
+		return err
+	}
+
+	return ew.Close()
+}
+
+// filter - write files of archive not matched by patterns, archive is compressed same as source.
+func (r *repackRun) filter(w io.Writer, ar *ArchiveReader, a *Archive) error {
+	var gw *gzip.Writer
+
+	if strings.HasSuffix(a.Name, ExtTarGz) {
+		gw = gzip.NewWriter(w)
+		w = gw
+	}
+
+	atw := tar.NewWriter(w)
+	removed := make(map[string]bool)
+
+	for {
+		h, err := ar.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		fp := a.FilePath(h.Name)
+
+		// hard link has no content, link to removed file is removed too
+		if h.Typeflag == tar.TypeLink && removed[path.Clean(h.Linkname)] ||
+			h.Typeflag != tar.TypeDir && slices.ContainsFunc(r.files, func(p *Pattern) bool { return p.Match(fp) }) {
+			removed[path.Clean(h.Name)] = true
+			r.res.Files = append(r.res.Files, fp)
+
+			continue
+		}
+
+		if err = atw.WriteHeader(h); err != nil {
+			return err
+		}
+
+		if _, err = io.Copy(atw, ar); err != nil {
+			return err
+		}
+	}
+
+	if err := atw.Close(); err != nil || gw == nil {
+		return err
+	}
+
+	return gw.Close()
+}
+
+// writeJSON - write backup.json with removed addons and folders and new sizes of archives.
+func (r *repackRun) writeJSON(tw *tar.Writer, h *tar.Header, raw []byte) error {
+	b, err := r.updateJSON(raw)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Corrupt, fmt.Errorf("%w: %w", ErrJSONNotValid, err))
+	}
+
+	nh := *h
+	nh.Size = int64(len(b))
+	nh.ModTime = time.Now()
+
+	if err = tw.WriteHeader(&nh); err != nil {
+		return err
+	}
+
+	_, err = tw.Write(b)
+
+	return err
+}
+
+func (r *repackRun) updateJSON(raw []byte) ([]byte, error) {
+	var o jsonObject
+	if err := json.Unmarshal(raw, &o); err != nil {
+		return nil, err
+	}
+
+	addons := make([]jsonObject, 0)
+	if err := o.get("addons", &addons); err != nil {
+		return nil, err
+	}
+
+	addons = slices.DeleteFunc(addons, func(a jsonObject) bool {
+		var s string
+
+		return a.get("slug", &s) == nil && slices.Contains(r.rp.Addons, s)
+	})
+
+	for i := range addons {
+		var s string
+		if err := addons[i].get("slug", &s); err != nil {
+			return nil, err
+		}
+
+		if size, ok := r.sizes[s]; ok {
+			if err := addons[i].set("size", sizeMB(size)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	folders := make([]string, 0)
+	if err := o.get("folders", &folders); err != nil {
+		return nil, err
+	}
+
+	folders = slices.DeleteFunc(folders, func(f string) bool { return slices.Contains(r.rp.Folders, f) })
+
+	if err := errors.Join(o.set("addons", addons), o.set("folders", folders)); err != nil {
+		return nil, err
+	}
+
+	// full restore removes addons not in backup, so backup without addons or folders is partial
+	if len(r.rp.Addons)+len(r.rp.Folders) > 0 {
+		if err := o.set("type", typePartial); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.updateHomeassistant(o); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	e := json.NewEncoder(&buf)
+	e.SetEscapeHTML(false)
+	e.SetIndent("", jsonIndent)
+
+	if err := e.Encode(o); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// updateHomeassistant - set exclude_database and size, partial backup can be without Home Assistant.
+func (r *repackRun) updateHomeassistant(o jsonObject) error {
+	var ha jsonObject
+	if err := o.get(homeassistantArchive, &ha); err != nil || ha == nil {
+		return err
+	}
+
+	if r.rp.Database {
+		if err := ha.set("exclude_database", true); err != nil {
+			return err
+		}
+	}
+
+	if size, ok := r.sizes[homeassistantArchive]; ok {
+		if err := ha.set("size", sizeMB(size)); err != nil {
+			return err
+		}
+	}
+
+	return o.set(homeassistantArchive, ha)
+}
+
+// sizeMB - size in megabytes with 2 decimals as in backup.json.
+func sizeMB(n int64) float64 {
+	return math.Round(float64(n)/bytesInMB*sizePrecision) / sizePrecision
+}
+
+// drain - read rest of archive after end of tar, so checksums of gzip and decryptor are checked.
+func (r *ArchiveReader) drain() error {
+	_, err := io.Copy(io.Discard, r.r)
+
+	return err
+}
@@ -0,0 +1,46 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/librun/ha-backup-tool/internal/decryptor"
+	"github.com/librun/ha-backup-tool/internal/key"
+)
+
+// Verify - read all archives of backup to end, archive is decrypted and decompressed, so checksums are checked.
+func (b *Backup) Verify(ks *key.Storage, d decryptor.Decryptor) error {
+	for _, a := range b.Archives {
+		if err := b.verifyArchive(a, ks, d); err != nil {
+			return fmt.Errorf("%s: %w", a.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (b *Backup) verifyArchive(a *Archive, ks *key.Storage, d decryptor.Decryptor) error {
+	ar, err := b.OpenArchive(a, ks, d)
+	if err != nil {
+		return err
+	}
+
+	for {
+		_, err = ar.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return errors.Join(err, ar.Close())
+		}
+	}
+
+	// tar reader stops on end blocks, rest of stream has checksum of gzip
+	if err = ar.drain(); err != nil {
+		return errors.Join(err, ar.Close())
+	}
+
+	return ar.Close()
+}
@@ -0,0 +1,275 @@
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-version"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/librun/ha-backup-tool/internal/entity"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+)
+
+const (
+	dirName     = "ha-backup-tool"
+	fileName    = "catalog.db"
+	dirMode     = 0o700
+	fileMode    = 0o600
+	openTimeout = time.Second
+
+	bucketBackups = "backups"
+)
+
+var (
+	ErrCatalogNotFound = errors.New("catalog not found, run command catalog scan")
+	ErrCatalogLocked   = errors.New("catalog is used by other process")
+	ErrEntryNotValid   = errors.New("entry of catalog not valid, run command catalog scan --rescan")
+)
+
+// Addon - addon in backup.
+type Addon struct {
+	Slug    string  `json:"slug"`
+	Name    string  `json:"name"`
+	Version string  `json:"version"`
+	Size    float64 `json:"size"`
+}
+
+// Entry - backup in catalog, key of entry is absolute path of backup file.
+type Entry struct {
+	Path              string    `json:"path"`
+	Slug              string    `json:"slug"`
+	Name              string    `json:"name"`
+	Date              time.Time `json:"date"`
+	Type              string    `json:"type"`
+	InstanceID        string    `json:"instance_id"`
+	HAVersion         string    `json:"homeassistant_version"`
+	SupervisorVersion string    `json:"supervisor_version"`
+	Protected         bool      `json:"protected"`
+	Crypto            string    `json:"crypto"`
+	Compressed        bool      `json:"compressed"`
+	ExcludeDatabase   bool      `json:"exclude_database"`
+	Addons            []Addon   `json:"addons"`
+	Folders           []string  `json:"folders"`
+	Size              int64     `json:"size"`
+	HASize            float64   `json:"homeassistant_size"`
+	ModTime           time.Time `json:"mod_time"`
+	Scanned           time.Time `json:"scanned"`
+}
+
+// Filter - filter of query, empty fields are not used. Both ends of date range are included.
+type Filter struct {
+	From          time.Time
+	To            time.Time
+	Instance      string
+	Addon         string
+	Type          string
+	BeforeVersion *version.Version
+	Dir           string
+	Limit         int
+}
+
+// Catalog - index of backups in bbolt database.
+type Catalog struct {
+	db *bolt.DB
+}
+
+// DefaultPath - path of catalog in user cache dir, catalog can be made again by scan.
+func DefaultPath() (string, error) {
+	d, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(d, dirName, fileName), nil
+}
+
+// NewEntry - make entry from backup.json and file info of backup.
+func NewEntry(p string, fi os.FileInfo, e *entity.HomeAssistantBackup) *Entry {
+	ce := Entry{
+		Path:              p,
+		Slug:              e.Slug,
+		Name:              e.Name,
+		Date:              e.Date,
+		Type:              e.Type,
+		InstanceID:        e.Extra.InstanceID,
+		HAVersion:         e.Homeassistant.Version,
+		SupervisorVersion: e.SupervisorVersion,
+		Protected:         e.Protected,
+		Crypto:            e.Crypto,
+		Compressed:        e.Compressed,
+		ExcludeDatabase:   e.Homeassistant.ExcludeDatabase,
+		Addons:            make([]Addon, 0, len(e.Addons)),
+		Folders:           e.Folders,
+		Size:              fi.Size(),
+		HASize:            e.Homeassistant.Size,
+		ModTime:           fi.ModTime(),
+		Scanned:           time.Now().UTC().Truncate(time.Second),
+	}
+
+	for _, a := range e.Addons {
+		ce.Addons = append(ce.Addons, Addon{Slug: a.Slug, Name: a.Name, Version: a.Version, Size: a.Size})
+	}
+
+	if ce.Folders == nil {
+		ce.Folders = make([]string, 0)
+	}
+
+	return &ce
+}
+
+// IsChanged - check file of backup is changed after scan.
+func (e *Entry) IsChanged(fi os.FileInfo) bool {
+	return e.Size != fi.Size() || !e.ModTime.Equal(fi.ModTime())
+}
+
+// HasAddon - check backup has addon with slug or name.
+func (e *Entry) HasAddon(s string) bool {
+	return slices.ContainsFunc(e.Addons, func(a Addon) bool {
+		return strings.EqualFold(a.Slug, s) || strings.EqualFold(a.Name, s)
+	})
+}
+
+// Open - open catalog, catalog is made only if it is not opened as read only.
+func Open(p string, readOnly bool) (*Catalog, error) {
+	if readOnly {
+		if _, err := os.Stat(p); errors.Is(err, os.ErrNotExist) {
+			return nil, exitcode.Wrap(exitcode.BadArgs, fmt.Errorf("%w: %s", ErrCatalogNotFound, p))
+		}
+	} else if err := os.MkdirAll(filepath.Dir(p), dirMode); err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(p, fileMode, &bolt.Options{Timeout: openTimeout, ReadOnly: readOnly})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, exitcode.Wrap(exitcode.IO, fmt.Errorf("%w: %s", ErrCatalogLocked, p))
+	}
+
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.Corrupt, err)
+	}
+
+	return &Catalog{db: db}, nil
+}
+
+// Close - close database of catalog.
+func (c *Catalog) Close() error {
+	return c.db.Close()
+}
+
+// Put - add or replace entries.
+func (c *Catalog) Put(es ...*Entry) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucketBackups))
+		if err != nil {
+			return err
+		}
+
+		for _, e := range es {
+			v, errM := json.Marshal(e)
+			if errM != nil {
+				return errM
+			}
+
+			if err = b.Put([]byte(e.Path), v); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Delete - delete entries by path of backup.
+func (c *Catalog) Delete(ps ...string) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketBackups))
+		if b == nil {
+			return nil
+		}
+
+		for _, p := range ps {
+			if err := b.Delete([]byte(p)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Query - get entries matched by filter, newest backup is first.
+func (c *Catalog) Query(f *Filter) ([]*Entry, error) {
+	es := make([]*Entry, 0)
+
+	err := c.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketBackups))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var e Entry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return exitcode.Wrap(exitcode.Corrupt, fmt.Errorf("%w: %s: %w", ErrEntryNotValid, k, err))
+			}
+
+			if f.Match(&e) {
+				es = append(es, &e)
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(es, func(a, b *Entry) int { return b.Date.Compare(a.Date) })
+
+	if f.Limit > 0 && len(es) > f.Limit {
+		es = es[:f.Limit]
+	}
+
+	return es, nil
+}
+
+// Match - check entry is matched by filter.
+func (f *Filter) Match(e *Entry) bool {
+	switch {
+	case !f.From.IsZero() && e.Date.Before(f.From):
+		return false
+	case !f.To.IsZero() && e.Date.After(f.To):
+		return false
+	case f.Instance != "" && e.InstanceID != f.Instance:
+		return false
+	case f.Addon != "" && !e.HasAddon(f.Addon):
+		return false
+	case f.Type != "" && !strings.EqualFold(e.Type, f.Type):
+		return false
+	case f.Dir != "" && !isInDir(e.Path, f.Dir):
+		return false
+	}
+
+	if f.BeforeVersion != nil {
+		v, err := version.NewVersion(e.HAVersion)
+		if err != nil || !v.LessThan(f.BeforeVersion) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isInDir - check path is in dir or its sub dirs.
+func isInDir(p, dir string) bool {
+	r, err := filepath.Rel(dir, p)
+
+	return err == nil && r != ".." && !strings.HasPrefix(r, ".."+string(filepath.Separator))
+}
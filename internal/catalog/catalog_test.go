@@ -0,0 +1,99 @@
+package catalog_test
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-version"
+
+	"github.com/librun/ha-backup-tool/internal/catalog"
+)
+
+func testEntries() []*catalog.Entry {
+	day := func(d int) time.Time { return time.Date(2025, 12, d, 10, 0, 0, 0, time.UTC) }
+
+	return []*catalog.Entry{
+		{Path: "/nas/home/a.tar", Date: day(1), Type: "full", InstanceID: "home", HAVersion: "2025.11.3"},
+		{Path: "/nas/home/b.tar", Date: day(3), Type: "partial", InstanceID: "home", HAVersion: "2025.12.1",
+			Addons: []catalog.Addon{{Slug: "core_mosquitto", Name: "Mosquitto broker"}}},
+		{Path: "/nas/home/c.tar", Date: day(5), Type: "full", InstanceID: "home", HAVersion: "2025.12.2"},
+		{Path: "/nas/office/d.tar", Date: day(2), Type: "full", InstanceID: "office", HAVersion: "2025.11.1"},
+	}
+}
+
+func TestCatalog_Query(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "dir", "catalog.db")
+
+	c, err := catalog.Open(p, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.Put(testEntries()...); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.Delete("/nas/home/c.tar"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = catalog.Open(p, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tests := []struct {
+		name   string
+		filter catalog.Filter
+		want   []string
+	}{
+		{"all newest first", catalog.Filter{}, []string{"b", "d", "a"}},
+		{"instance", catalog.Filter{Instance: "home"}, []string{"b", "a"}},
+		{"addon by name", catalog.Filter{Addon: "mosquitto broker"}, []string{"b"}},
+		{"date range", catalog.Filter{
+			From: time.Date(2025, 12, 2, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2025, 12, 3, 9, 0, 0, 0, time.UTC),
+		}, []string{"d"}},
+		{"end of date range included", catalog.Filter{
+			From: time.Date(2025, 12, 2, 10, 0, 0, 0, time.UTC),
+			To:   time.Date(2025, 12, 3, 10, 0, 0, 0, time.UTC),
+		}, []string{"b", "d"}},
+		{"last full before version", catalog.Filter{
+			Type: "full", Instance: "home", BeforeVersion: version.Must(version.NewVersion("2025.12")), Limit: 1,
+		}, []string{"a"}},
+		{"dir", catalog.Filter{Dir: "/nas/office"}, []string{"d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es, err := c.Query(&tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, 0, len(es))
+			for _, e := range es {
+				got = append(got, filepath.Base(e.Path)[:1])
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Query() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpen_NotFound(t *testing.T) {
+	_, err := catalog.Open(filepath.Join(t.TempDir(), "catalog.db"), true)
+	if !errors.Is(err, catalog.ErrCatalogNotFound) {
+		t.Errorf("Open() error = %v, want %v", err, catalog.ErrCatalogNotFound)
+	}
+}
@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/librun/ha-backup-tool/internal/backup"
+	"github.com/librun/ha-backup-tool/internal/catalog"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+	"github.com/librun/ha-backup-tool/internal/extractor"
+	"github.com/librun/ha-backup-tool/internal/options"
+	"github.com/librun/ha-backup-tool/internal/output"
+)
+
+// BackupError - backup which is not processed by command.
+type BackupError struct {
+	File      string `json:"file"`
+	Error     string `json:"error"`
+	ErrorCode string `json:"error_code"`
+}
+
+// NewBackupError - make error of backup with code for machine readable output.
+func NewBackupError(file string, err error) *BackupError {
+	return &BackupError{File: file, Error: err.Error(), ErrorCode: extractor.GetErrorCode(err)}
+}
+
+// listBackups - get backup files from arguments, dirs are searched for backups.
+// Without arguments backups matched by filter are taken from catalog, newest backup is first.
+func listBackups(ops *options.BackupsOptions, log *slog.Logger) ([]string, error) {
+	if len(ops.Files) == 0 {
+		ct, err := catalog.Open(ops.CatalogPath, true)
+		if err != nil {
+			log.Error("Could not open catalog", "file", ops.CatalogPath, "error", err)
+
+			return nil, err
+		}
+		defer ct.Close()
+
+		es, err := ct.Query(&ops.Filter)
+		if err != nil {
+			return nil, err
+		}
+
+		ps := make([]string, 0, len(es))
+		for _, e := range es {
+			ps = append(ps, e.Path)
+		}
+
+		log.Info("Found backups in catalog", "count", len(ps), "catalog", ops.CatalogPath)
+
+		return ps, nil
+	}
+
+	var ps []string
+
+	for _, f := range ops.Files {
+		s, err := os.Stat(f)
+		if err != nil {
+			return nil, exitcode.Wrap(exitcode.BadArgs, err)
+		}
+
+		if !s.IsDir() {
+			ps = append(ps, f)
+
+			continue
+		}
+
+		fs, err := backup.Find(f)
+		if err != nil {
+			return nil, exitcode.Wrap(exitcode.IO, fmt.Errorf("%w: %s", err, f))
+		}
+
+		ps = append(ps, fs...)
+	}
+
+	return ps, nil
+}
+
+// getBatchStatus - status of command for several backups by count of failed backups.
+func getBatchStatus(total, failed int) string {
+	switch {
+	case failed == 0:
+		return output.StatusSuccess
+	case failed < total:
+		return output.StatusPartial
+	}
+
+	return output.StatusFailed
+}
+
+// getBatchError - error of command for several backups, if all backups failed error has class of first error.
+func getBatchError(total int, errs []error, partial error) error {
+	switch {
+	case len(errs) == 0:
+		return nil
+	case len(errs) < total:
+		return exitcode.Wrap(exitcode.Partial, partial)
+	}
+
+	return exitcode.Wrap(exitcode.Get(errs[0]), fmt.Errorf("%w: %w", partial, errs[0]))
+}
@@ -0,0 +1,311 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/librun/ha-backup-tool/internal/backup"
+	"github.com/librun/ha-backup-tool/internal/catalog"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+	"github.com/librun/ha-backup-tool/internal/flags"
+	"github.com/librun/ha-backup-tool/internal/options"
+	"github.com/librun/ha-backup-tool/internal/output"
+)
+
+var (
+	ErrDirNotValid = errors.New("dir not valid")
+	ErrNotFullScan = errors.New("some backups are not added to catalog")
+)
+
+// CatalogScanReport - report of scan dir of backups.
+type CatalogScanReport struct {
+	Command    string              `json:"command"`
+	Dir        string              `json:"dir"`
+	Catalog    string              `json:"catalog"`
+	Status     string              `json:"status"`
+	Total      int                 `json:"total"`
+	Added      int                 `json:"added"`
+	Updated    int                 `json:"updated"`
+	Unchanged  int                 `json:"unchanged"`
+	Removed    int                 `json:"removed"`
+	Failed     []*CatalogScanError `json:"failed"`
+	ExitCode   int                 `json:"exit_code"`
+	DurationMs int64               `json:"duration_ms"`
+}
+
+// CatalogQueryReport - report of query of catalog, newest backup is first.
+type CatalogQueryReport struct {
+	Command    string           `json:"command"`
+	Dir        string           `json:"dir"`
+	Catalog    string           `json:"catalog"`
+	Status     string           `json:"status"`
+	Total      int              `json:"total"`
+	Backups    []*catalog.Entry `json:"backups"`
+	ExitCode   int              `json:"exit_code"`
+	DurationMs int64            `json:"duration_ms"`
+}
+
+// CatalogScanError - backup not added to catalog.
+type CatalogScanError struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+}
+
+// Catalog - command group for index of backups.
+func Catalog() *cli.Command {
+	return &cli.Command{
+		Name:  "catalog",
+		Usage: "command for index backups from dir into local catalog and search in it",
+		Commands: []*cli.Command{
+			{
+				Name:  "scan",
+				Usage: "read backup.json of every backup in dir and its sub dirs and save it in catalog",
+				Arguments: []cli.Argument{
+					&cli.StringArg{
+						Name:      "dir",
+						UsageText: "dir with backups",
+					},
+				},
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  flags.CatalogRescan,
+						Usage: "Read again backups not changed after last scan",
+					},
+				},
+				OnUsageError: OnUsageError,
+				Before:       ApplyConfig,
+				Action:       catalogAction(catalogScan),
+			},
+			{
+				Name:  "query",
+				Usage: "list backups from catalog, newest backup is first",
+				Arguments: []cli.Argument{
+					&cli.StringArg{
+						Name:      "dir",
+						UsageText: "only backups in dir (default all backups in catalog)",
+					},
+				},
+				Flags:        catalogFilterFlags(),
+				OnUsageError: OnUsageError,
+				Before:       ApplyConfig,
+				Action:       catalogAction(catalogQuery),
+			},
+		},
+	}
+}
+
+// catalogFilterFlags - flags of filter of backups in catalog.
+func catalogFilterFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  flags.CatalogFrom,
+			Usage: "Backups made from date (YYYY-MM-DD or RFC3339)",
+		},
+		&cli.StringFlag{
+			Name:  flags.CatalogTo,
+			Usage: "Backups made to date or time, it is included (YYYY-MM-DD or RFC3339)",
+		},
+		&cli.StringFlag{
+			Name:  flags.CatalogInstance,
+			Usage: "Backups of Home Assistant instance ID",
+		},
+		&cli.StringFlag{
+			Name:  flags.CatalogAddon,
+			Usage: "Backups with addon, slug or name of addon",
+		},
+		&cli.StringFlag{
+			Name:  flags.CatalogType,
+			Usage: "Backups with type: full or partial",
+		},
+		&cli.StringFlag{
+			Name:  flags.CatalogBeforeVersion,
+			Usage: "Backups of Home Assistant version lower than version",
+		},
+		&cli.IntFlag{
+			Name:  flags.CatalogLimit,
+			Usage: "Max count of backups, 0 for without limit",
+		},
+	}
+}
+
+// catalogAction - create options for command of catalog.
+func catalogAction(f func(c *cli.Command, ops *options.CmdCatalogOptions) error) cli.ActionFunc {
+	return func(_ context.Context, c *cli.Command) error {
+		ops, err := options.NewCmdCatalogOptions(c)
+		if err != nil {
+			return exitcode.Wrap(exitcode.BadArgs, err)
+		}
+
+		err = f(c, ops)
+		if errC := ops.Close(); err == nil {
+			err = errC
+		}
+
+		return err
+	}
+}
+
+func catalogScan(c *cli.Command, ops *options.CmdCatalogOptions) error {
+	var start = time.Now()
+
+	if s, err := os.Stat(ops.Dir); err != nil || !s.IsDir() {
+		return exitcode.Wrap(exitcode.BadArgs, fmt.Errorf("%w: %s", ErrDirNotValid, c.StringArg("dir")))
+	}
+
+	ps, err := backup.Find(ops.Dir)
+	if err != nil {
+		return exitcode.Wrap(exitcode.IO, err)
+	}
+
+	ct, err := catalog.Open(ops.Path, false)
+	if err != nil {
+		ops.Log.Error("Could not open catalog", "file", ops.Path, "error", err)
+
+		return err
+	}
+	defer ct.Close()
+
+	r := CatalogScanReport{Command: c.FullName(), Dir: ops.Dir, Catalog: ops.Path, Total: len(ps),
+		Failed: make([]*CatalogScanError, 0)}
+
+	es, err := scanBackups(ct, ps, ops, &r)
+	if err != nil {
+		return err
+	}
+
+	if err = ct.Put(es...); err != nil {
+		return exitcode.Wrap(exitcode.IO, err)
+	}
+
+	r.Status = output.StatusSuccess
+	if len(r.Failed) > 0 {
+		err = exitcode.Wrap(exitcode.Partial, ErrNotFullScan)
+		r.Status = output.StatusPartial
+	}
+
+	r.ExitCode = int(exitcode.Get(err))
+	r.DurationMs = time.Since(start).Milliseconds()
+
+	ops.Out.Printf("\n✅ Scanned %v backup file(s) in %s: %v added, %v updated, %v unchanged, %v removed\n",
+		r.Total, r.Dir, r.Added, r.Updated, r.Unchanged, r.Removed)
+
+	for _, f := range r.Failed {
+		ops.Out.Printf("⚠️  %s not added: %s\n", f.File, f.Error)
+	}
+
+	if errR := ops.Out.Report(r); errR != nil {
+		return errR
+	}
+
+	return err
+}
+
+// scanBackups - read changed backups and remove backups not found in dir from catalog.
+func scanBackups(ct *catalog.Catalog, ps []string, ops *options.CmdCatalogOptions,
+	r *CatalogScanReport) ([]*catalog.Entry, error) {
+	old, err := ct.Query(&catalog.Filter{Dir: ops.Dir})
+	if err != nil {
+		return nil, err
+	}
+
+	om := make(map[string]*catalog.Entry, len(old))
+	for _, e := range old {
+		om[e.Path] = e
+	}
+
+	es := make([]*catalog.Entry, 0, len(ps))
+
+	for _, p := range ps {
+		o, ok := om[p]
+		delete(om, p)
+
+		fi, errS := os.Stat(p)
+		if errS == nil && ok && !ops.Rescan && !o.IsChanged(fi) {
+			r.Unchanged++
+
+			continue
+		}
+
+		var e *catalog.Entry
+		if errS == nil {
+			var be, errR = backup.ReadJSON(p)
+			if errR == nil {
+				e = catalog.NewEntry(p, fi, be)
+			}
+
+			errS = errR
+		}
+
+		if errS != nil {
+			ops.Log.Warn("Could not read backup", "file", p, "error", errS)
+			// old entry is kept, file can be read on next scan
+			r.Failed = append(r.Failed, &CatalogScanError{File: p, Error: errS.Error()})
+
+			continue
+		}
+
+		ops.Log.Debug("Backup is read", "file", p, "slug", e.Slug, "date", e.Date)
+
+		if ok {
+			r.Updated++
+		} else {
+			r.Added++
+		}
+
+		es = append(es, e)
+	}
+
+	// backups which are deleted from dir
+	if len(om) > 0 {
+		if err = ct.Delete(slices.Collect(maps.Keys(om))...); err != nil {
+			return nil, exitcode.Wrap(exitcode.IO, err)
+		}
+
+		r.Removed = len(om)
+	}
+
+	return es, nil
+}
+
+func catalogQuery(c *cli.Command, ops *options.CmdCatalogOptions) error {
+	var start = time.Now()
+
+	ct, err := catalog.Open(ops.Path, true)
+	if err != nil {
+		ops.Log.Error("Could not open catalog", "file", ops.Path, "error", err)
+
+		return err
+	}
+	defer ct.Close()
+
+	es, err := ct.Query(&ops.Filter)
+	if err != nil {
+		return err
+	}
+
+	if !ops.Out.IsJSON() {
+		for _, e := range es {
+			p := ""
+			if e.Protected {
+				p = "🔒"
+			}
+
+			ops.Out.Printf("%s\t%s\t%s\t%s\t%s\t%s\t%s\n", e.Date.Local().Format(time.DateTime), e.Type,
+				e.HAVersion, e.InstanceID, p, e.Name, e.Path)
+		}
+
+		if len(es) == 0 {
+			ops.Out.Println("⚠️  No backups found in catalog.")
+		}
+	}
+
+	return ops.Out.Report(CatalogQueryReport{Command: c.FullName(), Dir: ops.Dir, Catalog: ops.Path,
+		Status: output.StatusSuccess, Total: len(es), Backups: es, DurationMs: time.Since(start).Milliseconds()})
+}
@@ -0,0 +1,80 @@
+package commands_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/librun/ha-backup-tool/internal/commands"
+	"github.com/librun/ha-backup-tool/internal/flags"
+	"github.com/librun/ha-backup-tool/internal/output"
+)
+
+func TestCatalogQuery(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	dir := t.TempDir()
+	for _, tb := range []testBackup{{name: "d1", day: 1}, {name: "d2", day: 2}, {name: "d3", day: 3}} {
+		writeTestBackup(t, dir, tb)
+	}
+
+	app := &cli.Command{
+		Name:     "ha-backup-tool",
+		Flags:    []cli.Flag{&cli.StringFlag{Name: flags.GlobalFormat}, &cli.StringFlag{Name: flags.GlobalCatalog}},
+		Commands: []*cli.Command{commands.Catalog()},
+	}
+	ct := filepath.Join(t.TempDir(), "catalog.db")
+
+	if _, err := captureStdout(t, func() error {
+		return app.Run(t.Context(), []string{"ha-backup-tool", "--catalog", ct, "catalog", "scan", dir})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{name: "all", want: []string{"d3", "d2", "d1"}},
+		// backup made at time of end of range is included
+		{name: "to time", args: []string{"--to", "2025-01-02T03:00:00Z"}, want: []string{"d2", "d1"}},
+		{name: "from and to time", args: []string{"--from", "2025-01-02T03:00:00Z", "--to", "2025-01-03T03:00:00Z"},
+			want: []string{"d3", "d2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := captureStdout(t, func() error {
+				return app.Run(t.Context(), append([]string{"ha-backup-tool", "--format", "json", "--catalog", ct,
+					"catalog", "query"}, tt.args...))
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var r commands.CatalogQueryReport
+			if err = json.Unmarshal(out, &r); err != nil {
+				t.Fatalf("report %q: %v", out, err)
+			}
+
+			if !strings.HasSuffix(r.Command, "query") || r.Status != output.StatusSuccess || r.Total != len(tt.want) {
+				t.Errorf("report = %+v", r)
+			}
+
+			got := make([]string, 0, len(r.Backups))
+			for _, e := range r.Backups {
+				got = append(got, strings.TrimSuffix(filepath.Base(e.Path), ".tar"))
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("backups = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
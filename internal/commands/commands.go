@@ -0,0 +1,14 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+)
+
+// OnUsageError - set class bad arguments for errors of parse flags and arguments.
+func OnUsageError(_ context.Context, _ *cli.Command, err error, _ bool) error {
+	return exitcode.Wrap(exitcode.BadArgs, err)
+}
@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/librun/ha-backup-tool/internal/config"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+	"github.com/librun/ha-backup-tool/internal/flags"
+)
+
+// ApplyConfig - set flags not set by user from config file, not exists default config file is skipped.
+func ApplyConfig(ctx context.Context, c *cli.Command) (context.Context, error) {
+	p := c.String(flags.GlobalConfig)
+	if p == "" {
+		dp, err := config.DefaultPath()
+		if err != nil {
+			return ctx, nil //nolint:nilerr // config file is optional
+		}
+
+		// profile is not exists without config file
+		if _, err = os.Stat(dp); errors.Is(err, os.ErrNotExist) && !c.IsSet(flags.GlobalProfile) {
+			return ctx, nil
+		}
+
+		p = dp
+	}
+
+	cfg, err := config.Load(p)
+	if err != nil {
+		return ctx, exitcode.Wrap(exitcode.BadArgs, err)
+	}
+
+	return ctx, cfg.Apply(c, c.String(flags.GlobalProfile))
+}
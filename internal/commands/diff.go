@@ -0,0 +1,249 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/librun/ha-backup-tool/internal/backup"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+	"github.com/librun/ha-backup-tool/internal/extractor"
+	"github.com/librun/ha-backup-tool/internal/flags"
+	"github.com/librun/ha-backup-tool/internal/options"
+)
+
+var (
+	ErrNotFullDiff  = errors.New("some archives are not compared")
+	ErrBackupNotSet = errors.New("two backups must be set")
+)
+
+// DiffReport - report of compare of two backups, added is only in new backup, removed is only in old backup.
+type DiffReport struct {
+	Command    string              `json:"command"`
+	Old        string              `json:"old"`
+	New        string              `json:"new"`
+	Status     string              `json:"status"`
+	Metadata   []*backup.DiffField `json:"metadata"`
+	Addons     backup.DiffAddons   `json:"addons"`
+	Folders    backup.DiffList     `json:"folders"`
+	Archives   backup.DiffList     `json:"archives"`
+	Files      backup.DiffFiles    `json:"files"`
+	Failed     []*DiffError        `json:"failed"`
+	ExitCode   int                 `json:"exit_code"`
+	DurationMs int64               `json:"duration_ms"`
+}
+
+// DiffError - archive which is not compared.
+type DiffError struct {
+	File      string `json:"file"`
+	Archive   string `json:"archive"`
+	Error     string `json:"error"`
+	ErrorCode string `json:"error_code"`
+}
+
+// Diff - command for compare two backups.
+func Diff() *cli.Command {
+	return &cli.Command{
+		Name:  "diff",
+		Usage: "command for compare metadata and files of two backups",
+		Arguments: []cli.Argument{
+			&cli.StringArg{
+				Name:      "old",
+				UsageText: "first backup, e.g. older backup for restore",
+			},
+			&cli.StringArg{
+				Name:      "new",
+				UsageText: "second backup, e.g. current backup",
+			},
+		},
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  flags.DiffText,
+				Usage: "Show diff of changed YAML and JSON files in homeassistant/data",
+			},
+			&cli.BoolFlag{
+				Name:  flags.DiffMetadataOnly,
+				Usage: "Compare only backup.json and list of archives, files in archives are not read",
+			},
+			&cli.StringFlag{
+				Name:  flags.DiffMaxFileSize,
+				Usage: "Max size of file for text diff, bigger files are compared only by hash (default size 1MiB)",
+			},
+			&cli.StringFlag{
+				Name:    flags.DiffCrypto,
+				Aliases: []string{"c"},
+				Usage:   "Version SecureTar v2, v3 and etc",
+			},
+		},
+		OnUsageError: OnUsageError,
+		Before:       ApplyConfig,
+		Action:       diffAction,
+	}
+}
+
+// diffAction - command for compare two backups.
+func diffAction(_ context.Context, c *cli.Command) error {
+	ops, err := options.NewCmdDiffOptions(c)
+	if err != nil {
+		return exitcode.Wrap(exitcode.BadArgs, err)
+	}
+
+	err = diffBackups(c, ops)
+	if errC := ops.Close(); err == nil {
+		err = errC
+	}
+
+	return err
+}
+
+func diffBackups(c *cli.Command, ops *options.CmdDiffOptions) error {
+	var start = time.Now()
+
+	if ops.Old == "" || ops.New == "" {
+		return exitcode.Wrap(exitcode.BadArgs, ErrBackupNotSet)
+	}
+
+	ob, err := openDiffBackup(ops.Old, ops)
+	if err != nil {
+		return err
+	}
+	defer ob.Close()
+
+	nb, err := openDiffBackup(ops.New, ops)
+	if err != nil {
+		return err
+	}
+	defer nb.Close()
+
+	d := backup.Diff{MetadataOnly: ops.MetadataOnly, Text: ops.Text, MaxFileSize: ops.MaxFileSize}
+
+	res, err := ob.Diff(nb, &d, ops.Key, ops.Decryptor)
+	if err != nil {
+		return exitcode.Wrap(exitcode.IO, err)
+	}
+
+	r := DiffReport{Command: c.Name, Old: ops.Old, New: ops.New, Metadata: res.Metadata, Addons: res.Addons,
+		Folders: res.Folders, Archives: res.Archives, Files: res.Files, Failed: make([]*DiffError, 0)}
+
+	errs := make([]error, 0, len(res.Failed))
+	for _, f := range res.Failed {
+		errs = append(errs, r.fail(f, ops))
+	}
+
+	printDiff(&r, ops)
+
+	err = getBatchError(res.Compared, errs, ErrNotFullDiff)
+	r.Status = getBatchStatus(res.Compared, len(errs))
+	r.ExitCode = int(exitcode.Get(err))
+	r.DurationMs = time.Since(start).Milliseconds()
+
+	if errR := ops.Out.Report(r); errR != nil {
+		return errR
+	}
+
+	return err
+}
+
+func openDiffBackup(p string, ops *options.CmdDiffOptions) (*backup.Backup, error) {
+	b, err := backup.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		err = exitcode.Wrap(exitcode.BadArgs, err)
+	}
+
+	if err != nil {
+		ops.Log.Error("Could not open backup", "file", p, "error", err)
+
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (r *DiffReport) fail(f *backup.DiffArchiveError, ops *options.CmdDiffOptions) error {
+	err := extractor.WrapError(f.Err, f.Backup.JSON.Protected)
+	ops.Log.Error("Could not read archive", "file", f.Backup.Path, "archive", f.Archive.Name, "error", err)
+	r.Failed = append(r.Failed, &DiffError{File: f.Backup.Path, Archive: f.Archive.Name, Error: err.Error(),
+		ErrorCode: extractor.GetErrorCode(err)})
+
+	return err
+}
+
+func printDiff(r *DiffReport, ops *options.CmdDiffOptions) {
+	if len(r.Metadata) > 0 {
+		ops.Out.Println("\n📋 Metadata")
+
+		for _, f := range r.Metadata {
+			ops.Out.Printf("   %s: %s → %s\n", f.Name, f.Old, f.New)
+		}
+	}
+
+	if len(r.Addons.Added)+len(r.Addons.Removed)+len(r.Addons.Changed) > 0 {
+		ops.Out.Println("\n🧩 Addons")
+
+		for _, a := range r.Addons.Added {
+			ops.Out.Printf("   + %s (%s) %s\n", a.Slug, a.Name, a.Version)
+		}
+
+		for _, a := range r.Addons.Removed {
+			ops.Out.Printf("   - %s (%s) %s\n", a.Slug, a.Name, a.Version)
+		}
+
+		for _, a := range r.Addons.Changed {
+			ops.Out.Printf("   ~ %s (%s) %s → %s\t%v → %v MB\n", a.Slug, a.Name, a.Old, a.New, a.OldSize, a.NewSize)
+		}
+	}
+
+	printDiffList("📁 Folders", r.Folders, ops)
+	printDiffList("📦 Archives", r.Archives, ops)
+
+	if len(r.Files.Added)+len(r.Files.Removed)+len(r.Files.Changed) > 0 {
+		ops.Out.Println("\n📄 Files")
+
+		for _, f := range r.Files.Added {
+			ops.Out.Printf("   + %s\t%d bytes\n", f.Path, f.Size)
+		}
+
+		for _, f := range r.Files.Removed {
+			ops.Out.Printf("   - %s\t%d bytes\n", f.Path, f.Size)
+		}
+
+		for _, f := range r.Files.Changed {
+			ops.Out.Printf("   ~ %s\t%d → %d bytes\n", f.Path, f.OldSize, f.NewSize)
+		}
+
+		for _, f := range r.Files.Changed {
+			if f.Diff != "" {
+				ops.Out.Printf("\n%s", f.Diff)
+			}
+		}
+	}
+
+	ops.Out.Printf("\n✅ Compared %s and %s: %v metadata field(s), %v addon(s), %v folder(s), %v archive(s), "+
+		"%v file(s) are different\n", r.Old, r.New, len(r.Metadata),
+		len(r.Addons.Added)+len(r.Addons.Removed)+len(r.Addons.Changed),
+		len(r.Folders.Added)+len(r.Folders.Removed), len(r.Archives.Added)+len(r.Archives.Removed),
+		len(r.Files.Added)+len(r.Files.Removed)+len(r.Files.Changed))
+
+	for _, f := range r.Failed {
+		ops.Out.Printf("⚠️  %s in %s not compared: %s\n", f.Archive, f.File, f.Error)
+	}
+}
+
+func printDiffList(title string, d backup.DiffList, ops *options.CmdDiffOptions) {
+	if len(d.Added)+len(d.Removed) == 0 {
+		return
+	}
+
+	ops.Out.Printf("\n%s\n", title)
+
+	for _, s := range d.Added {
+		ops.Out.Printf("   + %s\n", s)
+	}
+
+	for _, s := range d.Removed {
+		ops.Out.Printf("   - %s\n", s)
+	}
+}
@@ -5,13 +5,18 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/urfave/cli/v3"
 
+	"github.com/librun/ha-backup-tool/internal/conflict"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
 	"github.com/librun/ha-backup-tool/internal/extractor"
 	"github.com/librun/ha-backup-tool/internal/flags"
 	"github.com/librun/ha-backup-tool/internal/options"
+	"github.com/librun/ha-backup-tool/internal/output"
 	"github.com/librun/ha-backup-tool/internal/tarextractor"
 )
 
@@ -58,22 +63,78 @@ func Extract() *cli.Command {
 				Name:  flags.ExtractSkipCreateLinks,
 				Usage: "Skip create symlinks and hard links",
 			},
+			&cli.BoolFlag{
+				Name:  flags.ExtractKeepPartial,
+				Usage: "Keep staging dir with partial extracted files if extract failed",
+			},
+			&cli.BoolFlag{
+				Name:  flags.ExtractResume,
+				Usage: "Resume interrupted extract, skip archives already fully extracted",
+			},
+			&cli.StringFlag{
+				Name:  flags.ExtractOnExists,
+				Value: conflict.PolicyFailString,
+				Usage: "Action if output dir exists: fail, overwrite, skip, newer, rename",
+			},
+			&cli.BoolFlag{
+				Name:  flags.ExtractNoPreserve,
+				Usage: "Not preserve permissions, times and xattrs of files from archive",
+			},
+			&cli.BoolFlag{
+				Name:  flags.ExtractSameOwner,
+				Usage: "Preserve owner and group of files from archive (only when run as root)",
+			},
+			&cli.BoolFlag{
+				Name:  flags.ExtractCreateSpecial,
+				Usage: "Create fifo, char and block devices from archive (skipped by default, only on Linux)",
+			},
+			&cli.BoolFlag{
+				Name:  flags.ExtractAllowUnsafeLinks,
+				Usage: "Create symlinks with absolute target or target outside output dir",
+			},
+			&cli.BoolFlag{
+				Name:  flags.ExtractSkipSpaceCheck,
+				Usage: "Skip check of free disk space before extract",
+			},
+			&cli.IntFlag{
+				Name:    flags.ExtractJobs,
+				Aliases: []string{"j"},
+				Usage:   "Max count of archives extracted at same time, 0 for without limit",
+			},
 		},
-		Action: extractAction,
+		OnUsageError: OnUsageError,
+		Before:       ApplyConfig,
+		Action:       extractAction,
 	}
 }
 
 // extractAction - command for extract backups.
 func extractAction(_ context.Context, c *cli.Command) error {
-	var fs = c.StringArgs("backups")
-
 	ops, err := options.NewCmdExtractOptions(c)
 	if err != nil {
-		return err
+		return exitcode.Wrap(exitcode.BadArgs, err)
+	}
+
+	err = extractFiles(c, ops)
+	if errC := ops.Close(); err == nil {
+		err = errC
+	}
+
+	return err
+}
+
+func extractFiles(c *cli.Command, ops *options.CmdExtractOptions) error {
+	var fs = c.StringArgs("backups")
+	var err error
+
+	if ops.SameOwner && os.Geteuid() != 0 {
+		ops.Log.Warn("Flag --same-owner ignored, command is not run as root")
+
+		ops.SameOwner = false
 	}
 
 	if len(fs) == 0 {
-		fmt.Println("\n⚠️  No files for extract.")
+		ops.Out.Println("\n⚠️  No files for extract.")
 
 		return nil
 	}
@@ -87,55 +148,99 @@ func extractAction(_ context.Context, c *cli.Command) error {
 		}
 	}
 
-	fmt.Printf("📁 Found %s backup file(s) to process\n", fs)
+	ops.Log.Info("Found backup files to process", "count", len(fs), "files", fs)
 
-	var s int
+	var start = time.Now()
+	var brs = make([]*output.BackupReport, len(fs))
+	var errs = make([]error, len(fs))
 	var wg = sync.WaitGroup{}
 
-	for _, f := range fs {
+	ops.Progress.Start()
+
+	for i, f := range fs {
 		wg.Add(1)
 
 		go func() {
-			if errF := extractActionFile(f, ops, &wg); errF == nil {
-				s++
-			}
+			defer wg.Done()
+
+			brs[i], errs[i] = extractActionFile(f, ops)
 		}()
 	}
 
 	wg.Wait()
+	ops.Progress.Stop()
+
+	r := output.NewReport(c.Name)
+	r.Backups = brs
+	r.Finish(start)
 
-	if s > 0 {
-		fmt.Printf("\n✅ Successfully decrypted %v of %v backup file(s)!\n", s, len(fs))
-		fmt.Println("You can find the decrypted files in the extracted directories.")
+	if r.Success > 0 {
+		ops.Out.Printf("\n✅ Successfully decrypted %v of %v backup file(s)!\n", r.Success, r.Total)
+		ops.Out.Println("You can find the decrypted files in the extracted directories.")
+
+		printKeys(r, ops)
 	} else {
-		fmt.Println("\n⚠️ No files were successfully decrypted.")
+		ops.Out.Println("\n⚠️ No files were successfully decrypted.")
 	}
 
-	if s == 0 || s != len(fs) {
-		return ErrNotFullExtract
+	err = getExtractError(r, errs)
+	r.ExitCode = int(exitcode.Get(err))
+
+	if errR := ops.Out.Report(r); errR != nil {
+		return errR
 	}
 
-	return nil
+	return err
 }
 
-func extractActionFile(f string, ops *options.CmdExtractOptions, wg *sync.WaitGroup) error {
-	defer wg.Done()
+// printKeys - print which key opened backup, if several keys are set.
+func printKeys(r *output.Report, ops *options.CmdExtractOptions) {
+	if ops.Key.Len() < 2 { //nolint:mnd // one key is not need to show
+		return
+	}
 
-	if err := extractor.ValidateTarFile(f); err != nil {
-		if !ops.Verbose {
-			fmt.Printf("\n❌ File %s .tar not valid!\n", f)
-		} else {
-			fmt.Printf("\n❌ File %s .tar not valid! Error: %s\n", f, err)
+	for _, b := range r.Backups {
+		if len(b.Keys) > 0 {
+			ops.Out.Printf("🔑 %s opened by %s\n", b.File, strings.Join(b.Keys, ", "))
 		}
+	}
+}
 
-		return err
+// getExtractError - get error of command, if all backups failed error has class of first failed backup.
+func getExtractError(r *output.Report, errs []error) error {
+	switch r.Status {
+	case output.StatusSuccess:
+		return nil
+	case output.StatusPartial:
+		return exitcode.Wrap(exitcode.Partial, ErrNotFullExtract)
+	}
+
+	for _, e := range errs {
+		if e != nil {
+			return exitcode.Wrap(exitcode.Get(e), fmt.Errorf("%w: %w", ErrNotFullExtract, e))
+		}
 	}
 
-	if err := extractor.Extract(f, ops); err != nil {
-		fmt.Printf("⚠️ Last error processing %s: %s\n", f, err)
+	return ErrNotFullExtract
+}
+
+func extractActionFile(f string, ops *options.CmdExtractOptions) (*output.BackupReport, error) {
+	if err := extractor.ValidateTarFile(f); err != nil {
+		ops.Log.Error("File .tar not valid", "file", f, "error", err)
+
+		// not exists file is also bad argument
+		err = exitcode.Wrap(exitcode.BadArgs, err)
+
+		br := output.NewBackupReport(f)
+		br.Finish(err, extractor.GetErrorCode(err))
 
-		return err
+		return br, err
+	}
+
+	br, err := extractor.Extract(f, ops)
+	if err != nil {
+		ops.Log.Error("Last error processing backup", "file", f, "error", err)
 	}
 
-	return nil
+	return br, err
 }
@@ -0,0 +1,229 @@
+package commands
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/librun/ha-backup-tool/internal/backup"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+	"github.com/librun/ha-backup-tool/internal/extractor"
+	"github.com/librun/ha-backup-tool/internal/flags"
+	"github.com/librun/ha-backup-tool/internal/options"
+	"github.com/librun/ha-backup-tool/internal/output"
+)
+
+var (
+	ErrNotFullFind  = errors.New("some backups are not searched")
+	ErrPatternEmpty = errors.New("pattern is empty")
+)
+
+// FindReport - report of search of files in backups.
+type FindReport struct {
+	Command    string        `json:"command"`
+	Pattern    string        `json:"pattern"`
+	Status     string        `json:"status"`
+	Total      int           `json:"total"`
+	Found      int           `json:"found"`
+	ExitCode   int           `json:"exit_code"`
+	DurationMs int64         `json:"duration_ms"`
+	Backups    []*FindBackup `json:"backups"`
+}
+
+// FindBackup - files found in one backup.
+type FindBackup struct {
+	File      string       `json:"file"`
+	Name      string       `json:"name,omitempty"`
+	Date      time.Time    `json:"date"`
+	Status    string       `json:"status"`
+	Matches   []*FindMatch `json:"matches"`
+	Error     string       `json:"error,omitempty"`
+	ErrorCode string       `json:"error_code,omitempty"`
+}
+
+// FindMatch - file in backup, path starts with name of archive, e.g. homeassistant/data/automations.yaml.
+type FindMatch struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Find - command for search files in backups.
+func Find() *cli.Command {
+	return &cli.Command{
+		Name:  "find",
+		Usage: "command for search files in backups without extract",
+		Arguments: []cli.Argument{
+			&cli.StringArg{
+				Name:      "pattern",
+				UsageText: "glob of path in backup, e.g. homeassistant/data/*.yaml or automations.yaml",
+			},
+			&cli.StringArgs{
+				Name:      "backups",
+				UsageText: "backup files or dirs with backups (default backups from catalog)",
+				Min:       0,
+				Max:       -1,
+			},
+		},
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:    flags.FindCrypto,
+				Aliases: []string{"c"},
+				Usage:   "Version SecureTar v2, v3 and etc",
+			},
+		}, catalogFilterFlags()...),
+		OnUsageError: OnUsageError,
+		Before:       ApplyConfig,
+		Action:       findAction,
+	}
+}
+
+// findAction - command for search files in backups.
+func findAction(_ context.Context, c *cli.Command) error {
+	ops, err := options.NewCmdFindOptions(c)
+	if err != nil {
+		return exitcode.Wrap(exitcode.BadArgs, err)
+	}
+
+	err = findFiles(c, ops)
+	if errC := ops.Close(); err == nil {
+		err = errC
+	}
+
+	return err
+}
+
+func findFiles(c *cli.Command, ops *options.CmdFindOptions) error {
+	var start = time.Now()
+
+	if c.StringArg("pattern") == "" {
+		return exitcode.Wrap(exitcode.BadArgs, ErrPatternEmpty)
+	}
+
+	ps, err := listBackups(&ops.BackupsOptions, ops.Log)
+	if err != nil {
+		return err
+	}
+
+	r := FindReport{Command: c.Name, Pattern: c.StringArg("pattern"), Total: len(ps),
+		Backups: make([]*FindBackup, 0, len(ps))}
+
+	var errs []error
+
+	for _, p := range ps {
+		fb, errF := findInBackup(p, ops)
+		r.Backups = append(r.Backups, fb)
+
+		if errF != nil {
+			errs = append(errs, errF)
+		}
+
+		if len(fb.Matches) > 0 {
+			r.Found++
+		}
+
+		printFindBackup(fb, ops)
+	}
+
+	if r.Found == 0 {
+		ops.Out.Printf("\n⚠️  No files matched %s in %v backup file(s).\n", r.Pattern, r.Total)
+	} else {
+		ops.Out.Printf("\n✅ Files matched %s in %v of %v backup file(s).\n", r.Pattern, r.Found, r.Total)
+	}
+
+	err = getBatchError(len(ps), errs, ErrNotFullFind)
+	r.Status = getBatchStatus(len(ps), len(errs))
+	r.ExitCode = int(exitcode.Get(err))
+	r.DurationMs = time.Since(start).Milliseconds()
+
+	if errR := ops.Out.Report(r); errR != nil {
+		return errR
+	}
+
+	return err
+}
+
+// findInBackup - search files in all archives of backup, archives not readable are skipped.
+func findInBackup(p string, ops *options.CmdFindOptions) (*FindBackup, error) {
+	fb := FindBackup{File: p, Status: output.StatusSuccess, Matches: make([]*FindMatch, 0)}
+
+	b, err := backup.Open(p)
+	if err != nil {
+		ops.Log.Error("Could not open backup", "file", p, "error", err)
+		fb.fail(err)
+
+		return &fb, err
+	}
+	defer b.Close()
+
+	fb.Name, fb.Date = b.JSON.Name, b.JSON.Date
+
+	var lastErr error
+
+	for _, a := range b.Archives {
+		if !ops.Pattern.MatchArchive(a) {
+			continue
+		}
+
+		if errS := findInArchive(b, a, &fb, ops); errS != nil {
+			lastErr = extractor.WrapError(errS, b.JSON.Protected)
+			ops.Log.Error("Could not search in archive", "file", p, "archive", a.Name, "error", lastErr)
+		}
+	}
+
+	if lastErr != nil {
+		fb.fail(lastErr)
+	}
+
+	return &fb, lastErr
+}
+
+// findInArchive - read headers of files in archive and add matched files.
+func findInArchive(b *backup.Backup, a *backup.Archive, fb *FindBackup, ops *options.CmdFindOptions) error {
+	ar, err := b.OpenArchive(a, ops.Key, ops.Decryptor)
+	if err != nil {
+		return err
+	}
+
+	for {
+		h, errN := ar.Next()
+		if errors.Is(errN, io.EOF) {
+			break
+		}
+
+		if errN != nil {
+			return errors.Join(errN, ar.Close())
+		}
+
+		fp := a.FilePath(h.Name)
+		if h.Typeflag == tar.TypeDir || !ops.Pattern.Match(fp) {
+			continue
+		}
+
+		fb.Matches = append(fb.Matches, &FindMatch{Path: fp, Size: h.Size, ModTime: h.ModTime})
+	}
+
+	return ar.Close()
+}
+
+func (fb *FindBackup) fail(err error) {
+	fb.Status = output.StatusFailed
+	fb.Error = err.Error()
+	fb.ErrorCode = extractor.GetErrorCode(err)
+}
+
+func printFindBackup(fb *FindBackup, ops *options.CmdFindOptions) {
+	if len(fb.Matches) == 0 {
+		return
+	}
+
+	ops.Out.Printf("\n📦 %s (%s, %s)\n", fb.File, fb.Name, fb.Date.Local().Format(time.DateTime))
+
+	for _, m := range fb.Matches {
+		ops.Out.Printf("   %s\t%d\t%s\n", m.Path, m.Size, m.ModTime.Local().Format(time.DateTime))
+	}
+}
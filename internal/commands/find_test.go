@@ -0,0 +1,102 @@
+package commands_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/librun/ha-backup-tool/internal/commands"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+	"github.com/librun/ha-backup-tool/internal/flags"
+	"github.com/librun/ha-backup-tool/internal/output"
+)
+
+func TestFind(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	dir := t.TempDir()
+	writeTestBackup(t, dir, testBackup{name: "other", day: 1})
+
+	broken := filepath.Join(dir, "broken.tar")
+	if err := os.WriteFile(broken, []byte("not a backup"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	type match struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	tests := []struct {
+		file    string
+		status  string
+		matches []match
+	}{
+		{file: "../../test_data/test_unprotected.tar", status: output.StatusSuccess, matches: []match{
+			{path: "test/test1.txt", size: 19, modTime: time.Date(2025, 5, 26, 12, 34, 26, 0, time.UTC)},
+			{path: "test/test2.txt", size: 19, modTime: time.Date(2025, 5, 26, 12, 34, 26, 0, time.UTC)},
+		}},
+		// failure of backup not stops search in next backups
+		{file: broken, status: output.StatusFailed},
+		{file: "../../test_data/test_unprotected_with_links.tar", status: output.StatusSuccess, matches: []match{
+			{path: "test/test1.txt", size: 21, modTime: time.Date(2025, 5, 27, 15, 52, 48, 649397764, time.UTC)},
+			{path: "test/test2.txt", size: 21, modTime: time.Date(2025, 5, 27, 15, 53, 1, 3264463, time.UTC)},
+		}},
+		{file: filepath.Join(dir, "other.tar"), status: output.StatusSuccess},
+	}
+
+	app := &cli.Command{
+		Name:     "ha-backup-tool",
+		Flags:    []cli.Flag{&cli.StringFlag{Name: flags.GlobalFormat}},
+		Commands: []*cli.Command{commands.Find()},
+	}
+
+	args := []string{"ha-backup-tool", "--format", "json", "find", "test/test?.txt"}
+	for _, tt := range tests {
+		args = append(args, tt.file)
+	}
+
+	out, err := captureStdout(t, func() error { return app.Run(t.Context(), args) })
+	if exitcode.Get(err) != exitcode.Partial {
+		t.Fatalf("find error = %v, want code %d", err, exitcode.Partial)
+	}
+
+	var r commands.FindReport
+	if err = json.Unmarshal(out, &r); err != nil {
+		t.Fatalf("report %q: %v", out, err)
+	}
+
+	if r.Status != output.StatusPartial || r.ExitCode != int(exitcode.Partial) || r.Total != len(tests) || r.Found != 2 {
+		t.Errorf("report = %+v", r)
+	}
+
+	if len(r.Backups) != len(tests) {
+		t.Fatalf("backups = %d, want %d", len(r.Backups), len(tests))
+	}
+
+	for i, tt := range tests {
+		fb := r.Backups[i]
+		if fb.File != tt.file || fb.Status != tt.status || (tt.status == output.StatusFailed) != (fb.Error != "") {
+			t.Errorf("backup = %+v, want %s with status %s", fb, tt.file, tt.status)
+		}
+
+		if len(fb.Matches) != len(tt.matches) {
+			t.Errorf("%s: matches = %d, want %d", tt.file, len(fb.Matches), len(tt.matches))
+
+			continue
+		}
+
+		for j, m := range tt.matches {
+			got := fb.Matches[j]
+			if got.Path != m.path || got.Size != m.size || !got.ModTime.Equal(m.modTime) {
+				t.Errorf("%s: match = %+v, want %+v", tt.file, got, m)
+			}
+		}
+	}
+}
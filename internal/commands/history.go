@@ -0,0 +1,309 @@
+package commands
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/librun/ha-backup-tool/internal/backup"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+	"github.com/librun/ha-backup-tool/internal/extractor"
+	"github.com/librun/ha-backup-tool/internal/flags"
+	"github.com/librun/ha-backup-tool/internal/options"
+)
+
+const (
+	historyDirMode  = 0o755
+	historyFileMode = 0o600
+	historyTime     = "20060102T150405Z"
+	hashShortLen    = 12
+)
+
+var (
+	ErrNotFullHistory = errors.New("some backups are not read")
+	ErrPathEmpty      = errors.New("path of file in backup is empty")
+)
+
+// HistoryReport - report of versions of file in backups, oldest version is first.
+type HistoryReport struct {
+	Command    string            `json:"command"`
+	Path       string            `json:"path"`
+	Status     string            `json:"status"`
+	Total      int               `json:"total"`
+	Versions   []*HistoryVersion `json:"versions"`
+	Missing    []string          `json:"missing"`
+	Failed     []*BackupError    `json:"failed"`
+	ExitCode   int               `json:"exit_code"`
+	DurationMs int64             `json:"duration_ms"`
+}
+
+// HistoryVersion - version of file, same content in consecutive backups is one version.
+type HistoryVersion struct {
+	Number  int       `json:"number"`
+	Hash    string    `json:"sha256"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Backups []string  `json:"backups"`
+	SameAs  int       `json:"same_as,omitempty"`
+	File    string    `json:"file,omitempty"`
+	Diff    string    `json:"diff,omitempty"`
+}
+
+// historyBackup - backup with date from backup.json.
+type historyBackup struct {
+	path string
+	date time.Time
+}
+
+// historyRun - versions found in backups and content of last version for diff.
+type historyRun struct {
+	ops  *options.CmdHistoryOptions
+	r    *HistoryReport
+	prev []byte
+}
+
+// History - command for show versions of file in backups.
+func History() *cli.Command {
+	return &cli.Command{
+		Name:  "history",
+		Usage: "command for show versions of one file in backups with diff between versions",
+		Arguments: []cli.Argument{
+			&cli.StringArg{
+				Name:      "path",
+				UsageText: "path of file in backup, e.g. homeassistant/data/automations.yaml",
+			},
+			&cli.StringArgs{
+				Name:      "backups",
+				UsageText: "backup files or dirs with backups (default backups from catalog)",
+				Min:       0,
+				Max:       -1,
+			},
+		},
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:  flags.HistoryOutputDir,
+				Usage: "Directory for write every version of file",
+			},
+			&cli.BoolFlag{
+				Name:  flags.HistoryNoDiff,
+				Usage: "Not show diff between versions",
+			},
+			&cli.StringFlag{
+				Name:  flags.HistoryMaxFileSize,
+				Usage: "Max size of file, bigger versions are not read (default size 64MiB)",
+			},
+			&cli.StringFlag{
+				Name:    flags.HistoryCrypto,
+				Aliases: []string{"c"},
+				Usage:   "Version SecureTar v2, v3 and etc",
+			},
+		}, catalogFilterFlags()...),
+		OnUsageError: OnUsageError,
+		Before:       ApplyConfig,
+		Action:       historyAction,
+	}
+}
+
+// historyAction - command for show versions of file.
+func historyAction(_ context.Context, c *cli.Command) error {
+	ops, err := options.NewCmdHistoryOptions(c)
+	if err != nil {
+		return exitcode.Wrap(exitcode.BadArgs, err)
+	}
+
+	err = history(c, ops)
+	if errC := ops.Close(); err == nil {
+		err = errC
+	}
+
+	return err
+}
+
+func history(c *cli.Command, ops *options.CmdHistoryOptions) error {
+	var start = time.Now()
+
+	if ops.Path == "" {
+		return exitcode.Wrap(exitcode.BadArgs, ErrPathEmpty)
+	}
+
+	ps, err := listBackups(&ops.BackupsOptions, ops.Log)
+	if err != nil {
+		return err
+	}
+
+	r := HistoryReport{Command: c.Name, Path: ops.Path, Total: len(ps), Versions: make([]*HistoryVersion, 0),
+		Missing: make([]string, 0), Failed: make([]*BackupError, 0)}
+
+	var errs []error
+
+	hbs := make([]historyBackup, 0, len(ps))
+
+	for _, p := range ps {
+		e, errR := backup.ReadJSON(p)
+		if errR != nil {
+			ops.Log.Error("Could not read backup", "file", p, "error", errR)
+			r.Failed = append(r.Failed, NewBackupError(p, errR))
+			errs = append(errs, errR)
+
+			continue
+		}
+
+		hbs = append(hbs, historyBackup{path: p, date: e.Date})
+	}
+
+	slices.SortStableFunc(hbs, func(a, b historyBackup) int { return a.date.Compare(b.date) })
+
+	hr := historyRun{ops: ops, r: &r}
+
+	for _, hb := range hbs {
+		if errV := hr.add(hb); errV != nil {
+			errs = append(errs, errV)
+		}
+	}
+
+	printHistory(&r, ops)
+
+	err = getBatchError(len(ps), errs, ErrNotFullHistory)
+	if err == nil && len(r.Versions) == 0 {
+		err = exitcode.Wrap(exitcode.BadArgs, fmt.Errorf("%w: %s", backup.ErrFileNotFound, ops.Path))
+	}
+
+	r.Status = getBatchStatus(len(ps), len(errs))
+	r.ExitCode = int(exitcode.Get(err))
+	r.DurationMs = time.Since(start).Milliseconds()
+
+	if errR := ops.Out.Report(r); errR != nil {
+		return errR
+	}
+
+	return err
+}
+
+// add - read file from backup and add new version if content is changed from last version.
+func (hr *historyRun) add(hb historyBackup) error {
+	b, err := backup.Open(hb.path)
+	if err != nil {
+		hr.ops.Log.Error("Could not open backup", "file", hb.path, "error", err)
+		hr.r.Failed = append(hr.r.Failed, NewBackupError(hb.path, err))
+
+		return err
+	}
+	defer b.Close()
+
+	content, h, err := b.ReadFile(hr.ops.Path, hr.ops.Key, hr.ops.Decryptor, hr.ops.MaxFileSize)
+	if errors.Is(err, backup.ErrFileNotFound) {
+		hr.ops.Log.Debug("File not found in backup", "file", hb.path, "path", hr.ops.Path)
+		hr.r.Missing = append(hr.r.Missing, hb.path)
+
+		return nil
+	}
+
+	if err != nil {
+		err = extractor.WrapError(err, b.JSON.Protected)
+		hr.ops.Log.Error("Could not read file from backup", "file", hb.path, "path", hr.ops.Path, "error", err)
+		hr.r.Failed = append(hr.r.Failed, NewBackupError(hb.path, err))
+
+		return err
+	}
+
+	s := sha256.Sum256(content)
+	hash := hex.EncodeToString(s[:])
+
+	var last *HistoryVersion
+	if len(hr.r.Versions) > 0 {
+		last = hr.r.Versions[len(hr.r.Versions)-1]
+	}
+
+	if last != nil && last.Hash == hash {
+		last.Backups = append(last.Backups, hb.path)
+		last.To = hb.date
+
+		return nil
+	}
+
+	v := HistoryVersion{Number: len(hr.r.Versions) + 1, Hash: hash, Size: h.Size, ModTime: h.ModTime, From: hb.date,
+		To: hb.date, Backups: []string{hb.path}}
+
+	// file can be restored to content of older version, its content is already written
+	for _, o := range hr.r.Versions {
+		if o.Hash == hash {
+			v.SameAs, v.File = o.Number, o.File
+
+			break
+		}
+	}
+
+	if last != nil && !hr.ops.NoDiff {
+		v.Diff = backup.DiffText(versionLabel(last), versionLabel(&v), hr.prev, content)
+	}
+
+	if hr.ops.OutputDir != "" && v.SameAs == 0 {
+		if v.File, err = writeVersion(&v, content, hr.ops); err != nil {
+			hr.ops.Log.Error("Could not write version of file", "dir", hr.ops.OutputDir, "error", err)
+
+			return exitcode.Wrap(exitcode.IO, err)
+		}
+	}
+
+	hr.prev = content
+	hr.r.Versions = append(hr.r.Versions, &v)
+
+	return nil
+}
+
+func versionLabel(v *HistoryVersion) string {
+	return fmt.Sprintf("#%d %s", v.Number, filepath.Base(v.Backups[0]))
+}
+
+// writeVersion - write version of file into output dir, name has number and date of version.
+func writeVersion(v *HistoryVersion, content []byte, ops *options.CmdHistoryOptions) (string, error) {
+	if err := os.MkdirAll(ops.OutputDir, historyDirMode); err != nil {
+		return "", err
+	}
+
+	// file can be secrets.yaml, so it is readable only by owner
+	p := filepath.Join(ops.OutputDir, fmt.Sprintf("%03d_%s_%s", v.Number, v.From.UTC().Format(historyTime),
+		path.Base(ops.Path)))
+
+	return p, os.WriteFile(p, content, historyFileMode)
+}
+
+func printHistory(r *HistoryReport, ops *options.CmdHistoryOptions) {
+	if len(r.Versions) == 0 {
+		ops.Out.Printf("\n⚠️  File %s not found in %v backup file(s).\n", r.Path, r.Total)
+
+		return
+	}
+
+	for _, v := range r.Versions {
+		ops.Out.Printf("\n🕘 #%d %s - %s\t%d bytes\tsha256 %s\t%d backup(s)\n", v.Number,
+			v.From.Local().Format(time.DateTime), v.To.Local().Format(time.DateTime), v.Size, v.Hash[:hashShortLen],
+			len(v.Backups))
+		ops.Out.Printf("   %s\n", v.Backups[0])
+
+		if v.SameAs > 0 {
+			ops.Out.Printf("   same as #%d\n", v.SameAs)
+		}
+
+		if v.File != "" {
+			ops.Out.Printf("   written to %s\n", v.File)
+		}
+
+		if v.Diff != "" {
+			ops.Out.Printf("\n%s", v.Diff)
+		}
+	}
+
+	ops.Out.Printf("\n✅ Found %v version(s) of %s in %v backup file(s).\n", len(r.Versions), r.Path, r.Total)
+}
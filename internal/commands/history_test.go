@@ -0,0 +1,120 @@
+package commands_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/librun/ha-backup-tool/internal/commands"
+	"github.com/librun/ha-backup-tool/internal/flags"
+	"github.com/librun/ha-backup-tool/internal/output"
+)
+
+func TestHistory(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	dir := t.TempDir()
+	// file is changed in d3 and restored in d4
+	for _, tb := range []testBackup{
+		{name: "d1", day: 1, content: "a\n"},
+		{name: "d2", day: 2, content: "a\n"},
+		{name: "d3", day: 3, content: "b\n"},
+		{name: "d4", day: 4, content: "a\n"},
+	} {
+		writeTestBackup(t, dir, tb)
+	}
+
+	app := &cli.Command{
+		Name:     "ha-backup-tool",
+		Flags:    []cli.Flag{&cli.StringFlag{Name: flags.GlobalFormat}},
+		Commands: []*cli.Command{commands.History()},
+	}
+	od := filepath.Join(t.TempDir(), "versions")
+
+	out, err := captureStdout(t, func() error {
+		return app.Run(t.Context(), []string{"ha-backup-tool", "--format", "json", "history", "--output-dir", od,
+			"homeassistant/data/automations.yaml", dir})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r commands.HistoryReport
+	if err = json.Unmarshal(out, &r); err != nil {
+		t.Fatalf("report %q: %v", out, err)
+	}
+
+	if r.Status != output.StatusSuccess || r.Total != 4 || len(r.Versions) != 3 {
+		t.Fatalf("report = %+v", r)
+	}
+
+	files := []string{"001_20250101T030000Z_automations.yaml", "002_20250103T030000Z_automations.yaml"}
+	tests := []struct {
+		backups []string
+		sameAs  int
+		file    string
+		diff    []string
+	}{
+		// same content in consecutive backups is one version
+		{backups: []string{"d1", "d2"}, file: files[0]},
+		{backups: []string{"d3"}, file: files[1], diff: []string{"-a", "+b"}},
+		// restored content is not written again
+		{backups: []string{"d4"}, sameAs: 1, file: files[0], diff: []string{"-b", "+a"}},
+	}
+
+	for i, tt := range tests {
+		v := r.Versions[i]
+
+		var bs []string
+		for _, b := range v.Backups {
+			bs = append(bs, strings.TrimSuffix(filepath.Base(b), ".tar"))
+		}
+
+		if v.Number != i+1 || v.SameAs != tt.sameAs || !slices.Equal(bs, tt.backups) {
+			t.Errorf("version = %+v, want backups %v same as %d", v, tt.backups, tt.sameAs)
+		}
+
+		if v.File != filepath.Join(od, tt.file) {
+			t.Errorf("version #%d file = %s, want %s", v.Number, v.File, tt.file)
+		}
+
+		for _, l := range tt.diff {
+			if !slices.Contains(strings.Split(v.Diff, "\n"), l) {
+				t.Errorf("version #%d diff %q not has line %q", v.Number, v.Diff, l)
+			}
+		}
+
+		if len(tt.diff) == 0 && v.Diff != "" {
+			t.Errorf("version #%d diff = %q, want empty", v.Number, v.Diff)
+		}
+	}
+
+	es, err := os.ReadDir(od)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, e := range es {
+		got = append(got, e.Name())
+
+		fi, errI := e.Info()
+		if errI != nil {
+			t.Fatal(errI)
+		}
+
+		if fi.Mode().Perm() != 0o600 {
+			t.Errorf("mode of %s = %v, want 0600", e.Name(), fi.Mode().Perm())
+		}
+	}
+
+	if !slices.Equal(got, files) {
+		t.Errorf("files = %v, want %v", got, files)
+	}
+}
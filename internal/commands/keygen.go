@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+	"github.com/librun/ha-backup-tool/internal/flags"
+	"github.com/librun/ha-backup-tool/internal/key"
+	"github.com/librun/ha-backup-tool/internal/options"
+)
+
+const (
+	kitFileMode = 0o600
+	stdoutPath  = "-"
+)
+
+var (
+	ErrKitExists = errors.New("emergency kit file is exists, use --force for overwrite")
+)
+
+// Keygen - command for generate new key and emergency kit.
+func Keygen() *cli.Command {
+	return &cli.Command{
+		Name:  "keygen",
+		Usage: "command for generate new encryption key and write emergency kit",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    flags.KeygenOutput,
+				Aliases: []string{"o"},
+				Value:   key.KitFileName,
+				Usage:   "Filepath for emergency kit, - for write to stdout",
+			},
+			&cli.StringFlag{
+				Name:  flags.KeygenInstance,
+				Usage: "Name of Home Assistant instance",
+			},
+			&cli.StringFlag{
+				Name:  flags.KeygenInstanceID,
+				Usage: "Instance ID of Home Assistant (extra.instance_id of backup.json) for check on extract",
+			},
+			&cli.StringFlag{
+				Name:  flags.KeygenURL,
+				Usage: "URL of Home Assistant instance",
+			},
+			&cli.BoolFlag{
+				Name:  flags.KeygenDate,
+				Usage: "Write date of creation into emergency kit",
+			},
+			&cli.BoolFlag{
+				Name:  flags.KeygenForce,
+				Usage: "Overwrite existing emergency kit file",
+			},
+		},
+		OnUsageError: OnUsageError,
+		Before:       ApplyConfig,
+		Action:       keygenAction,
+	}
+}
+
+// keygenAction - command for generate key.
+func keygenAction(_ context.Context, c *cli.Command) error {
+	ops, err := options.NewCmdKeygenOptions(c)
+	if err != nil {
+		return exitcode.Wrap(exitcode.BadArgs, err)
+	}
+
+	err = keygen(ops)
+	if errC := ops.Close(); err == nil {
+		err = errC
+	}
+
+	return err
+}
+
+func keygen(ops *options.CmdKeygenOptions) error {
+	t, err := key.Generate()
+	if err != nil {
+		return err
+	}
+
+	kt := key.Kit{Instance: ops.Instance, InstanceID: ops.InstanceID, URL: ops.URL, Keys: []string{t}}
+	if ops.Date {
+		kt.Created = time.Now().UTC().Truncate(time.Second)
+	}
+
+	b := key.FormatKit(&kt)
+
+	// kit is result of command, so it is written to stdout also in json mode
+	if ops.Output == stdoutPath {
+		_, err = os.Stdout.Write(b)
+
+		return err
+	}
+
+	if err = writeKit(ops.Output, b, ops.Force); err != nil {
+		ops.Log.Error("Unable to write emergency kit", "file", ops.Output, "error", err)
+
+		return err
+	}
+
+	ops.Log.Info("Emergency kit is written", "file", ops.Output)
+	ops.Out.Printf("\n✅ New encryption key is written to emergency kit %s\n", ops.Output)
+	ops.Out.Println("Keep this file in a safe place, such as a password manager.")
+
+	return nil
+}
+
+// writeKit - write emergency kit readable only by owner, existing file is not overwritten without force.
+func writeKit(p string, b []byte, force bool) error {
+	fl := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		fl = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+
+	f, err := os.OpenFile(p, fl, kitFileMode)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return exitcode.Wrap(exitcode.BadArgs, fmt.Errorf("%w: %s", ErrKitExists, p))
+		}
+
+		return err
+	}
+
+	if _, err = f.Write(b); err != nil {
+		return errors.Join(err, f.Close())
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,266 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+	"github.com/librun/ha-backup-tool/internal/flags"
+	"github.com/librun/ha-backup-tool/internal/key"
+	"github.com/librun/ha-backup-tool/internal/keystore"
+	"github.com/librun/ha-backup-tool/internal/options"
+)
+
+// KeyListItem - key in list of key store, key itself is not shown.
+type KeyListItem struct {
+	Label   string    `json:"label"`
+	Name    string    `json:"name,omitempty"`
+	Added   time.Time `json:"added"`
+	Default bool      `json:"default"`
+}
+
+// Keys - command group for manage encrypted key store.
+func Keys() *cli.Command {
+	return &cli.Command{
+		Name:  "keys",
+		Usage: "command for manage keys in encrypted key store",
+		Commands: []*cli.Command{
+			{
+				Name:  "add",
+				Usage: "add key from emergency kit, label is instance ID of Home Assistant",
+				Arguments: []cli.Argument{
+					&cli.StringArg{
+						Name:      "kit",
+						UsageText: "emergency kit file",
+					},
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  flags.KeysLabel,
+						Usage: "Label of key, instance ID of Home Assistant (default instance ID from emergency kit)",
+					},
+					&cli.StringFlag{
+						Name:  flags.KeysName,
+						Usage: "Name of key (default instance name from emergency kit)",
+					},
+					&cli.BoolFlag{
+						Name:  flags.KeysDefault,
+						Usage: "Set key as default",
+					},
+				},
+				OnUsageError: OnUsageError,
+				Before:       ApplyConfig,
+				Action:       keysAction(keysAdd),
+			},
+			{
+				Name:         "list",
+				Usage:        "list keys in key store",
+				OnUsageError: OnUsageError,
+				Before:       ApplyConfig,
+				Action:       keysAction(keysList),
+			},
+			{
+				Name:  "remove",
+				Usage: "remove key from key store",
+				Arguments: []cli.Argument{
+					&cli.StringArg{
+						Name:      "label",
+						UsageText: "label of key",
+					},
+				},
+				OnUsageError: OnUsageError,
+				Before:       ApplyConfig,
+				Action:       keysAction(keysRemove),
+			},
+			{
+				Name:  "default",
+				Usage: "set default key, it is tried first if no key has label of backup instance",
+				Arguments: []cli.Argument{
+					&cli.StringArg{
+						Name:      "label",
+						UsageText: "label of key",
+					},
+				},
+				OnUsageError: OnUsageError,
+				Before:       ApplyConfig,
+				Action:       keysAction(keysDefault),
+			},
+		},
+	}
+}
+
+// keysAction - create options for command of key store.
+func keysAction(f func(c *cli.Command, ops *options.CmdKeysOptions) error) cli.ActionFunc {
+	return func(_ context.Context, c *cli.Command) error {
+		ops, err := options.NewCmdKeysOptions(c)
+		if err != nil {
+			return exitcode.Wrap(exitcode.BadArgs, err)
+		}
+
+		err = f(c, ops)
+		if errC := ops.Close(); err == nil {
+			err = errC
+		}
+
+		return err
+	}
+}
+
+func keysAdd(c *cli.Command, ops *options.CmdKeysOptions) error {
+	p := c.StringArg("kit")
+	if p == "" {
+		return exitcode.Wrap(exitcode.BadArgs, key.ErrFileNotValid)
+	}
+
+	kt, err := key.ReadKit(p)
+	if err != nil {
+		ops.Log.Error("Could not find encryption key in emergency kit file", "file", p, "error", err)
+
+		return exitcode.Wrap(exitcode.BadArgs, err)
+	}
+
+	e := keystore.Entry{
+		Label: c.String(flags.KeysLabel),
+		Name:  c.String(flags.KeysName),
+		Key:   kt.Keys[0],
+		Added: time.Now().UTC().Truncate(time.Second),
+	}
+
+	if e.Label == "" {
+		e.Label = kt.InstanceID
+	}
+
+	if e.Name == "" {
+		e.Name = kt.Instance
+	}
+
+	if len(kt.Keys) > 1 {
+		ops.Log.Warn("Emergency kit has several keys, first key is added", "file", p, "keys", len(kt.Keys))
+	}
+
+	return updateStore(ops, true, func(st *keystore.Store) error {
+		replaced, errA := st.Add(&e)
+		if errA != nil {
+			return errA
+		}
+
+		if c.Bool(flags.KeysDefault) || len(st.Entries) == 1 {
+			st.Default = e.Label
+		}
+
+		if replaced {
+			ops.Out.Printf("✅ Key %s is replaced in key store %s\n", e.Label, ops.StorePath)
+		} else {
+			ops.Out.Printf("✅ Key %s is added to key store %s\n", e.Label, ops.StorePath)
+		}
+
+		return nil
+	})
+}
+
+func keysList(_ *cli.Command, ops *options.CmdKeysOptions) error {
+	st, _, err := openStore(ops, false)
+	if err != nil {
+		return err
+	}
+
+	ls := make([]KeyListItem, 0, len(st.Entries))
+
+	for _, e := range st.Entries {
+		it := KeyListItem{Label: e.Label, Name: e.Name, Added: e.Added, Default: e.Label == st.Default}
+		ls = append(ls, it)
+
+		if !ops.Out.IsJSON() {
+			d := ""
+			if it.Default {
+				d = " (default)"
+			}
+
+			ops.Out.Printf("%s\t%s\t%s%s\n", it.Label, it.Name, it.Added.Format(time.DateOnly), d)
+		}
+	}
+
+	if len(ls) == 0 && !ops.Out.IsJSON() {
+		ops.Out.Println("⚠️  Key store is empty.")
+	}
+
+	return ops.Out.Report(ls)
+}
+
+func keysRemove(c *cli.Command, ops *options.CmdKeysOptions) error {
+	l := c.StringArg("label")
+
+	return updateStore(ops, false, func(st *keystore.Store) error {
+		if err := st.Remove(l); err != nil {
+			return err
+		}
+
+		ops.Out.Printf("✅ Key %s is removed from key store %s\n", l, ops.StorePath)
+
+		return nil
+	})
+}
+
+func keysDefault(c *cli.Command, ops *options.CmdKeysOptions) error {
+	l := c.StringArg("label")
+
+	return updateStore(ops, false, func(st *keystore.Store) error {
+		if err := st.SetDefault(l); err != nil {
+			return err
+		}
+
+		ops.Out.Printf("✅ Key %s is default in key store %s\n", l, ops.StorePath)
+
+		return nil
+	})
+}
+
+// openStore - ask passphrase and decrypt key store, for new store passphrase is asked twice.
+func openStore(ops *options.CmdKeysOptions, create bool) (*keystore.Store, string, error) {
+	_, err := os.Stat(ops.StorePath)
+	isNew := errors.Is(err, os.ErrNotExist)
+
+	if isNew && !create {
+		return &keystore.Store{Entries: make([]*keystore.Entry, 0)}, "", nil
+	}
+
+	p, err := keystore.Passphrase(os.Stdin, ops.Out.Writer(), isNew)
+	if err != nil {
+		return nil, "", err
+	}
+
+	st, err := keystore.Load(ops.StorePath, p)
+	if err != nil {
+		ops.Log.Error("Could not open key store", "file", ops.StorePath, "error", err)
+
+		return nil, "", err
+	}
+
+	return st, p, nil
+}
+
+// updateStore - change key store by function f and save it.
+func updateStore(ops *options.CmdKeysOptions, create bool, f func(st *keystore.Store) error) error {
+	st, p, err := openStore(ops, create)
+	if err != nil {
+		return err
+	}
+
+	if err = f(st); err != nil {
+		return err
+	}
+
+	if err = st.Save(ops.StorePath, p); err != nil {
+		ops.Log.Error("Could not save key store", "file", ops.StorePath, "error", err)
+
+		return err
+	}
+
+	ops.Log.Info("Key store is saved", "file", ops.StorePath)
+
+	return nil
+}
@@ -0,0 +1,305 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/librun/ha-backup-tool/internal/backup"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+	"github.com/librun/ha-backup-tool/internal/extractor"
+	"github.com/librun/ha-backup-tool/internal/flags"
+	"github.com/librun/ha-backup-tool/internal/options"
+	"github.com/librun/ha-backup-tool/internal/retention"
+)
+
+const (
+	defaultKeepDaily   = 7
+	defaultKeepWeekly  = 4
+	defaultKeepMonthly = 12
+)
+
+var (
+	ErrNotFullPrune = errors.New("some backups are not checked or removed")
+)
+
+// PruneReport - report of remove backups not kept by policy.
+type PruneReport struct {
+	Command    string         `json:"command"`
+	Dirs       []string       `json:"dirs"`
+	DryRun     bool           `json:"dry_run"`
+	Status     string         `json:"status"`
+	Total      int            `json:"total"`
+	Kept       int            `json:"kept"`
+	Removed    int            `json:"removed"`
+	Backups    []*PruneBackup `json:"backups"`
+	Failed     []*BackupError `json:"failed"`
+	ExitCode   int            `json:"exit_code"`
+	DurationMs int64          `json:"duration_ms"`
+}
+
+// PruneBackup - decision of policy for backup, reasons are periods for which backup is kept.
+type PruneBackup struct {
+	File     string    `json:"file"`
+	Instance string    `json:"instance_id"`
+	Date     time.Time `json:"date"`
+	Keep     bool      `json:"keep"`
+	Reasons  []string  `json:"reasons"`
+	Removed  bool      `json:"removed"`
+}
+
+// Prune - command for remove old backups by grandfather-father-son policy.
+func Prune() *cli.Command {
+	return &cli.Command{
+		Name:  "prune",
+		Usage: "command for remove old backups from dirs by policy with daily, weekly and monthly backups",
+		Arguments: []cli.Argument{
+			&cli.StringArgs{
+				Name:      "dirs",
+				UsageText: "dirs with backups, backups in sub dirs are included",
+				Min:       1,
+				Max:       -1,
+			},
+		},
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  flags.PruneKeepDaily,
+				Usage: "Count of days for which newest backup of day is kept",
+				Value: defaultKeepDaily,
+			},
+			&cli.IntFlag{
+				Name:  flags.PruneKeepWeekly,
+				Usage: "Count of weeks for which newest backup of week is kept",
+				Value: defaultKeepWeekly,
+			},
+			&cli.IntFlag{
+				Name:  flags.PruneKeepMonthly,
+				Usage: "Count of months for which newest backup of month is kept",
+				Value: defaultKeepMonthly,
+			},
+			&cli.BoolFlag{
+				Name:  flags.PruneDryRun,
+				Usage: "Show backups which will be removed without remove",
+			},
+			&cli.BoolFlag{
+				Name: flags.PruneVerify,
+				Usage: "Read all archives of every backup before policy, backups not passed verification are not " +
+					"removed and not kept by policy",
+			},
+			&cli.StringFlag{
+				Name:    flags.PruneCrypto,
+				Aliases: []string{"c"},
+				Usage:   "Version SecureTar v2, v3 and etc",
+			},
+		},
+		OnUsageError: OnUsageError,
+		Before:       ApplyConfig,
+		Action:       pruneAction,
+	}
+}
+
+// pruneAction - command for remove old backups.
+func pruneAction(_ context.Context, c *cli.Command) error {
+	ops, err := options.NewCmdPruneOptions(c)
+	if err != nil {
+		return exitcode.Wrap(exitcode.BadArgs, err)
+	}
+
+	err = prune(c, ops)
+	if errC := ops.Close(); err == nil {
+		err = errC
+	}
+
+	return err
+}
+
+func prune(c *cli.Command, ops *options.CmdPruneOptions) error {
+	var start = time.Now()
+
+	ps, err := findPruneBackups(ops.Dirs)
+	if err != nil {
+		return err
+	}
+
+	r := PruneReport{Command: c.Name, Dirs: ops.Dirs, DryRun: ops.DryRun, Total: len(ps),
+		Backups: make([]*PruneBackup, 0, len(ps)), Failed: make([]*BackupError, 0)}
+
+	var errs []error
+
+	rbs := make([]retention.Backup, 0, len(ps))
+
+	for _, p := range ps {
+		rb, err := readPruneBackup(p, ops)
+		if err != nil {
+			// backup not read is not removed and not kept by policy
+			ops.Log.Error("Could not check backup", "file", p, "error", err)
+			r.Failed = append(r.Failed, NewBackupError(p, err))
+			errs = append(errs, err)
+
+			continue
+		}
+
+		rbs = append(rbs, rb)
+	}
+
+	ds := ops.Policy.Apply(rbs)
+
+	// file of kept backup is never removed, even if it is found in dirs twice
+	kept := make(map[string]bool, len(ds))
+	for _, d := range ds {
+		if d.Keep() {
+			kept[d.Path] = true
+		}
+	}
+
+	for _, d := range ds {
+		pb := PruneBackup{File: d.Path, Instance: d.Instance, Date: d.Date, Keep: d.Keep(), Reasons: d.Reasons}
+		r.Backups = append(r.Backups, &pb)
+
+		if pb.Keep {
+			r.Kept++
+
+			continue
+		}
+
+		if ops.DryRun || kept[pb.File] {
+			continue
+		}
+
+		if err := os.Remove(pb.File); err != nil {
+			err = exitcode.Wrap(exitcode.IO, err)
+			ops.Log.Error("Could not remove backup", "file", pb.File, "error", err)
+			r.Failed = append(r.Failed, NewBackupError(pb.File, err))
+			errs = append(errs, err)
+
+			continue
+		}
+
+		ops.Log.Info("Backup is removed", "file", pb.File, "date", pb.Date)
+
+		pb.Removed = true
+		r.Removed++
+	}
+
+	printPrune(&r, ops)
+
+	err = getBatchError(len(ps), errs, ErrNotFullPrune)
+	r.Status = getBatchStatus(len(ps), len(errs))
+	r.ExitCode = int(exitcode.Get(err))
+	r.DurationMs = time.Since(start).Milliseconds()
+
+	if errR := ops.Out.Report(r); errR != nil {
+		return errR
+	}
+
+	return err
+}
+
+// findPruneBackups - find backups in dirs, backup found in overlapping dirs is added once with absolute path.
+func findPruneBackups(dirs []string) ([]string, error) {
+	var ps []string
+
+	seen := make(map[string]bool)
+
+	for _, d := range dirs {
+		if s, err := os.Stat(d); err != nil || !s.IsDir() {
+			return nil, exitcode.Wrap(exitcode.BadArgs, fmt.Errorf("%w: %s", ErrDirNotValid, d))
+		}
+
+		fs, err := backup.Find(d)
+		if err != nil {
+			return nil, exitcode.Wrap(exitcode.IO, fmt.Errorf("%w: %s", err, d))
+		}
+
+		for _, p := range fs {
+			if p, err = filepath.Abs(p); err != nil {
+				return nil, exitcode.Wrap(exitcode.IO, err)
+			}
+
+			// same file can be found by link of dir
+			k, errE := filepath.EvalSymlinks(p)
+			if errE != nil {
+				k = p
+			}
+
+			if !seen[k] {
+				seen[k] = true
+				ps = append(ps, p)
+			}
+		}
+	}
+
+	return ps, nil
+}
+
+// readPruneBackup - read date and instance of backup, with verify all archives of backup are read.
+func readPruneBackup(p string, ops *options.CmdPruneOptions) (retention.Backup, error) {
+	if !ops.Verify {
+		e, err := backup.ReadJSON(p)
+		if err != nil {
+			return retention.Backup{}, err
+		}
+
+		return retention.Backup{Path: p, Instance: e.Extra.InstanceID, Date: e.Date.Local()}, nil
+	}
+
+	b, err := backup.Open(p)
+	if err != nil {
+		return retention.Backup{}, err
+	}
+	defer b.Close()
+
+	ops.Log.Debug("Verify backup", "file", p)
+
+	if err = b.Verify(ops.Key, ops.Decryptor); err != nil {
+		return retention.Backup{}, extractor.WrapError(err, b.JSON.Protected)
+	}
+
+	return retention.Backup{Path: p, Instance: b.JSON.Extra.InstanceID, Date: b.JSON.Date.Local()}, nil
+}
+
+func printPrune(r *PruneReport, ops *options.CmdPruneOptions) {
+	var instance *string
+
+	for _, b := range r.Backups {
+		if instance == nil || *instance != b.Instance {
+			instance = &b.Instance
+			ops.Out.Printf("\n🏠 Instance %s\n", b.Instance)
+		}
+
+		var action string
+
+		switch {
+		case b.Keep:
+			action = "keep   " + strings.Join(b.Reasons, ", ")
+		case b.Removed:
+			action = "removed"
+		case r.DryRun:
+			action = "remove (dry run)"
+		default:
+			action = "not removed"
+		}
+
+		ops.Out.Printf("   %s\t%s\t%s\n", b.Date.Format(time.DateTime), b.File, action)
+	}
+
+	for _, f := range r.Failed {
+		ops.Out.Printf("⚠️  %s not checked or removed, backup is left: %s\n", f.File, f.Error)
+	}
+
+	if r.DryRun {
+		ops.Out.Printf("\n✅ Dry run: %v backup file(s) will be removed, %v kept of %v in %s.\n",
+			len(r.Backups)-r.Kept, r.Kept, r.Total, strings.Join(r.Dirs, ", "))
+
+		return
+	}
+
+	ops.Out.Printf("\n✅ Removed %v backup file(s), %v kept of %v in %s.\n", r.Removed, r.Kept, r.Total,
+		strings.Join(r.Dirs, ", "))
+}
@@ -0,0 +1,201 @@
+package commands_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/librun/ha-backup-tool/internal/commands"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+)
+
+// testBackup - backup of test, date is day of January 2025, broken archive is not valid gzip.
+// Content is content of homeassistant/data/automations.yaml, default "a".
+type testBackup struct {
+	name    string
+	day     int
+	broken  bool
+	noJSON  bool
+	content string
+}
+
+// writeTestBackup - write backup with homeassistant archive into dir.
+func writeTestBackup(t *testing.T, dir string, tb testBackup) {
+	t.Helper()
+
+	var gz bytes.Buffer
+
+	gw := gzip.NewWriter(&gz)
+	tw := tar.NewWriter(gw)
+
+	content := tb.content
+	if content == "" {
+		content = "a"
+	}
+
+	h := tar.Header{Name: "./data/automations.yaml", Mode: 0o644, Size: int64(len(content))}
+	if err := tw.WriteHeader(&h); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tw.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := gz.Bytes()
+	if tb.broken {
+		// byte of crc32 in trailer of gzip
+		archive[len(archive)-5] ^= 0xff
+	}
+
+	files := map[string][]byte{"homeassistant.tar.gz": archive}
+	if !tb.noJSON {
+		files["backup.json"] = fmt.Appendf(nil, `{"slug":%q,"name":"Full","type":"full",
+"date":"2025-01-%02dT03:00:00+00:00","extra":{"instance_id":"home"},"homeassistant":{"version":"2025.1.0"}}`,
+			tb.name, tb.day)
+	}
+
+	var b bytes.Buffer
+
+	tw = tar.NewWriter(&b)
+	for _, n := range []string{"backup.json", "homeassistant.tar.gz"} {
+		c, ok := files[n]
+		if !ok {
+			continue
+		}
+
+		if err := tw.WriteHeader(&tar.Header{Name: n, Mode: 0o644, Size: int64(len(c))}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tw.Write(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, tb.name+".tar"), b.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	days := []testBackup{{name: "d1", day: 1}, {name: "d2", day: 2}, {name: "d3", day: 3}, {name: "d4", day: 4}}
+
+	tests := []struct {
+		name    string
+		backups []testBackup
+		args    []string
+		dirs    []string
+		link    bool
+		want    []string
+		code    exitcode.Code
+	}{
+		{
+			name:    "not kept removed",
+			backups: days,
+			want:    []string{"d3", "d4"},
+		},
+		{
+			name:    "dry run",
+			backups: days,
+			args:    []string{"--dry-run"},
+			want:    []string{"d1", "d2", "d3", "d4"},
+		},
+		{
+			// backup not passed verification is left and not counted as kept
+			name:    "verify failed",
+			backups: append(slices.Clone(days), testBackup{name: "d5", day: 5, broken: true}),
+			args:    []string{"--verify"},
+			want:    []string{"d3", "d4", "d5"},
+			code:    exitcode.Partial,
+		},
+		{
+			name:    "backup.json not read",
+			backups: append(slices.Clone(days), testBackup{name: "other", noJSON: true}),
+			want:    []string{"d3", "d4", "other"},
+			code:    exitcode.Partial,
+		},
+		{
+			// backup found twice is not removed as not kept
+			name:    "overlapping dirs",
+			backups: days,
+			dirs:    []string{"/.", "/"},
+			want:    []string{"d3", "d4"},
+		},
+		{
+			name:    "dir and link to dir",
+			backups: days,
+			link:    true,
+			want:    []string{"d3", "d4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// config file and key store of user are not used
+			t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+			t.Setenv("HOME", t.TempDir())
+
+			dir := t.TempDir()
+			for _, tb := range tt.backups {
+				writeTestBackup(t, dir, tb)
+			}
+
+			app := &cli.Command{Name: "ha-backup-tool", Commands: []*cli.Command{commands.Prune()}}
+			args := append([]string{"ha-backup-tool", "prune", "--keep-daily", "2", "--keep-weekly", "0",
+				"--keep-monthly", "0"}, tt.args...)
+
+			args = append(args, dir)
+			for _, d := range tt.dirs {
+				args = append(args, dir+d)
+			}
+
+			if tt.link {
+				l := filepath.Join(t.TempDir(), "link")
+				if err := os.Symlink(dir, l); err != nil {
+					t.Fatal(err)
+				}
+
+				args = append(args, l)
+			}
+
+			err := app.Run(t.Context(), args)
+			if exitcode.Get(err) != tt.code {
+				t.Fatalf("prune error = %v, want code %d", err, tt.code)
+			}
+
+			es, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, e := range es {
+				got = append(got, e.Name()[:len(e.Name())-len(filepath.Ext(e.Name()))])
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("backups left = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
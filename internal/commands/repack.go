@@ -0,0 +1,224 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/librun/ha-backup-tool/internal/backup"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+	"github.com/librun/ha-backup-tool/internal/extractor"
+	"github.com/librun/ha-backup-tool/internal/flags"
+	"github.com/librun/ha-backup-tool/internal/options"
+	"github.com/librun/ha-backup-tool/internal/output"
+)
+
+var (
+	ErrRepackArgs     = errors.New("backup and output must be set")
+	ErrOutputExists   = errors.New("output file is exists, use --force for overwrite")
+	ErrOutputIsBackup = errors.New("output file is same as backup")
+)
+
+// RepackReport - report of copy of backup without removed parts.
+type RepackReport struct {
+	Command    string   `json:"command"`
+	Backup     string   `json:"backup"`
+	Output     string   `json:"output"`
+	Status     string   `json:"status"`
+	Archives   []string `json:"removed_archives"`
+	Files      []string `json:"removed_files"`
+	OldSize    int64    `json:"old_size"`
+	NewSize    int64    `json:"new_size"`
+	ExitCode   int      `json:"exit_code"`
+	DurationMs int64    `json:"duration_ms"`
+	Error      string   `json:"error,omitempty"`
+	ErrorCode  string   `json:"error_code,omitempty"`
+}
+
+// Repack - command for copy of backup without addons, folders and files.
+func Repack() *cli.Command {
+	return &cli.Command{
+		Name:  "repack",
+		Usage: "command for copy backup without addons, folders or files, e.g. without database",
+		Arguments: []cli.Argument{
+			&cli.StringArg{
+				Name:      "backup",
+				UsageText: "backup file",
+			},
+			&cli.StringArg{
+				Name:      "output",
+				UsageText: "new backup file",
+			},
+		},
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:  flags.RepackExcludeAddon,
+				Usage: "Slug of addon which is removed from backup, can be set several times",
+			},
+			&cli.StringSliceFlag{
+				Name:  flags.RepackExcludeFolder,
+				Usage: "Folder which is removed from backup (share, media, ssl, addons/local), can be set several times",
+			},
+			&cli.BoolFlag{
+				Name:  flags.RepackExcludeDatabase,
+				Usage: "Remove database of recorder home-assistant_v2.db from Home Assistant archive",
+			},
+			&cli.StringSliceFlag{
+				Name: flags.RepackExclude,
+				Usage: "Glob of files which are removed from archives, e.g. homeassistant/data/tts/**, " +
+					"can be set several times",
+			},
+			&cli.BoolFlag{
+				Name:  flags.RepackForce,
+				Usage: "Overwrite output file if it exists",
+			},
+			&cli.StringFlag{
+				Name:    flags.RepackCrypto,
+				Aliases: []string{"c"},
+				Usage:   "Version SecureTar v2, v3 and etc",
+			},
+		},
+		OnUsageError: OnUsageError,
+		Before:       ApplyConfig,
+		Action:       repackAction,
+	}
+}
+
+// repackAction - command for copy of backup without removed parts.
+func repackAction(_ context.Context, c *cli.Command) error {
+	ops, err := options.NewCmdRepackOptions(c)
+	if err != nil {
+		return exitcode.Wrap(exitcode.BadArgs, err)
+	}
+
+	err = repack(c, ops)
+	if errC := ops.Close(); err == nil {
+		err = errC
+	}
+
+	return err
+}
+
+func repack(c *cli.Command, ops *options.CmdRepackOptions) error {
+	var start = time.Now()
+
+	r := RepackReport{Command: c.Name, Backup: ops.Backup, Output: ops.Output, Archives: make([]string, 0),
+		Files: make([]string, 0)}
+
+	if err := checkRepackArgs(ops); err != nil {
+		return r.fail(exitcode.Wrap(exitcode.BadArgs, err), start, ops)
+	}
+
+	b, err := backup.Open(ops.Backup)
+	if errors.Is(err, os.ErrNotExist) {
+		err = exitcode.Wrap(exitcode.BadArgs, err)
+	}
+
+	if err != nil {
+		ops.Log.Error("Could not open backup", "file", ops.Backup, "error", err)
+
+		return r.fail(err, start, ops)
+	}
+	defer b.Close()
+
+	res, err := writeRepack(b, ops)
+	if err != nil {
+		err = extractor.WrapError(err, b.JSON.Protected)
+		ops.Log.Error("Could not repack backup", "file", ops.Backup, "error", err)
+
+		return r.fail(err, start, ops)
+	}
+
+	r.Archives, r.Files = res.Archives, res.Files
+
+	if fi, errS := os.Stat(ops.Backup); errS == nil {
+		r.OldSize = fi.Size()
+	}
+
+	if fi, errS := os.Stat(ops.Output); errS == nil {
+		r.NewSize = fi.Size()
+	}
+
+	r.Status = output.StatusSuccess
+	r.DurationMs = time.Since(start).Milliseconds()
+
+	ops.Log.Info("Backup is repacked", "file", ops.Backup, "output", ops.Output)
+
+	for _, a := range r.Archives {
+		ops.Out.Printf("🗑️  %s\n", a)
+	}
+
+	for _, f := range r.Files {
+		ops.Out.Printf("🗑️  %s\n", f)
+	}
+
+	ops.Out.Printf("\n✅ Backup %s is repacked to %s: %v archive(s) and %v file(s) removed, size %d → %d bytes.\n",
+		r.Backup, r.Output, len(r.Archives), len(r.Files), r.OldSize, r.NewSize)
+
+	return ops.Out.Report(r)
+}
+
+// fail - report of not repacked backup, output is not written.
+func (r *RepackReport) fail(err error, start time.Time, ops *options.CmdRepackOptions) error {
+	r.Status = output.StatusFailed
+	r.ExitCode = int(exitcode.Get(err))
+	r.Error = err.Error()
+	r.ErrorCode = extractor.GetErrorCode(err)
+	r.DurationMs = time.Since(start).Milliseconds()
+
+	if errR := ops.Out.Report(r); errR != nil {
+		return errR
+	}
+
+	return err
+}
+
+// checkRepackArgs - output must not be backup, existing output is overwritten only with force.
+func checkRepackArgs(ops *options.CmdRepackOptions) error {
+	if ops.Backup == "" || ops.Output == "" {
+		return ErrRepackArgs
+	}
+
+	fo, err := os.Stat(ops.Output)
+	if err != nil {
+		return nil //nolint:nilerr // output not exists
+	}
+
+	if fb, errB := os.Stat(ops.Backup); errB == nil && os.SameFile(fb, fo) {
+		return fmt.Errorf("%w: %s", ErrOutputIsBackup, ops.Output)
+	}
+
+	if !ops.Force {
+		return fmt.Errorf("%w: %s", ErrOutputExists, ops.Output)
+	}
+
+	return nil
+}
+
+// writeRepack - write new backup into temp file near output, output is replaced only by full backup.
+func writeRepack(b *backup.Backup, ops *options.CmdRepackOptions) (*backup.RepackResult, error) {
+	f, err := os.CreateTemp(filepath.Dir(ops.Output), ".repack-*"+backup.ExtTar)
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.IO, err)
+	}
+
+	res, err := b.Repack(f, &ops.Repack, ops.Key, ops.Decryptor)
+	if errC := f.Close(); err == nil {
+		err = errC
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), ops.Output)
+	}
+
+	if err != nil {
+		return nil, errors.Join(err, os.Remove(f.Name()))
+	}
+
+	return res, nil
+}
@@ -0,0 +1,93 @@
+package commands_test
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/librun/ha-backup-tool/internal/commands"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+	"github.com/librun/ha-backup-tool/internal/flags"
+	"github.com/librun/ha-backup-tool/internal/output"
+)
+
+func TestRepack_FailedReport(t *testing.T) {
+	dir := t.TempDir()
+	writeTestBackup(t, dir, testBackup{name: "broken", day: 1, broken: true})
+
+	tests := []struct {
+		name   string
+		backup string
+		code   exitcode.Code
+	}{
+		{name: "backup not exists", backup: filepath.Join(dir, "not_exists.tar"), code: exitcode.BadArgs},
+		{name: "archive not read", backup: filepath.Join(dir, "broken.tar"), code: exitcode.Corrupt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+			t.Setenv("HOME", t.TempDir())
+
+			app := &cli.Command{
+				Name:     "ha-backup-tool",
+				Flags:    []cli.Flag{&cli.StringFlag{Name: flags.GlobalFormat}},
+				Commands: []*cli.Command{commands.Repack()},
+			}
+			o := filepath.Join(t.TempDir(), "out.tar")
+
+			out, err := captureStdout(t, func() error {
+				return app.Run(t.Context(), []string{"ha-backup-tool", "--format", "json", "repack", "--exclude",
+					"homeassistant/data/automations.yaml", tt.backup, o})
+			})
+			if exitcode.Get(err) != tt.code {
+				t.Fatalf("repack error = %v, want code %d", err, tt.code)
+			}
+
+			var r commands.RepackReport
+			if err = json.Unmarshal(out, &r); err != nil {
+				t.Fatalf("report %q: %v", out, err)
+			}
+
+			if r.Status != output.StatusFailed || r.ExitCode != int(tt.code) || r.Error == "" || r.ErrorCode == "" {
+				t.Errorf("report = %+v, want failed with code %d", r, tt.code)
+			}
+
+			if _, err = os.Stat(o); err == nil {
+				t.Errorf("output %s is written", o)
+			}
+		})
+	}
+}
+
+// captureStdout - run f and read report written to stdout.
+func captureStdout(t *testing.T, f func() error) ([]byte, error) {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+
+	errF := f()
+
+	os.Stdout = stdout
+
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return out, errF
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+	"go.yaml.in/yaml/v3"
+
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+	"github.com/librun/ha-backup-tool/internal/flags"
+)
+
+const (
+	dirName  = "ha-backup-tool"
+	fileName = "config.yaml"
+	homeDir  = "~"
+)
+
+var (
+	ErrConfigNotValid  = errors.New("config file not valid")
+	ErrProfileNotFound = errors.New("profile not found in config file")
+	ErrOptionNotValid  = errors.New("option in config file not valid")
+)
+
+// replacedBy - flag is not set from config if user set flag which replaces it, e.g. --verbose is short for debug
+// level, so log level from config not overrides verbose from command line.
+var replacedBy = map[string]string{flags.GlobalLogLevel: flags.GlobalVerbose}
+
+// Values - values of flags by long name of flag.
+type Values map[string]any
+
+// Section - values of global flags and values of flags of commands by name of command, e.g. "extract" or "keys add".
+type Section struct {
+	Global   Values            `yaml:"global"`
+	Commands map[string]Values `yaml:"commands"`
+}
+
+// Config - default values of flags and named profiles, e.g. one profile per Home Assistant instance.
+type Config struct {
+	Section  `yaml:",inline"`
+	Profile  string              `yaml:"profile"`
+	Profiles map[string]*Section `yaml:"profiles"`
+}
+
+// DefaultPath - path of config file in user config dir.
+func DefaultPath() (string, error) {
+	d, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(d, dirName, fileName), nil
+}
+
+// Load - read config file.
+func Load(p string) (*Config, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(b)
+}
+
+// Parse - parse config in yaml format, unknown sections are errors.
+func Parse(b []byte) (*Config, error) {
+	var c Config
+
+	d := yaml.NewDecoder(bytes.NewReader(b))
+	d.KnownFields(true)
+
+	if err := d.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, exitcode.Wrap(exitcode.BadArgs, fmt.Errorf("%w: %w", ErrConfigNotValid, err))
+	}
+
+	return &c, nil
+}
+
+// Apply - set flags of command not set by user, values of profile are used before default values of config.
+// Empty profile is profile from config file.
+func (c *Config) Apply(cmd *cli.Command, profile string) error {
+	if profile == "" {
+		profile = c.Profile
+	}
+
+	ss := make([]*Section, 0, 2) //nolint:mnd // profile and default values
+	if profile != "" {
+		s, ok := c.Profiles[profile]
+		if !ok {
+			return exitcode.Wrap(exitcode.BadArgs, fmt.Errorf("%w: %s", ErrProfileNotFound, profile))
+		}
+
+		ss = append(ss, s)
+	}
+
+	ss = append(ss, &c.Section)
+
+	name := strings.TrimPrefix(cmd.FullName(), cmd.Root().Name+" ")
+
+	for _, s := range ss {
+		if s == nil {
+			continue
+		}
+
+		// command can have own flag with same name as global flag, so global flags are set in root command
+		if err := apply(cmd, s.Commands[name]); err != nil {
+			return err
+		}
+
+		if err := apply(cmd.Root(), s.Global); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// apply - set values of flags not set before.
+func apply(cmd *cli.Command, vs Values) error {
+	for _, n := range slices.Sorted(maps.Keys(vs)) {
+		if vs[n] == nil {
+			continue
+		}
+
+		f := findFlag(cmd, n)
+		if f == nil {
+			return exitcode.Wrap(exitcode.BadArgs, fmt.Errorf("%w: unknown option %s", ErrOptionNotValid, n))
+		}
+
+		if f.IsSet() || isReplaced(cmd, n) {
+			continue
+		}
+
+		var ss []string
+
+		switch v := vs[n].(type) {
+		case []any:
+			for _, i := range v {
+				ss = append(ss, toString(i))
+			}
+		default:
+			ss = []string{toString(v)}
+		}
+
+		// list for flag with one value is comma separated list, as in include and exclude
+		if m, ok := f.(cli.DocGenerationMultiValueFlag); !ok || !m.IsMultiValueFlag() {
+			ss = []string{strings.Join(ss, ",")}
+		}
+
+		for _, s := range ss {
+			if err := cmd.Set(n, s); err != nil {
+				return exitcode.Wrap(exitcode.BadArgs, fmt.Errorf("%w: %s: %w", ErrOptionNotValid, n, err))
+			}
+		}
+	}
+
+	return nil
+}
+
+// isReplaced - flag which replaces flag by name is set.
+func isReplaced(cmd *cli.Command, n string) bool {
+	r, ok := replacedBy[n]
+	if !ok {
+		return false
+	}
+
+	f := findFlag(cmd, r)
+
+	return f != nil && f.IsSet()
+}
+
+// findFlag - find flag of command by name, aliases are not used in config file.
+func findFlag(cmd *cli.Command, n string) cli.Flag {
+	for _, f := range cmd.Flags {
+		if len(f.Names()) > 0 && f.Names()[0] == n {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// toString - value of flag as string, path in home dir can start with "~/".
+func toString(v any) string {
+	s := fmt.Sprint(v)
+
+	if strings.HasPrefix(s, homeDir+"/") {
+		if h, err := os.UserHomeDir(); err == nil {
+			s = filepath.Join(h, s[len(homeDir)+1:])
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,189 @@
+package config_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/librun/ha-backup-tool/internal/config"
+)
+
+const testConfig = `
+global:
+  emergency: [a.txt, b.txt]
+  max-archive-size: 1GB
+commands:
+  extract:
+    output: /srv/restore
+    include: [core*, media*]
+profiles:
+  home:
+    global:
+      max-archive-size: 2GB
+    commands:
+      extract:
+        output: /srv/home
+`
+
+type result struct {
+	emergency []string
+	size      string
+	output    string
+	include   string
+}
+
+func run(t *testing.T, profile string, args ...string) (result, error) {
+	t.Helper()
+
+	cfg, err := config.Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r result
+
+	app := &cli.Command{
+		Name: "app",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{Name: "emergency"},
+			&cli.StringFlag{Name: "max-archive-size"},
+			&cli.StringFlag{Name: "output"},
+		},
+		Commands: []*cli.Command{{
+			Name: "extract",
+			Flags: []cli.Flag{
+				&cli.StringFlag{Name: "output"},
+				&cli.StringFlag{Name: "include"},
+			},
+			Before: func(ctx context.Context, c *cli.Command) (context.Context, error) {
+				return ctx, cfg.Apply(c, profile)
+			},
+			Action: func(_ context.Context, c *cli.Command) error {
+				r = result{
+					emergency: c.StringSlice("emergency"),
+					size:      c.String("max-archive-size"),
+					output:    c.String("output"),
+					include:   c.String("include"),
+				}
+
+				return nil
+			},
+		}},
+	}
+
+	err = app.Run(context.Background(), append([]string{"app"}, args...))
+
+	return r, err
+}
+
+func TestConfig_Apply(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		args    []string
+		want    result
+	}{
+		{
+			name: "default values",
+			args: []string{"extract"},
+			want: result{[]string{"a.txt", "b.txt"}, "1GB", "/srv/restore", "core*,media*"},
+		},
+		{
+			name:    "profile before default values",
+			profile: "home",
+			args:    []string{"extract"},
+			want:    result{[]string{"a.txt", "b.txt"}, "2GB", "/srv/home", "core*,media*"},
+		},
+		{
+			name:    "flags before config",
+			profile: "home",
+			args:    []string{"-emergency", "c.txt", "extract", "-output", "out", "-max-archive-size", "3GB"},
+			want:    result{[]string{"c.txt"}, "3GB", "out", "core*,media*"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := run(t, tt.profile, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(got.emergency, tt.want.emergency) || got.size != tt.want.size ||
+				got.output != tt.want.output || got.include != tt.want.include {
+				t.Errorf("flags = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_ApplyErrors(t *testing.T) {
+	if _, err := run(t, "office", "extract"); !errors.Is(err, config.ErrProfileNotFound) {
+		t.Errorf("Apply() of unknown profile error = %v, want %v", err, config.ErrProfileNotFound)
+	}
+
+	cfg, err := config.Parse([]byte("global:\n  unknown: 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cfg.Apply(&cli.Command{Name: "app"}, "")
+	if !errors.Is(err, config.ErrOptionNotValid) {
+		t.Errorf("Apply() of unknown option error = %v, want %v", err, config.ErrOptionNotValid)
+	}
+
+	if _, err = config.Parse([]byte("extract:\n  output: out\n")); !errors.Is(err, config.ErrConfigNotValid) {
+		t.Errorf("Parse() of unknown section error = %v, want %v", err, config.ErrConfigNotValid)
+	}
+}
+
+func TestConfig_ApplyVerbose(t *testing.T) {
+	cfg, err := config.Parse([]byte("global:\n  log-level: warn\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "log level from config", args: []string{"app", "cmd"}, want: "warn"},
+		// verbose from command line is short for debug level, it is not overridden by config
+		{name: "verbose", args: []string{"app", "--verbose", "cmd"}},
+		{name: "log level from command line", args: []string{"app", "--log-level", "error", "cmd"}, want: "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+
+			app := &cli.Command{
+				Name:  "app",
+				Flags: []cli.Flag{&cli.BoolFlag{Name: "verbose"}, &cli.StringFlag{Name: "log-level"}},
+				Commands: []*cli.Command{{
+					Name: "cmd",
+					Before: func(ctx context.Context, c *cli.Command) (context.Context, error) {
+						return ctx, cfg.Apply(c, "")
+					},
+					Action: func(_ context.Context, c *cli.Command) error {
+						got = c.String("log-level")
+
+						return nil
+					},
+				}},
+			}
+
+			if err = app.Run(context.Background(), tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("log-level = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package conflict
+
+import (
+	"errors"
+	"strings"
+)
+
+// Policy - how to act when output dir or file already exists.
+type Policy int
+
+const (
+	PolicyFail Policy = iota
+	PolicyOverwrite
+	PolicySkip
+	PolicyNewer
+	PolicyRename
+)
+
+const (
+	PolicyFailString      = "fail"
+	PolicyOverwriteString = "overwrite"
+	PolicySkipString      = "skip"
+	PolicyNewerString     = "newer"
+	PolicyRenameString    = "rename"
+	PolicyUnknownString   = "unknown"
+)
+
+var (
+	ErrPolicyUnknown = errors.New("on exists policy not support")
+)
+
+func (p Policy) String() string {
+	switch p {
+	case PolicyFail:
+		return PolicyFailString
+	case PolicyOverwrite:
+		return PolicyOverwriteString
+	case PolicySkip:
+		return PolicySkipString
+	case PolicyNewer:
+		return PolicyNewerString
+	case PolicyRename:
+		return PolicyRenameString
+	default:
+		return PolicyUnknownString
+	}
+}
+
+// IsMerge - check that policy extract backup into existing dir.
+func (p Policy) IsMerge() bool {
+	return p == PolicyOverwrite || p == PolicySkip || p == PolicyNewer
+}
+
+func ParseFromString(s string) (Policy, error) {
+	switch strings.ToLower(s) {
+	case "", PolicyFailString:
+		return PolicyFail, nil
+	case PolicyOverwriteString:
+		return PolicyOverwrite, nil
+	case PolicySkipString:
+		return PolicySkip, nil
+	case PolicyNewerString:
+		return PolicyNewer, nil
+	case PolicyRenameString:
+		return PolicyRename, nil
+	}
+
+	return 0, ErrPolicyUnknown
+}
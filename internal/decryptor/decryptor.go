@@ -1,22 +1,68 @@
 package decryptor
 
 import (
+	"compress/gzip"
+	"errors"
 	"io"
 
 	v2 "github.com/librun/ha-backup-tool/internal/decryptor/v2"
 	v3 "github.com/librun/ha-backup-tool/internal/decryptor/v3"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
 )
 
-func New(r io.Reader, t Decryptor, passwd string) (io.ReadCloser, error) {
+// KeyCache - cache of derived keys, used by SecureTar v3.
+type KeyCache = v3.Cache
+
+// New - create reader for decrypt archive, errors of reader have class for exit code.
+// Cache c is optional and can be nil.
+func New(r io.Reader, t Decryptor, passwd string, c KeyCache) (io.ReadCloser, error) {
+	var rc io.ReadCloser
+	var err error
+
 	switch t {
 	case DecryptorSecureTarAuto:
+		err = ErrDecryptorUnknown
 	case DecryptorSecureTarV1:
-		return nil, ErrDecryptorV1NotSupported
+		err = ErrDecryptorV1NotSupported
 	case DecryptorSecureTarV2:
-		return v2.NewReader(r, passwd)
+		rc, err = v2.NewReader(r, passwd)
 	case DecryptorSecureTarV3:
-		return v3.NewReader(r, passwd)
+		rc, err = v3.NewReaderWithCache(r, passwd, c)
+	default:
+		err = ErrDecryptorUnknown
+	}
+
+	if err != nil {
+		return nil, WrapError(err)
+	}
+
+	return classReader{rc}, nil
+}
+
+// Probe - check key by start of archive: SecureTar v3 validates key by header,
+// for SecureTar v2 key is valid if first decrypted block is gzip header.
+func Probe(r io.Reader, t Decryptor, passwd string, c KeyCache) error {
+	rc, err := New(r, t, passwd, c)
+	if err != nil {
+		return err
+	}
+
+	if _, err = gzip.NewReader(rc); errors.Is(err, gzip.ErrHeader) {
+		return exitcode.Wrap(exitcode.WrongKey, err)
+	}
+
+	return err
+}
+
+// TotalSize - get size of decrypted data if decryptor know it (SecureTar v3).
+func TotalSize(rc io.ReadCloser) (int64, bool) {
+	if c, ok := rc.(classReader); ok {
+		rc = c.ReadCloser
+	}
+
+	if r, ok := rc.(*v3.Reader); ok {
+		return int64(r.TotalSize), true //nolint:gosec // size of archive fits in int64
 	}
 
-	return nil, ErrDecryptorUnknown
+	return 0, false
 }
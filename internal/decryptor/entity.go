@@ -6,6 +6,7 @@ import (
 
 	"github.com/hashicorp/go-version"
 	"github.com/librun/ha-backup-tool/internal/entity"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
 )
 
 type Decryptor int
@@ -57,14 +58,14 @@ func ParseFromString(s string) (Decryptor, error) {
 	case "":
 		return DecryptorSecureTarAuto, nil
 	case DecryptorSecureTarV1String:
-		return DecryptorSecureTarV1, ErrDecryptorV1NotSupported
+		return DecryptorSecureTarV1, exitcode.Wrap(exitcode.UnsupportedCrypto, ErrDecryptorV1NotSupported)
 	case DecryptorSecureTarV2String:
 		return DecryptorSecureTarV2, nil
 	case DecryptorSecureTarV3String:
 		return DecryptorSecureTarV3, nil
 	}
 
-	return 0, ErrDecryptorUnknown
+	return 0, exitcode.Wrap(exitcode.BadArgs, ErrDecryptorUnknown)
 }
 
 func ParseFromBackupJSON(e *entity.HomeAssistantBackup, d Decryptor) (Decryptor, error) {
@@ -0,0 +1,43 @@
+package decryptor
+
+import (
+	"errors"
+	"io"
+
+	v2 "github.com/librun/ha-backup-tool/internal/decryptor/v2"
+	v3 "github.com/librun/ha-backup-tool/internal/decryptor/v3"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+)
+
+// classReader - reader with class of decrypt errors for exit code.
+type classReader struct {
+	io.ReadCloser
+}
+
+func (r classReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+
+	return n, WrapError(err)
+}
+
+func (r classReader) Close() error {
+	return WrapError(r.ReadCloser.Close())
+}
+
+// WrapError - set class of decrypt error: wrong key, not supported crypto or broken archive.
+func WrapError(err error) error {
+	switch {
+	case err == nil, errors.Is(err, io.EOF):
+		return err
+	case errors.Is(err, v3.ErrIncorrectPassword):
+		return exitcode.Wrap(exitcode.WrongKey, err)
+	case errors.Is(err, ErrDecryptorUnknown), errors.Is(err, ErrDecryptorV1NotSupported):
+		return exitcode.Wrap(exitcode.UnsupportedCrypto, err)
+	case errors.Is(err, v2.ErrNotEnoughBytes), errors.Is(err, v2.ErrTooShort), errors.Is(err, v2.ErrModulo),
+		errors.Is(err, v3.ErrInvalidHeader), errors.Is(err, v3.ErrReadOverflow), errors.Is(err, v3.ErrReadIncomplete),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return exitcode.Wrap(exitcode.Corrupt, err)
+	}
+
+	return err
+}
@@ -0,0 +1,110 @@
+package v2
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+)
+
+const (
+	saltLen = aes.BlockSize
+	// magic, plaintext size with reserved bytes and salt.
+	headerSize = len(SecuretarMagic) + aes.BlockSize + saltLen
+)
+
+// Writer - io.WriteCloser which encrypts data by AES CBC, Close writes last block with PKCS7 padding.
+type Writer struct {
+	w    io.Writer
+	mode cipher.BlockMode
+	buf  []byte
+}
+
+// NewWriter - create writer, with header magic and plaintext size are written before salt as in SecureTar v2,
+// without header only salt is written as in old backups.
+func NewWriter(w io.Writer, passwd string, size int64, header bool) (*Writer, error) {
+	key, err := PasswordToKey(passwd)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, saltLen)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	iv, err := GenerateIv(key, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	var h []byte
+
+	if header {
+		h = append(h, SecuretarMagic...)
+		h = binary.BigEndian.AppendUint64(h, uint64(size)) //nolint:gosec // size of archive is not negative
+		h = append(h, make([]byte, aes.BlockSize-8)...)
+	}
+
+	if _, err = w.Write(append(h, salt...)); err != nil {
+		return nil, err
+	}
+
+	return &Writer{w: w, mode: cipher.NewCBCEncrypter(block, iv)}, nil
+}
+
+// Write - encrypt full blocks, rest of data is kept for next write.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+
+	n := len(w.buf) - len(w.buf)%aes.BlockSize
+	if n == 0 {
+		return len(p), nil
+	}
+
+	w.mode.CryptBlocks(w.buf[:n], w.buf[:n])
+
+	if _, err := w.w.Write(w.buf[:n]); err != nil {
+		return 0, err
+	}
+
+	w.buf = w.buf[:copy(w.buf, w.buf[n:])]
+
+	return len(p), nil
+}
+
+// Close - write last block with padding, underlying writer is not closed.
+func (w *Writer) Close() error {
+	pad := aes.BlockSize - len(w.buf)%aes.BlockSize
+	for range pad {
+		w.buf = append(w.buf, byte(pad))
+	}
+
+	w.mode.CryptBlocks(w.buf, w.buf)
+
+	_, err := w.w.Write(w.buf)
+	w.buf = w.buf[:0]
+
+	return err
+}
+
+// HasHeader - check start of archive is header of SecureTar v2.
+func HasHeader(prefix []byte) bool {
+	return string(prefix) == SecuretarMagic
+}
+
+// EncryptedSize - size of encrypted archive for plaintext size, padding is always added.
+func EncryptedSize(size int64, header bool) int64 {
+	n := int64(saltLen) + size + aes.BlockSize - size%aes.BlockSize
+	if header {
+		n += int64(headerSize - saltLen)
+	}
+
+	return n
+}
@@ -0,0 +1,67 @@
+package v2_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	v2 "github.com/librun/ha-backup-tool/internal/decryptor/v2"
+)
+
+func TestWriter(t *testing.T) {
+	passwd := "XXXX-XXXX-XXXX-XXXX-XXXX-XXXX-XXXX"
+
+	tests := []struct {
+		name   string
+		size   int
+		header bool
+	}{
+		{name: "empty", size: 0},
+		{name: "not full block", size: 5},
+		{name: "full block", size: 32},
+		{name: "with header", size: 1000, header: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plain := bytes.Repeat([]byte("a"), tt.size)
+
+			var b bytes.Buffer
+
+			w, err := v2.NewWriter(&b, passwd, int64(tt.size), tt.header)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// several writes are joined into blocks
+			for _, p := range [][]byte{plain[:tt.size/2], plain[tt.size/2:]} {
+				if _, err = w.Write(p); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err = w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := v2.EncryptedSize(int64(tt.size), tt.header); got != int64(b.Len()) {
+				t.Errorf("EncryptedSize() = %d, written %d", got, b.Len())
+			}
+
+			r, err := v2.NewReader(&b, passwd)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// reader reads only full blocks, padding is not removed
+			got := make([]byte, b.Len())
+			if _, err = io.ReadFull(r, got); err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.HasPrefix(got, plain) || int(got[len(got)-1]) != len(got)-tt.size {
+				t.Errorf("decrypted %q, want %q with padding", got, plain)
+			}
+		})
+	}
+}
@@ -71,15 +71,26 @@ type Header struct {
 	ChachaHeader   [chacha20poly1305.NonceSizeX]byte
 }
 
+// Cache - cache of keys derived by Argon2id, key of cache is password and root salt.
+type Cache interface {
+	Get(password string, salt []byte) ([]byte, bool)
+	Put(password string, salt, key []byte)
+}
+
 func NewReader(r io.Reader, password string) (*Reader, error) {
+	return NewReaderWithCache(r, password, nil)
+}
+
+// NewReaderWithCache - create reader, derived key is taken from cache if it exists, nil cache is not used.
+// Only valid keys are put into cache.
+func NewReaderWithCache(r io.Reader, password string, c Cache) (*Reader, error) {
 	h, err := ReadHeader(r)
 	if err != nil {
 		return nil, err
 	}
 
-	argonKey := GetKey(h, password)
-
-	if err = ValidatePassword(h, argonKey); err != nil {
+	argonKey, err := getValidKey(h, password, c)
+	if err != nil {
 		return nil, err
 	}
 
@@ -234,6 +245,27 @@ func ReadHeader(r io.Reader) (*Header, error) {
 	return &h, nil
 }
 
+// getValidKey - get derived key from cache or derive it, key is validated by header.
+func getValidKey(h *Header, password string, c Cache) ([]byte, error) {
+	if c != nil {
+		if k, ok := c.Get(password, h.RootSalt[:]); ok && ValidatePassword(h, k) == nil {
+			return k, nil
+		}
+	}
+
+	k := GetKey(h, password)
+
+	if err := ValidatePassword(h, k); err != nil {
+		return nil, err
+	}
+
+	if c != nil {
+		c.Put(password, h.RootSalt[:], k)
+	}
+
+	return k, nil
+}
+
 func GetKey(h *Header, password string) []byte {
 	return argon2.IDKey(
 		[]byte(password),
@@ -338,3 +338,61 @@ func makeV3HeaderBytes(h *v3.Header, totalSize uint64) []byte {
 
 	return buf
 }
+
+type testCache struct {
+	keys map[string][]byte
+	gets int
+	hits int
+	puts int
+}
+
+func (c *testCache) Get(password string, salt []byte) ([]byte, bool) {
+	c.gets++
+
+	k, ok := c.keys[password+string(salt)]
+	if ok {
+		c.hits++
+	}
+
+	return k, ok
+}
+
+func (c *testCache) Put(password string, salt, key []byte) {
+	c.puts++
+	c.keys[password+string(salt)] = key
+}
+
+func TestNewReaderWithCache(t *testing.T) {
+	plaintext := []byte("cached key")
+	stream := buildTestV3Stream(t, "password123", plaintext, uint64(len(plaintext)))
+	c := &testCache{keys: make(map[string][]byte)}
+
+	_, err := v3.NewReaderWithCache(bytes.NewReader(stream), "wrongpassword", c)
+	if !errors.Is(err, v3.ErrIncorrectPassword) {
+		t.Fatalf("Expected ErrIncorrectPassword, got %v", err)
+	}
+
+	if c.puts != 0 {
+		t.Errorf("Expected wrong key not cached, got %d puts", c.puts)
+	}
+
+	for range 2 {
+		r, err := v3.NewReaderWithCache(bytes.NewReader(stream), "password123", c)
+		if err != nil {
+			t.Fatalf("NewReaderWithCache failed: %v", err)
+		}
+
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("ReadAll failed: %v", err)
+		}
+
+		if !bytes.Equal(got, plaintext) {
+			t.Errorf("Expected %q, got %q", plaintext, got)
+		}
+	}
+
+	if c.puts != 1 || c.hits != 1 {
+		t.Errorf("Expected 1 put and 1 hit, got %d puts and %d hits", c.puts, c.hits)
+	}
+}
@@ -0,0 +1,113 @@
+package v3
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+
+	"github.com/openziti/secretstream"
+)
+
+// Writer - io.WriteCloser which encrypts data by secret stream in chunks, last chunk has final tag.
+type Writer struct {
+	w         io.Writer
+	encryptor secretstream.Encryptor
+	buf       []byte
+}
+
+// NewWriter - create writer, new salts are generated and header with plaintext size is written.
+func NewWriter(w io.Writer, password string, size uint64) (*Writer, error) {
+	var h Header
+
+	binary.BigEndian.PutUint64(h.MetaData[:8], size)
+
+	for _, s := range [][]byte{h.RootSalt[:], h.ValidationSalt[:], h.DecodeSalt[:]} {
+		if _, err := rand.Read(s); err != nil {
+			return nil, err
+		}
+	}
+
+	argonKey := GetKey(&h, password)
+
+	vk, err := GetBlake2bKey(argonKey, h.ValidationSalt)
+	if err != nil {
+		return nil, err
+	}
+
+	copy(h.ValidationKey[:], vk)
+
+	dk, err := GetBlake2bKey(argonKey, h.DecodeSalt)
+	if err != nil {
+		return nil, err
+	}
+
+	e, sh, err := secretstream.NewEncryptor(dk)
+	if err != nil {
+		return nil, err
+	}
+
+	copy(h.ChachaHeader[:], sh)
+
+	if _, err = w.Write(h.bytes()); err != nil {
+		return nil, err
+	}
+
+	return &Writer{w: w, encryptor: e, buf: make([]byte, 0, secretStreamChunkDataSize)}, nil
+}
+
+// Write - encrypt full chunks, full chunk is pushed only on next write because last chunk must have final tag.
+func (w *Writer) Write(p []byte) (int, error) {
+	n := len(p)
+
+	for len(p) > 0 {
+		if len(w.buf) == secretStreamChunkDataSize {
+			if err := w.push(secretstream.TagMessage); err != nil {
+				return 0, err
+			}
+		}
+
+		c := min(len(p), secretStreamChunkDataSize-len(w.buf))
+		w.buf = append(w.buf, p[:c]...)
+		p = p[c:]
+	}
+
+	return n, nil
+}
+
+// Close - write last chunk, underlying writer is not closed.
+func (w *Writer) Close() error {
+	return w.push(secretstream.TagFinal)
+}
+
+func (w *Writer) push(tag byte) error {
+	c, err := w.encryptor.Push(w.buf, tag)
+	if err != nil {
+		return err
+	}
+
+	w.buf = w.buf[:0]
+	_, err = w.w.Write(c)
+
+	return err
+}
+
+// bytes - header in format of SecureTar v3.
+func (h *Header) bytes() []byte {
+	b := make([]byte, 0, HeaderSize)
+	b = append(b, SecuretarMagic...)
+	b = append(b, make([]byte, SecuretarMagicLen-len(SecuretarMagic))...)
+	b = append(b, h.MetaData[:]...)
+	b = append(b, h.RootSalt[:]...)
+	b = append(b, h.ValidationSalt[:]...)
+	b = append(b, h.ValidationKey[:]...)
+	b = append(b, h.DecodeSalt[:]...)
+
+	return append(b, h.ChachaHeader[:]...)
+}
+
+// EncryptedSize - size of encrypted archive for plaintext size, empty plaintext has one chunk.
+func EncryptedSize(size uint64) uint64 {
+	chunks := max(1, (size+secretStreamChunkDataSize-1)/secretStreamChunkDataSize)
+
+	return HeaderSize + size + chunks*secretstream.StreamABytes
+}
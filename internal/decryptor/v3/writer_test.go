@@ -0,0 +1,66 @@
+package v3_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	v3 "github.com/librun/ha-backup-tool/internal/decryptor/v3"
+)
+
+func TestWriter(t *testing.T) {
+	const chunk = 1024 * 1024
+
+	tests := []struct {
+		name string
+		size int
+	}{
+		{name: "empty", size: 0},
+		{name: "small", size: 100},
+		{name: "full chunk", size: chunk},
+		{name: "several chunks", size: 2*chunk + 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plain := bytes.Repeat([]byte("abc"), tt.size/3+1)[:tt.size]
+
+			var b bytes.Buffer
+
+			w, err := v3.NewWriter(&b, "password", uint64(tt.size))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err = io.Copy(w, bytes.NewReader(plain)); err != nil {
+				t.Fatal(err)
+			}
+
+			if err = w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := v3.EncryptedSize(uint64(tt.size)); got != uint64(b.Len()) {
+				t.Errorf("EncryptedSize() = %d, written %d", got, b.Len())
+			}
+
+			r, err := v3.NewReader(&b, "password")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(got, plain) {
+				t.Errorf("decrypted %d bytes, want %d", len(got), len(plain))
+			}
+
+			if err = r.Close(); err != nil {
+				t.Errorf("Close() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package decryptor
+
+import (
+	"io"
+
+	v2 "github.com/librun/ha-backup-tool/internal/decryptor/v2"
+	v3 "github.com/librun/ha-backup-tool/internal/decryptor/v3"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+)
+
+// Format - format of encrypted archive, archive is encrypted again in same format.
+type Format struct {
+	Decryptor Decryptor
+	header    bool
+}
+
+// DetectFormat - read start of archive, SecureTar v2 archive can be without header in old backups.
+func DetectFormat(r io.Reader, t Decryptor) (Format, error) {
+	f := Format{Decryptor: t}
+
+	if t == DecryptorSecureTarV2 {
+		b := make([]byte, len(v2.SecuretarMagic))
+		if _, err := io.ReadFull(r, b); err != nil {
+			return f, exitcode.Wrap(exitcode.Corrupt, err)
+		}
+
+		f.header = v2.HasHeader(b)
+	}
+
+	return f, nil
+}
+
+// NewWriter - create writer for encrypt archive, size is size of not encrypted archive.
+// Writer must be closed for write end of archive.
+func (f Format) NewWriter(w io.Writer, passwd string, size int64) (io.WriteCloser, error) {
+	switch f.Decryptor {
+	case DecryptorSecureTarV2:
+		return v2.NewWriter(w, passwd, size, f.header)
+	case DecryptorSecureTarV3:
+		return v3.NewWriter(w, passwd, uint64(size)) //nolint:gosec // size of archive is not negative
+	case DecryptorSecureTarAuto, DecryptorSecureTarV1:
+	}
+
+	return nil, exitcode.Wrap(exitcode.UnsupportedCrypto, ErrDecryptorUnknown)
+}
+
+// EncryptedSize - size of encrypted archive for size of not encrypted archive.
+func (f Format) EncryptedSize(size int64) int64 {
+	if f.Decryptor == DecryptorSecureTarV3 {
+		return int64(v3.EncryptedSize(uint64(size))) //nolint:gosec // size of archive fits in int64
+	}
+
+	return v2.EncryptedSize(size, f.header)
+}
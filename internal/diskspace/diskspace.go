@@ -0,0 +1,14 @@
+package diskspace
+
+import (
+	"errors"
+)
+
+var (
+	ErrNotSupported = errors.New("check free disk space not support on this os")
+)
+
+// Free - get free disk space in bytes available for user on disk with path.
+func Free(path string) (uint64, error) {
+	return free(path)
+}
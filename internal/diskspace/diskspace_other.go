@@ -0,0 +1,7 @@
+//go:build !linux && !darwin && !windows
+
+package diskspace
+
+func free(_ string) (uint64, error) {
+	return 0, ErrNotSupported
+}
@@ -0,0 +1,16 @@
+//go:build linux || darwin
+
+package diskspace
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+func free(path string) (uint64, error) {
+	var s unix.Statfs_t
+	if err := unix.Statfs(path, &s); err != nil {
+		return 0, err
+	}
+
+	return s.Bavail * uint64(s.Bsize), nil //nolint:gosec // block size is positive
+}
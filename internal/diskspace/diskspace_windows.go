@@ -0,0 +1,19 @@
+package diskspace
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+func free(path string) (uint64, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var fa, t, f uint64
+	if err = windows.GetDiskFreeSpaceEx(p, &fa, &t, &f); err != nil {
+		return 0, err
+	}
+
+	return fa, nil
+}
@@ -25,6 +25,15 @@ type (
 			WithAutomaticSettings       bool      `json:"with_automatic_settings"`
 			SupervisorBackupRequestDate time.Time `json:"supervisor.backup_request_date"`
 		} `json:"extra"`
+		Addons       []Addon  `json:"addons"`
+		Folders      []string `json:"folders"`
 		Repositories []string `json:"repositories"`
 	}
+
+	Addon struct {
+		Slug    string  `json:"slug"`
+		Name    string  `json:"name"`
+		Version string  `json:"version"`
+		Size    float64 `json:"size"`
+	}
 )
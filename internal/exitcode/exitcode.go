@@ -0,0 +1,71 @@
+package exitcode
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// Code - exit code of process, every code is class of failure.
+type Code int
+
+const (
+	OK                Code = 0
+	Error             Code = 1 // unknown error
+	BadArgs           Code = 2 // not valid flags, arguments or input files
+	WrongKey          Code = 3 // wrong or not valid key, key not found in emergency kit
+	UnsupportedCrypto Code = 4 // crypto or backup version not support
+	Corrupt           Code = 5 // broken archive or backup.json, archive exceeds limits
+	IO                Code = 6 // read or write error, disk full
+	Partial           Code = 7 // some backups extracted, some failed
+)
+
+// ClassError - error with class of failure, used for select exit code.
+type ClassError struct {
+	Code Code
+	Err  error
+}
+
+func (e *ClassError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ClassError) Unwrap() error {
+	return e.Err
+}
+
+// Wrap - set class of error, class set earlier is kept.
+func Wrap(c Code, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var ce *ClassError
+	if errors.As(err, &ce) {
+		return err
+	}
+
+	return &ClassError{Code: c, Err: err}
+}
+
+// Get - get exit code for error, errors without class are I/O if they are file errors.
+func Get(err error) Code {
+	if err == nil {
+		return OK
+	}
+
+	var ce *ClassError
+	if errors.As(err, &ce) {
+		return ce.Code
+	}
+
+	var pe *fs.PathError
+	var le *os.LinkError
+	var se *os.SyscallError
+	if errors.As(err, &pe) || errors.As(err, &le) || errors.As(err, &se) || errors.Is(err, syscall.ENOSPC) {
+		return IO
+	}
+
+	return Error
+}
@@ -0,0 +1,49 @@
+package exitcode_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+)
+
+func TestGet(t *testing.T) {
+	errBase := errors.New("base") //nolint:err113 // Test error
+
+	tests := []struct {
+		name string
+		err  error
+		want exitcode.Code
+	}{
+		{name: "nil", err: nil, want: exitcode.OK},
+		{name: "without class", err: errBase, want: exitcode.Error},
+		{name: "class", err: exitcode.Wrap(exitcode.WrongKey, errBase), want: exitcode.WrongKey},
+		{
+			name: "wrapped class",
+			err:  fmt.Errorf("extract: %w", exitcode.Wrap(exitcode.Corrupt, errBase)),
+			want: exitcode.Corrupt,
+		},
+		{
+			name: "first class is kept",
+			err:  exitcode.Wrap(exitcode.BadArgs, exitcode.Wrap(exitcode.UnsupportedCrypto, errBase)),
+			want: exitcode.UnsupportedCrypto,
+		},
+		{name: "path error", err: &os.PathError{Op: "open", Path: "x", Err: os.ErrNotExist}, want: exitcode.IO},
+		{name: "disk full", err: fmt.Errorf("write: %w", syscall.ENOSPC), want: exitcode.IO},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitcode.Get(tt.err); got != tt.want {
+				t.Errorf("Expected code %d, got %d", tt.want, got)
+			}
+
+			if tt.err != nil && !errors.Is(tt.err, errBase) && tt.want != exitcode.IO {
+				t.Error("Expected wrapped error is kept")
+			}
+		})
+	}
+}
@@ -4,12 +4,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"os"
 
 	decryptor "github.com/librun/ha-backup-tool/internal/decryptor"
 	"github.com/librun/ha-backup-tool/internal/entity"
-	"github.com/librun/ha-backup-tool/internal/logger"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
 )
 
 type BackupConfig struct {
@@ -23,17 +22,8 @@ func NewBackupConfig(compressed bool) *BackupConfig {
 
 func BackupConfigUnmarshalJSON(fpath string) (*BackupConfig, error) {
 	var bc BackupConfig
-	fo, err := os.Open(fpath)
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		if err = fo.Close(); err != nil {
-			logger.Fatalf("File: %s Error close file: %v", fpath, err)
-		}
-	}()
 
-	b, err := io.ReadAll(fo)
+	b, err := os.ReadFile(fpath)
 	if err != nil {
 		return nil, err
 	}
@@ -51,7 +41,8 @@ func (b *BackupConfig) InitAndValidate() error {
 	var errD error
 	if b.decryptor, errD = decryptor.ParseFromBackupJSON(b.e, b.decryptor); errD != nil {
 		if errors.Is(errD, decryptor.ErrDecryptorUnknown) {
-			return fmt.Errorf("crypto type %s not support", b.e.Crypto) //nolint:err113 // Dynamic error
+			//nolint:err113 // Dynamic error
+			return exitcode.Wrap(exitcode.UnsupportedCrypto, fmt.Errorf("crypto type %s not support", b.e.Crypto))
 		}
 
 		return errD
@@ -64,7 +55,8 @@ func (b *BackupConfig) InitAndValidate() error {
 	}
 
 	if !vs {
-		return fmt.Errorf("version backup %d not support", b.e.Version) //nolint:err113 // Dynamic error
+		//nolint:err113 // Dynamic error
+		return exitcode.Wrap(exitcode.UnsupportedCrypto, fmt.Errorf("version backup %d not support", b.e.Version))
 	}
 
 	return nil
@@ -85,3 +77,8 @@ func (b *BackupConfig) IsProtected() bool {
 func (b *BackupConfig) IsCompressed() bool {
 	return b.e.Compressed
 }
+
+// InstanceID - id of Home Assistant instance which made backup.
+func (b *BackupConfig) InstanceID() string {
+	return b.e.Extra.InstanceID
+}
@@ -0,0 +1,99 @@
+package extractor
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io"
+
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+	"github.com/librun/ha-backup-tool/internal/key"
+	"github.com/librun/ha-backup-tool/internal/limits"
+	"github.com/librun/ha-backup-tool/internal/tarextractor"
+)
+
+// Error codes for machine readable output.
+const (
+	ErrorCodeInvalidFile        = "invalid_file"
+	ErrorCodeInvalidBackupJSON  = "invalid_backup_json"
+	ErrorCodeInvalidArgument    = "invalid_argument"
+	ErrorCodeOutputExists       = "output_exists"
+	ErrorCodeNotEnoughSpace     = "not_enough_space"
+	ErrorCodeLimitExceeded      = "limit_exceeded"
+	ErrorCodeInvalidKey         = "invalid_key"
+	ErrorCodeKeyRequired        = "key_required"
+	ErrorCodeWrongKey           = "wrong_key"
+	ErrorCodeCryptoNotSupported = "crypto_not_supported"
+	ErrorCodeCorrupt            = "corrupt"
+	ErrorCodeIO                 = "io"
+	ErrorCodeUnknown            = "unknown"
+)
+
+// WrapError - set class of extract error for exit code.
+// Archive encrypted by SecureTar v2 with wrong key is found only by broken gzip header, so for
+// protected archive broken header is wrong key.
+func WrapError(err error, protected bool) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrFileNotValid), errors.Is(err, ErrDirExists):
+		return exitcode.Wrap(exitcode.BadArgs, err)
+	case errors.Is(err, ErrNotEnoughSpace):
+		return exitcode.Wrap(exitcode.IO, err)
+	case protected && errors.Is(err, gzip.ErrHeader):
+		return exitcode.Wrap(exitcode.WrongKey, err)
+	case isLimitError(err), errors.Is(err, ErrBackupJSONUnmarshal), errors.Is(err, ErrBackupJSONValidate),
+		errors.Is(err, gzip.ErrHeader), errors.Is(err, gzip.ErrChecksum), errors.Is(err, tar.ErrHeader),
+		errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, tarextractor.ErrTaintedPath):
+		return exitcode.Wrap(exitcode.Corrupt, err)
+	}
+
+	return err
+}
+
+// GetErrorCode - get code of error for machine readable output.
+func GetErrorCode(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrFileNotValid):
+		return ErrorCodeInvalidFile
+	case errors.Is(err, ErrDirExists):
+		return ErrorCodeOutputExists
+	case errors.Is(err, ErrNotEnoughSpace):
+		return ErrorCodeNotEnoughSpace
+	case isLimitError(err):
+		return ErrorCodeLimitExceeded
+	case errors.Is(err, key.ErrKeyNotSet):
+		return ErrorCodeKeyRequired
+	case errors.Is(err, key.ErrPasswordNotValid), errors.Is(err, key.ErrPasswordEmpty),
+		errors.Is(err, key.ErrEmergencyFileNotHaveKey), errors.Is(err, key.ErrFileNotValid):
+		return ErrorCodeInvalidKey
+	}
+
+	switch exitcode.Get(err) {
+	case exitcode.BadArgs:
+		return ErrorCodeInvalidArgument
+	case exitcode.WrongKey:
+		return ErrorCodeWrongKey
+	case exitcode.UnsupportedCrypto:
+		return ErrorCodeCryptoNotSupported
+	case exitcode.Corrupt:
+		if errors.Is(err, ErrBackupJSONUnmarshal) || errors.Is(err, ErrBackupJSONValidate) {
+			return ErrorCodeInvalidBackupJSON
+		}
+
+		return ErrorCodeCorrupt
+	case exitcode.IO:
+		return ErrorCodeIO
+	case exitcode.OK, exitcode.Error, exitcode.Partial:
+	}
+
+	return ErrorCodeUnknown
+}
+
+func isLimitError(err error) bool {
+	return errors.Is(err, limits.ErrFileSizeExceeded) || errors.Is(err, limits.ErrTotalSizeExceeded) ||
+		errors.Is(err, limits.ErrEntriesExceeded) || errors.Is(err, limits.ErrPathDepthExceeded) ||
+		errors.Is(err, limits.ErrRatioExceeded)
+}
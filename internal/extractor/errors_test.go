@@ -0,0 +1,68 @@
+package extractor_test
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+	"github.com/librun/ha-backup-tool/internal/extractor"
+	"github.com/librun/ha-backup-tool/internal/limits"
+)
+
+func TestWrapError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		protected bool
+		wantCode  exitcode.Code
+		wantStr   string
+	}{
+		{name: "nil", err: nil, wantCode: exitcode.OK, wantStr: ""},
+		{
+			name: "broken header of protected archive", err: gzip.ErrHeader, protected: true,
+			wantCode: exitcode.WrongKey, wantStr: extractor.ErrorCodeWrongKey,
+		},
+		{
+			name: "broken header of not protected archive", err: gzip.ErrHeader,
+			wantCode: exitcode.Corrupt, wantStr: extractor.ErrorCodeCorrupt,
+		},
+		{
+			name: "truncated archive", err: io.ErrUnexpectedEOF, protected: true,
+			wantCode: exitcode.Corrupt, wantStr: extractor.ErrorCodeCorrupt,
+		},
+		{
+			name: "limit", err: fmt.Errorf("%w 10", limits.ErrEntriesExceeded),
+			wantCode: exitcode.Corrupt, wantStr: extractor.ErrorCodeLimitExceeded,
+		},
+		{
+			name: "output exists", err: fmt.Errorf("%w: out", extractor.ErrDirExists),
+			wantCode: exitcode.BadArgs, wantStr: extractor.ErrorCodeOutputExists,
+		},
+		{
+			name: "not enough space", err: fmt.Errorf("%w for extract", extractor.ErrNotEnoughSpace),
+			wantCode: exitcode.IO, wantStr: extractor.ErrorCodeNotEnoughSpace,
+		},
+		{
+			name: "unsupported crypto in backup.json",
+			err: fmt.Errorf("%w: %w", extractor.ErrBackupJSONValidate,
+				exitcode.Wrap(exitcode.UnsupportedCrypto, io.EOF)),
+			wantCode: exitcode.UnsupportedCrypto, wantStr: extractor.ErrorCodeCryptoNotSupported,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := extractor.WrapError(tt.err, tt.protected)
+
+			if got := exitcode.Get(err); got != tt.wantCode {
+				t.Errorf("Expected exit code %d, got %d", tt.wantCode, got)
+			}
+
+			if got := extractor.GetErrorCode(err); got != tt.wantStr {
+				t.Errorf("Expected error code %q, got %q", tt.wantStr, got)
+			}
+		})
+	}
+}
@@ -5,14 +5,22 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 
+	"github.com/librun/ha-backup-tool/internal/backup"
+	"github.com/librun/ha-backup-tool/internal/conflict"
 	decryptor "github.com/librun/ha-backup-tool/internal/decryptor"
+	"github.com/librun/ha-backup-tool/internal/key"
+	"github.com/librun/ha-backup-tool/internal/limits"
 	"github.com/librun/ha-backup-tool/internal/logger"
 	"github.com/librun/ha-backup-tool/internal/options"
+	"github.com/librun/ha-backup-tool/internal/output"
+	"github.com/librun/ha-backup-tool/internal/progress"
 	"github.com/librun/ha-backup-tool/internal/tarextractor"
 )
 
@@ -21,6 +29,11 @@ type tarGzReader struct {
 	file *os.File
 }
 
+const (
+	stagingDirSuffix  = ".partial"
+	journalFileSuffix = ".journal"
+)
+
 //nolint:gochecknoglobals // This is const varible
 var (
 	backupJSONVersionSupport = []int{2}
@@ -28,16 +41,186 @@ var (
 
 var (
 	ErrFileNotValid        = errors.New("file not valid")
-	ErrNotFullUnpack       = errors.New("one or more files not success unpack")
 	ErrBackupJSONNotHave   = fmt.Errorf("file %s not have", options.BackupJSON)
 	ErrBackupJSONUnmarshal = fmt.Errorf("error unmarshal %s file", options.BackupJSON)
 	ErrBackupJSONValidate  = fmt.Errorf("error validate %s file", options.BackupJSON)
+	ErrDirExists           = errors.New("dir is exists")
 )
 
 // Extract - start unpack archive.
-func Extract(file string, ops *options.CmdExtractOptions) error {
-	fmt.Printf("📦 Extracting %s...\n", file)
-	d, err := ExtractBackup(file, ops)
+// Backup is unpacked into hidden staging dir next to target dir and renamed into place
+// only after all inner archives are extracted successfully.
+// Report is always returned, also when extract failed.
+func Extract(file string, ops *options.CmdExtractOptions) (*output.BackupReport, error) {
+	br := output.NewBackupReport(file)
+
+	err := WrapError(extract(file, ops, br), false)
+	br.Finish(err, GetErrorCode(err))
+
+	return br, err
+}
+
+func extract(file string, ops *options.CmdExtractOptions, br *output.BackupReport) error {
+	// limits are counted for every backup separately
+	bOps := *ops
+	bOps.Limits = ops.Limits.Clone()
+	if bOps.Log == nil {
+		bOps.Log = logger.Discard()
+	}
+	ops = &bOps
+
+	ops.Log.Info("Extracting backup", "file", file)
+
+	if s, err := os.Stat(file); err == nil {
+		br.Size = s.Size()
+	}
+
+	dir, merge, err := resolveOutputDir(file, ops)
+	if err != nil {
+		return err
+	}
+
+	br.OutputDir = dir
+
+	sd := GetStagingDir(dir)
+	jp := GetJournalPath(dir)
+
+	resume := ops.Resume && isExists(sd) && isExists(jp)
+	if resume {
+		ops.Log.Info("Resume extract", "file", file, "staging", sd)
+	} else if err := removeAll(sd); err != nil {
+		return err
+	}
+
+	// on resume part of backup already extracted, so required space is unknown
+	if !resume && !ops.SkipSpaceCheck {
+		if err := checkFreeSpace(file, dir, ops); err != nil {
+			return err
+		}
+	}
+
+	j, err := tarextractor.OpenJournal(jp, resume)
+	if err != nil {
+		return err
+	}
+
+	err = extractToStaging(file, sd, j, ops, br)
+	if errC := j.Close(); err == nil {
+		err = errC
+	}
+
+	if err != nil {
+		cleanupStaging(file, sd, jp, ops)
+
+		return err
+	}
+
+	if err = os.Remove(jp); err != nil {
+		return err
+	}
+
+	if merge {
+		return mergeStaging(sd, dir, ops, br)
+	}
+
+	return os.Rename(sd, dir)
+}
+
+// resolveOutputDir - get output dir for backup by on exists policy and flag that backup must be merged into it.
+func resolveOutputDir(file string, ops *options.CmdExtractOptions) (string, bool, error) {
+	dir := GetOutputDir(file, ops)
+	if !isExists(dir) {
+		return dir, false, nil
+	}
+
+	switch {
+	case ops.OnExists.IsMerge():
+		return dir, true, nil
+	case ops.OnExists == conflict.PolicyRename:
+		d := getFreeDir(dir)
+		ops.Log.Warn("Output dir is exists, extract to new dir", "dir", dir, "new_dir", d)
+
+		return d, false, nil
+	}
+
+	return "", false, fmt.Errorf("%w: %s", ErrDirExists, dir)
+}
+
+// mergeStaging - move extracted files from staging dir into existing dir and print summary.
+func mergeStaging(sd, dir string, ops *options.CmdExtractOptions, br *output.BackupReport) error {
+	s, err := MergeDir(sd, dir, ops.OnExists, !ops.NoPreserve)
+	if err != nil {
+		ops.Log.Error("Merge failed, not merged files kept in staging dir", "dir", dir, "staging", sd)
+
+		return err
+	}
+
+	br.Merge = &output.MergeReport{Added: len(s.Added), Replaced: s.Replaced, Kept: len(s.Kept)}
+
+	ops.Log.Info("Merged into existing dir", "dir", dir,
+		"added", len(s.Added), "replaced", len(s.Replaced), "kept", len(s.Kept))
+
+	for _, r := range s.Replaced {
+		ops.Log.Info("Replaced file", "file", r)
+	}
+
+	for _, r := range s.Kept {
+		ops.Log.Debug("Kept existing file", "file", r)
+	}
+
+	return removeAll(sd)
+}
+
+// GetOutputDir - get dir for unpack backup.
+func GetOutputDir(file string, ops *options.CmdExtractOptions) string {
+	dir := ops.OutputDir
+
+	if ops.ExtractToSubDir && dir != "" {
+		dir = filepath.Join(dir, tarextractor.GetBaseNameArchive(file))
+	} else if dir == "" {
+		dir = filepath.Join(filepath.Dir(file), tarextractor.GetBaseNameArchive(file))
+	}
+
+	return dir
+}
+
+// GetStagingDir - get hidden dir near output dir for unpack backup before rename.
+func GetStagingDir(dir string) string {
+	dir = filepath.Clean(dir)
+
+	return filepath.Join(filepath.Dir(dir), "."+filepath.Base(dir)+stagingDirSuffix)
+}
+
+// GetJournalPath - get hidden journal file near output dir for resume unpack backup.
+func GetJournalPath(dir string) string {
+	dir = filepath.Clean(dir)
+
+	return filepath.Join(filepath.Dir(dir), "."+filepath.Base(dir)+journalFileSuffix)
+}
+
+// cleanupStaging - delete staging dir and journal after failed extract if user not ask keep it.
+func cleanupStaging(file, sd, jp string, ops *options.CmdExtractOptions) {
+	if ops.KeepPartial || ops.Resume {
+		ops.Log.Warn("Partial extract kept", "file", file, "staging", sd)
+
+		return
+	}
+
+	if errR := removeAll(sd); errR != nil {
+		ops.Log.Warn("Failed delete staging dir", "dir", sd, "error", errR)
+	}
+
+	if errR := os.Remove(jp); errR != nil {
+		ops.Log.Warn("Failed delete journal", "file", jp, "error", errR)
+	}
+}
+
+func extractToStaging(file, dir string, j *tarextractor.Journal, ops *options.CmdExtractOptions,
+	br *output.BackupReport) error {
+	d, fs, n, err := ExtractBackup(file, dir, j, ops)
+	br.Bytes += n
+	br.Skipped = append(br.Skipped, relPaths(dir, fs)...)
+
 	if err != nil {
 		return err
 	}
@@ -47,6 +230,10 @@ func Extract(file string, ops *options.CmdExtractOptions) error {
 		return err
 	}
 
+	if e.IsProtected() {
+		checkKitInstance(file, e, ops)
+	}
+
 	if !e.IsCompressed() {
 		return nil
 	}
@@ -63,6 +250,7 @@ func Extract(file string, ops *options.CmdExtractOptions) error {
 	}
 
 	var lastErr error
+	var mu sync.Mutex
 
 	wg := sync.WaitGroup{}
 	for _, st := range sts {
@@ -71,23 +259,36 @@ func Extract(file string, ops *options.CmdExtractOptions) error {
 		go func() {
 			defer wg.Done()
 
-			if errE := ExtractBackupItem(file, st, e.IsProtected(), decr, ops); errE != nil {
-				if ops.Verbose {
-					fmt.Printf("❌ Failed extract from backup: %s/%s encrypted: %t Error: %s\n",
-						file, filepath.Base(st), e.IsProtected(), errE)
-				}
+			ops.Acquire()
+			ar, errE := ExtractBackupItem(file, st, e.InstanceID(), e.IsProtected(), decr, j, ops)
+			ops.Release()
 
+			mu.Lock()
+			br.Archives = append(br.Archives, ar)
+			br.Bytes += ar.Bytes
+			br.Skipped = append(br.Skipped, ar.Skipped...)
+			if ar.Key != "" && !slices.Contains(br.Keys, ar.Key) {
+				br.Keys = append(br.Keys, ar.Key)
+			}
+			mu.Unlock()
+
+			if errE != nil {
+				ops.Log.Debug("Failed extract from backup", "file", file, "archive", filepath.Base(st),
+					"encrypted", e.IsProtected(), "error", errE)
+
+				mu.Lock()
 				lastErr = errE
+				mu.Unlock()
 
 				return
 			}
 
-			if errR := os.Remove(st); errR != nil {
-				if ops.Verbose {
-					fmt.Printf("❌ Failed delete file: %s/%s Error: %s\n", file, filepath.Base(st), errR)
-				}
+			if errR := os.Remove(st); errR != nil && !errors.Is(errR, os.ErrNotExist) {
+				ops.Log.Warn("Failed delete file", "file", file, "archive", filepath.Base(st), "error", errR)
 
+				mu.Lock()
 				lastErr = errR
+				mu.Unlock()
 
 				return
 			}
@@ -99,37 +300,29 @@ func Extract(file string, ops *options.CmdExtractOptions) error {
 	return lastErr
 }
 
-// ExtractBackup - unpack base tar file.
-func ExtractBackup(file string, ops *options.CmdExtractOptions) ([]string, error) {
+// ExtractBackup - unpack base tar file to dir, return extracted and skipped files and count of written bytes.
+func ExtractBackup(file, dir string, j *tarextractor.Journal,
+	ops *options.CmdExtractOptions) ([]string, []string, int64, error) {
 	r, err := os.Open(file)
 	if err != nil {
-		return nil, err
+		return nil, nil, 0, err
 	}
-	defer func() {
-		if err = r.Close(); err != nil {
-			logger.Fatalf("Backup: %s Error close file: %v", file, err)
-		}
-	}()
 
-	dir := ops.OutputDir
+	b := ops.Progress.NewBar(filepath.Base(file), fileSize(r))
 
-	if ops.ExtractToSubDir && dir != "" {
-		dir = filepath.Join(dir, tarextractor.GetBaseNameArchive(file))
-	} else if dir == "" {
-		dir = filepath.Join(filepath.Dir(file), tarextractor.GetBaseNameArchive(file))
+	te := tarextractor.New(dir, ops).WithJournal(j, filepath.Base(file))
+	fl, fs, err := te.Run(b.Reader(r))
+	if errC := r.Close(); err == nil {
+		err = errC
 	}
 
-	if _, errS := os.Stat(dir); errS == nil {
-		return nil, fmt.Errorf("dir %s is exists", dir) //nolint:err113 // Dynamic error
-	}
+	b.Done()
 
-	te := tarextractor.New(dir, ops)
-	fl, fs, errE := te.Run(r)
 	if len(fs) > 0 {
-		fmt.Printf("⚠️ In progress extract %s skipped %d file(s)\n", file, len(fs))
+		ops.Log.Warn("Skipped entries", "file", file, "count", len(fs))
 	}
 
-	return fl, errE
+	return fl, fs, te.Written(), err
 }
 
 // ValidateTarFile validates that the provided path exists and points to a tar archive.
@@ -152,40 +345,95 @@ func ValidateTarFile(p string) error {
 }
 
 // ExtractBackupItem - function for extract backup sub archive.
-func ExtractBackupItem(archName, fpath string, protected bool, decryptor decryptor.Decryptor,
-	ops *options.CmdExtractOptions) error {
+// Report is always returned, also when extract failed.
+func ExtractBackupItem(archName, fpath, instanceID string, protected bool, decryptor decryptor.Decryptor,
+	j *tarextractor.Journal, ops *options.CmdExtractOptions) (*output.ArchiveReport, error) {
 	fn := filepath.Base(fpath)
+	ar := output.NewArchiveReport(fn, protected)
+
+	if j != nil && j.IsDone(fn) {
+		ops.Log.Info("Skip already extracted archive", "file", archName, "archive", fn)
+
+		ar.Finish(nil, "")
+		ar.Status = output.StatusSkipped
+
+		return ar, nil
+	}
 
-	var k string
+	err := WrapError(extractBackupItem(archName, fpath, instanceID, protected, decryptor, j, ops, ar), protected)
+	ar.Finish(err, GetErrorCode(err))
+
+	return ar, err
+}
+
+func extractBackupItem(archName, fpath, instanceID string, protected bool, decryptor decryptor.Decryptor,
+	j *tarextractor.Journal, ops *options.CmdExtractOptions, ar *output.ArchiveReport) error {
+	fn := filepath.Base(fpath)
+
+	var k key.Key
 	if protected {
 		var err error
-		k, err = ops.Key.GetKey()
-		if err != nil {
+		open := func() (io.ReadCloser, error) { return os.Open(fpath) }
+		l := ops.Log.With("file", archName, "archive", fn)
+
+		if k, err = backup.FindKey(ops.Key, instanceID, decryptor, open, l); err != nil {
 			return err
 		}
+
+		ar.Key = k.Source
 	}
 
-	r, err := newTarGzReader(fpath, k, protected, decryptor)
+	b := ops.Progress.NewBar(filepath.Base(archName)+"/"+fn, 0)
+	defer b.Done()
+
+	r, err := newTarGzReader(fpath, k.Value, protected, decryptor, ops.Key, b)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if err = r.Close(); err != nil {
-			logger.Fatalf("File %s/%s Error close file: %v", archName, fn, err)
-		}
-	}()
 
-	if err = extractTarGz(r, fpath, "", ops); err != nil {
-		fmt.Printf("❌ Unable to extract %s/%s - possible wrong password or broken file\n", archName, fn)
+	var fs []string
+	fs, ar.Bytes, err = extractTarGz(r, fpath, "", j, ops)
+	if errC := r.Close(); err == nil {
+		err = errC
+	}
+
+	ar.Skipped = relPaths(filepath.Dir(fpath), fs)
+
+	if err != nil {
+		ops.Log.Error("Unable to extract archive, possible wrong password or broken file",
+			"file", archName, "archive", fn, "error", err)
 
 		return err
 	}
 
-	fmt.Printf("🔓 Extract success %s/%s... \n", archName, fn)
+	ops.Log.Info("Extract success", "file", archName, "archive", fn)
 
 	return nil
 }
 
+// removeAll - remove dir, read only dirs restored from archive are made writable before.
+func removeAll(p string) error {
+	if err := os.RemoveAll(p); err == nil {
+		return nil
+	}
+
+	_ = filepath.WalkDir(p, func(fp string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			_ = os.Chmod(fp, tarextractor.UnpackDirMod)
+		}
+
+		return nil
+	})
+
+	return os.RemoveAll(p)
+}
+
+func isExists(p string) bool {
+	_, err := os.Stat(p)
+
+	return err == nil
+}
+
 func filterFilesBySuffix(fl []string, suffix string) []string {
 	var fltg []string
 
@@ -221,21 +469,19 @@ func getBackupJSON(file string, fl []string, ops *options.CmdExtractOptions) (*B
 			h = true
 			var err error
 			if e, err = BackupConfigUnmarshalJSON(f); err != nil {
-				fmt.Printf("❌ Backup %s error unmarshal %s: %s\n", file, options.BackupJSON, err)
+				ops.Log.Error("Error unmarshal "+options.BackupJSON, "file", file, "error", err)
 
-				return nil, ErrBackupJSONUnmarshal
+				return nil, fmt.Errorf("%w: %w", ErrBackupJSONUnmarshal, err)
 			}
 		}
 	}
 
 	if !h {
-		if ops.Verbose {
-			fmt.Printf("⚠️ Backup %s not have %s\n", file, options.BackupJSON)
-		}
+		ops.Log.Debug("Backup not have "+options.BackupJSON, "file", file)
 
 		e = NewBackupConfig(hgz)
 
-		if ops.Key.IsEmKitPathSet() || ops.Key.IsPasswordSet() {
+		if ops.Key.IsSet() {
 			e.SetProtected(true)
 		}
 
@@ -243,15 +489,18 @@ func getBackupJSON(file string, fl []string, ops *options.CmdExtractOptions) (*B
 	}
 
 	if err := e.InitAndValidate(); err != nil {
-		fmt.Printf("❌ Backup %s error validate %s: %s\n", file, options.BackupJSON, err)
+		ops.Log.Error("Error validate "+options.BackupJSON, "file", file, "error", err)
 
-		return nil, ErrBackupJSONValidate
+		return nil, fmt.Errorf("%w: %w", ErrBackupJSONValidate, err)
 	}
 
 	return e, nil
 }
 
-func newTarGzReader(filename, passwd string, protected bool, decrypt decryptor.Decryptor) (*tarGzReader, error) {
+// newTarGzReader - open archive for read with decrypt if it protected, read bytes are counted by progress bar.
+// Progress is counted by read file, for SecureTar v3 by decrypted data because its size is known.
+func newTarGzReader(filename, passwd string, protected bool, decrypt decryptor.Decryptor, c decryptor.KeyCache,
+	b *progress.Bar) (*tarGzReader, error) {
 	var re tarGzReader
 
 	var err error
@@ -260,20 +509,47 @@ func newTarGzReader(filename, passwd string, protected bool, decrypt decryptor.D
 		return nil, err
 	}
 
+	b.SetTotal(fileSize(re.file))
+
 	if !protected {
-		re.ReadCloser = re.file
+		re.ReadCloser = io.NopCloser(b.Reader(re.file))
 
 		return &re, nil
 	}
 
-	re.ReadCloser, err = decryptor.New(re.file, decrypt, passwd)
+	if decrypt == decryptor.DecryptorSecureTarV3 {
+		re.ReadCloser, err = decryptor.New(re.file, decrypt, passwd, c)
+	} else {
+		re.ReadCloser, err = decryptor.New(b.Reader(re.file), decrypt, passwd, c)
+	}
+
 	if err != nil {
-		return nil, err
+		return nil, errors.Join(err, re.file.Close())
+	}
+
+	if ts, ok := decryptor.TotalSize(re.ReadCloser); ok {
+		b.SetTotal(ts)
+		re.ReadCloser = readCloser{Reader: b.Reader(re.ReadCloser), Closer: re.ReadCloser}
 	}
 
 	return &re, nil
 }
 
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// fileSize - get size of opened file, 0 if unknown.
+func fileSize(f *os.File) int64 {
+	s, err := f.Stat()
+	if err != nil {
+		return 0
+	}
+
+	return s.Size()
+}
+
 func (r *tarGzReader) Close() error {
 	if r.file != nil {
 		if err := r.file.Close(); err != nil {
@@ -284,11 +560,13 @@ func (r *tarGzReader) Close() error {
 	return r.ReadCloser.Close()
 }
 
-// extractTarGz - unpack tar.gz files after encrypt
-func extractTarGz(r io.Reader, filename, outputDir string, ops *options.CmdExtractOptions) error {
-	rg, err := gzip.NewReader(r)
+// extractTarGz - unpack tar.gz files after encrypt, return skipped files and count of written bytes.
+func extractTarGz(r io.Reader, filename, outputDir string, j *tarextractor.Journal,
+	ops *options.CmdExtractOptions) ([]string, int64, error) {
+	cr := limits.NewCountReader(r)
+	rg, err := gzip.NewReader(cr)
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
 
 	dir := outputDir
@@ -300,12 +578,25 @@ func extractTarGz(r io.Reader, filename, outputDir string, ops *options.CmdExtra
 	sOps.Include = nil
 	sOps.Exclude = nil
 
-	te := tarextractor.New(dir, &sOps)
-	_, fs, errE := te.Run(rg)
+	te := tarextractor.New(dir, &sOps).WithJournal(j, filepath.Base(filename))
+	_, fs, errE := te.Run(ops.Limits.RatioReader(rg, cr))
 	if len(fs) > 0 {
-		bn := filepath.Base(filename)
-		fmt.Printf("⚠️ In progress extract %s skipped %d file(s)\n", bn, len(fs))
+		ops.Log.Warn("Skipped entries", "archive", filepath.Base(filename), "count", len(fs))
+	}
+
+	return fs, te.Written(), errE
+}
+
+// relPaths - get paths relative to base dir, used for report paths independent of staging dir.
+func relPaths(base string, ps []string) []string {
+	rs := make([]string, 0, len(ps))
+	for _, p := range ps {
+		if r, err := filepath.Rel(base, p); err == nil {
+			p = filepath.ToSlash(r)
+		}
+
+		rs = append(rs, p)
 	}
 
-	return errE
+	return rs
 }
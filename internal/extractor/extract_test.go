@@ -0,0 +1,46 @@
+package extractor_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/librun/ha-backup-tool/internal/extractor"
+	"github.com/librun/ha-backup-tool/internal/key"
+	"github.com/librun/ha-backup-tool/internal/limits"
+	"github.com/librun/ha-backup-tool/internal/options"
+)
+
+func TestExtract_WithoutJSONDefaultStore(t *testing.T) {
+	tests := []struct {
+		name string
+		src  key.Sources
+	}{
+		{name: "without key", src: key.Sources{PasswordFD: key.NoFD}},
+		{
+			// default key store found without flag not makes backup protected
+			name: "default key store",
+			src:  key.Sources{PasswordFD: key.NoFD, Store: "not_exists.store", StoreImplicit: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := filepath.Join(t.TempDir(), "out")
+			ops := &options.CmdExtractOptions{
+				GlobalOptions: options.GlobalOptions{Key: key.NewStorage(tt.src), MaxArchiveSize: 1 << 20,
+					Limits: &limits.Limits{}},
+				OutputDir:      o,
+				SkipSpaceCheck: true,
+			}
+
+			if _, err := extractor.Extract("../../test_data/test_unprotected_without_json.tar", ops); err != nil {
+				t.Fatalf("Extract failed: %v", err)
+			}
+
+			if _, err := os.Stat(o); err != nil {
+				t.Errorf("Output dir not created: %v", err)
+			}
+		})
+	}
+}
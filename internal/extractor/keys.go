@@ -0,0 +1,41 @@
+package extractor
+
+import (
+	"github.com/librun/ha-backup-tool/internal/backup"
+	"github.com/librun/ha-backup-tool/internal/options"
+)
+
+var (
+	ErrKeyNotMatch = backup.ErrKeyNotMatch
+)
+
+// checkKitInstance - warn if emergency kits are made for other instance than backup.
+// Instance ID is only in kits made by keygen, kits of Home Assistant UI have only instance name.
+func checkKitInstance(file string, e *BackupConfig, ops *options.CmdExtractOptions) {
+	id := e.InstanceID()
+	if id == "" || !ops.Key.IsSet() {
+		return
+	}
+
+	// error of keys is returned on extract of archive
+	kts, err := ops.Key.Kits()
+	if err != nil {
+		return
+	}
+
+	var ids []string
+
+	for _, kt := range kts {
+		if kt.InstanceID == id {
+			return
+		}
+
+		if kt.InstanceID != "" {
+			ids = append(ids, kt.InstanceID)
+		}
+	}
+
+	if len(ids) > 0 {
+		ops.Log.Warn("Emergency kit is made for other instance", "file", file, "instance_id", id, "kit_instance_id", ids)
+	}
+}
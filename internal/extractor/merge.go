@@ -0,0 +1,144 @@
+package extractor
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/librun/ha-backup-tool/internal/conflict"
+)
+
+const (
+	// mergeDirMod - owner can move entries out of staged dir, mode from archive is applied after merge.
+	mergeDirMod = 0700
+)
+
+// MergeSummary - result of merge extracted backup into existing dir.
+type MergeSummary struct {
+	Added    []string
+	Replaced []string
+	Kept     []string
+}
+
+// MergeDir - move files from src dir into existing dst dir, conflicts are resolved by policy.
+// With preserve mode and times of dirs from src are applied to replaced dirs after their contents are merged.
+func MergeDir(src, dst string, p conflict.Policy, preserve bool) (*MergeSummary, error) {
+	var s MergeSummary
+
+	if err := mergeDir(src, dst, "", p, preserve, &s); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func mergeDir(src, dst, rel string, p conflict.Policy, preserve bool, s *MergeSummary) error {
+	es, err := os.ReadDir(filepath.Join(src, rel))
+	if err != nil {
+		return err
+	}
+
+	for _, en := range es {
+		r := filepath.Join(rel, en.Name())
+		sp := filepath.Join(src, r)
+		dp := filepath.Join(dst, r)
+
+		si, errS := os.Lstat(sp)
+		if errS != nil {
+			return errS
+		}
+
+		di, errD := os.Lstat(dp)
+		if errors.Is(errD, os.ErrNotExist) {
+			if err = os.Rename(sp, dp); err != nil {
+				return err
+			}
+
+			s.Added = append(s.Added, r)
+
+			continue
+		} else if errD != nil {
+			return errD
+		}
+
+		if si.IsDir() && di.IsDir() {
+			if err = mergeSubDir(src, dst, r, si, di, p, preserve, s); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if !isReplace(p, si, di) {
+			s.Kept = append(s.Kept, r)
+
+			continue
+		}
+
+		// rename can't replace dir or replace file by dir
+		if si.IsDir() || di.IsDir() {
+			if err = os.RemoveAll(dp); err != nil {
+				return err
+			}
+		}
+
+		if err = os.Rename(sp, dp); err != nil {
+			return err
+		}
+
+		s.Replaced = append(s.Replaced, r)
+	}
+
+	return nil
+}
+
+// mergeSubDir - merge dir which exists in src and dst, staged dir can be read only after extract.
+// Replace of dir is checked by times before merge, because merge changes time of dst dir.
+func mergeSubDir(src, dst, rel string, si, di os.FileInfo, p conflict.Policy, preserve bool,
+	s *MergeSummary) error {
+	sp, dp := filepath.Join(src, rel), filepath.Join(dst, rel)
+
+	if err := os.Chmod(sp, si.Mode().Perm()|mergeDirMod); err != nil {
+		return err
+	}
+
+	if err := mergeDir(src, dst, rel, p, preserve, s); err != nil {
+		return err
+	}
+
+	if !preserve || !isReplace(p, si, di) {
+		return nil
+	}
+
+	if err := os.Chmod(dp, si.Mode()&(fs.ModePerm|fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky)); err != nil {
+		return err
+	}
+
+	// merge changes time of dir, so time from archive is set last
+	return os.Chtimes(dp, time.Time{}, si.ModTime())
+}
+
+func isReplace(p conflict.Policy, si, di os.FileInfo) bool {
+	switch p {
+	case conflict.PolicyOverwrite:
+		return true
+	case conflict.PolicyNewer:
+		return si.ModTime().After(di.ModTime())
+	case conflict.PolicyFail, conflict.PolicySkip, conflict.PolicyRename:
+	}
+
+	return false
+}
+
+// getFreeDir - get not existing dir name by add number suffix.
+func getFreeDir(dir string) string {
+	for i := 1; ; i++ {
+		d := dir + "_" + strconv.Itoa(i)
+		if !isExists(d) {
+			return d
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package extractor_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/librun/ha-backup-tool/internal/conflict"
+	"github.com/librun/ha-backup-tool/internal/extractor"
+)
+
+func TestMergeDir(t *testing.T) {
+	var td = []struct {
+		Policy   conflict.Policy
+		Added    int
+		Replaced int
+		Kept     int
+		Old      string
+		New      string
+	}{
+		{Policy: conflict.PolicyOverwrite, Added: 1, Replaced: 2, Old: "new", New: "new"},
+		{Policy: conflict.PolicySkip, Added: 1, Kept: 2, Old: "old", New: "old"},
+		{Policy: conflict.PolicyNewer, Added: 1, Replaced: 1, Kept: 1, Old: "old", New: "new"},
+	}
+
+	for _, v := range td {
+		src, dst := t.TempDir(), t.TempDir()
+		now := time.Now()
+
+		writeTestFile(t, filepath.Join(dst, "data", "old.yaml"), "old", now)
+		writeTestFile(t, filepath.Join(dst, "data", "new.yaml"), "old", now.Add(-time.Hour))
+		writeTestFile(t, filepath.Join(src, "data", "old.yaml"), "new", now.Add(-time.Hour))
+		writeTestFile(t, filepath.Join(src, "data", "new.yaml"), "new", now)
+		writeTestFile(t, filepath.Join(src, "backup.json"), "{}", now)
+
+		s, err := extractor.MergeDir(src, dst, v.Policy, true)
+		if err != nil {
+			t.Fatalf("For policy %s MergeDir failed: %v", v.Policy, err)
+		}
+
+		if len(s.Added) != v.Added || len(s.Replaced) != v.Replaced || len(s.Kept) != v.Kept {
+			t.Errorf("For policy %s got added %d replaced %d kept %d wait %d %d %d", v.Policy,
+				len(s.Added), len(s.Replaced), len(s.Kept), v.Added, v.Replaced, v.Kept)
+		}
+
+		if b, _ := os.ReadFile(filepath.Join(dst, "data", "old.yaml")); string(b) != v.Old {
+			t.Errorf("For policy %s old.yaml got %s wait %s", v.Policy, b, v.Old)
+		}
+
+		if b, _ := os.ReadFile(filepath.Join(dst, "data", "new.yaml")); string(b) != v.New {
+			t.Errorf("For policy %s new.yaml got %s wait %s", v.Policy, b, v.New)
+		}
+
+		if _, err = os.Stat(filepath.Join(dst, "backup.json")); err != nil {
+			t.Errorf("For policy %s backup.json not added: %v", v.Policy, err)
+		}
+	}
+}
+
+func TestMergeDir_ReadOnlyDir(t *testing.T) {
+	var td = []struct {
+		Policy   conflict.Policy
+		Preserve bool
+		Mode     os.FileMode
+	}{
+		{Policy: conflict.PolicyOverwrite, Preserve: true, Mode: 0555},
+		{Policy: conflict.PolicyOverwrite, Preserve: false, Mode: 0750},
+		{Policy: conflict.PolicySkip, Preserve: true, Mode: 0750},
+	}
+
+	for _, v := range td {
+		src, dst := t.TempDir(), t.TempDir()
+		now := time.Now()
+		mt := now.Add(-24 * time.Hour).Truncate(time.Second)
+
+		writeTestFile(t, filepath.Join(dst, "data", "old.yaml"), "old", now)
+		writeTestFile(t, filepath.Join(src, "data", "new.yaml"), "new", now)
+
+		if err := os.Chmod(filepath.Join(dst, "data"), 0750); err != nil {
+			t.Fatalf("Chmod failed: %v", err)
+		}
+
+		// staged dir is read only after extract with preserved mode
+		sd := filepath.Join(src, "data")
+		if err := os.Chmod(sd, 0555); err != nil {
+			t.Fatalf("Chmod failed: %v", err)
+		}
+
+		if err := os.Chtimes(sd, mt, mt); err != nil {
+			t.Fatalf("Chtimes failed: %v", err)
+		}
+
+		if _, err := extractor.MergeDir(src, dst, v.Policy, v.Preserve); err != nil {
+			t.Fatalf("For policy %s preserve %v MergeDir failed: %v", v.Policy, v.Preserve, err)
+		}
+
+		if _, err := os.Stat(filepath.Join(dst, "data", "new.yaml")); err != nil {
+			t.Errorf("For policy %s preserve %v new.yaml not added: %v", v.Policy, v.Preserve, err)
+		}
+
+		s, err := os.Stat(filepath.Join(dst, "data"))
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+
+		if s.Mode().Perm() != v.Mode {
+			t.Errorf("For policy %s preserve %v got mode %v wait %v", v.Policy, v.Preserve, s.Mode().Perm(), v.Mode)
+		}
+
+		if v.Mode == 0555 && !s.ModTime().Equal(mt) {
+			t.Errorf("For policy %s preserve %v got time %v wait %v", v.Policy, v.Preserve, s.ModTime(), mt)
+		}
+
+		// temp dir is removed by test cleanup
+		if err = os.Chmod(filepath.Join(dst, "data"), 0755); err != nil {
+			t.Fatalf("Chmod failed: %v", err)
+		}
+	}
+}
+
+func writeTestFile(t *testing.T, p, data string, mt time.Time) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+
+	if err := os.WriteFile(p, []byte(data), 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	if err := os.Chtimes(p, mt, mt); err != nil {
+		t.Fatalf("Chtimes failed: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package extractor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/librun/ha-backup-tool/internal/backup"
+	"github.com/librun/ha-backup-tool/internal/datasize"
+	"github.com/librun/ha-backup-tool/internal/diskspace"
+	"github.com/librun/ha-backup-tool/internal/entity"
+	"github.com/librun/ha-backup-tool/internal/options"
+)
+
+var (
+	ErrNotEnoughSpace = errors.New("not enough free disk space")
+)
+
+// checkFreeSpace - check that disk with output dir have space for backup before extract.
+// Required space is size of backup file (archives inside backup are copied to staging dir)
+// plus sizes of home assistant and addons from backup.json.
+func checkFreeSpace(file, dir string, ops *options.CmdExtractOptions) error {
+	s, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+
+	rs := uint64(s.Size()) //nolint:gosec // file size is positive
+
+	b, err := backup.ReadJSON(file)
+	if err != nil {
+		ops.Log.Debug("Size of backup content unknown", "file", file, "error", err)
+	} else {
+		rs += GetBackupSize(b)
+	}
+
+	f, err := diskspace.Free(getExistsParent(dir))
+	if errors.Is(err, diskspace.ErrNotSupported) {
+		ops.Log.Debug("Skip check free disk space", "error", err)
+
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if f < rs {
+		return fmt.Errorf("%w for extract %s: required %d bytes, available %d bytes (use --skip-space-check for skip)",
+			ErrNotEnoughSpace, file, rs, f)
+	}
+
+	return nil
+}
+
+// GetBackupSize - get size of backup content in bytes, sizes in backup.json are in megabytes.
+func GetBackupSize(b *entity.HomeAssistantBackup) uint64 {
+	s := b.Homeassistant.Size
+	for _, a := range b.Addons {
+		s += a.Size
+	}
+
+	return uint64(s * float64(datasize.MebibyteSize/datasize.ByteSize))
+}
+
+// getExistsParent - get nearest exists dir for path, output dir can be not created yet.
+func getExistsParent(p string) string {
+	p = filepath.Clean(p)
+	for !isExists(p) {
+		n := filepath.Dir(p)
+		if n == p {
+			break
+		}
+
+		p = n
+	}
+
+	return p
+}
@@ -3,12 +3,85 @@ package flags
 const (
 	GlobalEmergency      = "emergency"
 	GlobalPassword       = "password"
+	GlobalPasswordFile   = "password-file"
+	GlobalPasswordFD     = "password-fd"
+	GlobalKeyring        = "keyring"
+	GlobalPasswordRaw    = "password-raw"
+	GlobalKeyCache       = "key-cache"
+	GlobalKeyStore       = "key-store"
 	GlobalMaxArchiveSize = "max-archive-size"
 	GlobalVerbose        = "verbose"
+	GlobalFormat         = "format"
+	GlobalLogLevel       = "log-level"
+	GlobalLogFormat      = "log-format"
+	GlobalLogFile        = "log-file"
+	GlobalProgress       = "progress"
+	GlobalMaxTotalSize   = "max-total-size"
+	GlobalMaxEntries     = "max-entries"
+	GlobalMaxPathDepth   = "max-path-depth"
+	GlobalMaxRatio       = "max-ratio"
+	GlobalConfig         = "config"
+	GlobalProfile        = "profile"
+	GlobalCatalog        = "catalog"
 
-	ExtractInclude         = "include"
-	ExtractExclude         = "exclude"
-	ExtractOutput          = "output"
-	ExtractCrypto          = "crypto"
-	ExtractSkipCreateLinks = "skip-create-links"
+	ExtractInclude          = "include"
+	ExtractExclude          = "exclude"
+	ExtractOutput           = "output"
+	ExtractCrypto           = "crypto"
+	ExtractSkipCreateLinks  = "skip-create-links"
+	ExtractKeepPartial      = "keep-partial"
+	ExtractResume           = "resume"
+	ExtractOnExists         = "on-exists"
+	ExtractNoPreserve       = "no-preserve"
+	ExtractSameOwner        = "same-owner"
+	ExtractCreateSpecial    = "create-special-files"
+	ExtractAllowUnsafeLinks = "allow-unsafe-links"
+	ExtractSkipSpaceCheck   = "skip-space-check"
+	ExtractJobs             = "jobs"
+
+	KeygenOutput     = "output"
+	KeygenInstance   = "instance"
+	KeygenInstanceID = "instance-id"
+	KeygenURL        = "url"
+	KeygenDate       = "date"
+	KeygenForce      = "force"
+
+	KeysLabel   = "label"
+	KeysName    = "name"
+	KeysDefault = "default"
+
+	CatalogRescan        = "rescan"
+	CatalogFrom          = "from"
+	CatalogTo            = "to"
+	CatalogInstance      = "instance"
+	CatalogAddon         = "addon"
+	CatalogType          = "type"
+	CatalogBeforeVersion = "before-version"
+	CatalogLimit         = "limit"
+
+	FindCrypto = "crypto"
+
+	HistoryOutputDir   = "output-dir"
+	HistoryNoDiff      = "no-diff"
+	HistoryCrypto      = "crypto"
+	HistoryMaxFileSize = "max-file-size"
+
+	DiffText         = "text"
+	DiffMetadataOnly = "metadata-only"
+	DiffCrypto       = "crypto"
+	DiffMaxFileSize  = "max-file-size"
+
+	PruneKeepDaily   = "keep-daily"
+	PruneKeepWeekly  = "keep-weekly"
+	PruneKeepMonthly = "keep-monthly"
+	PruneDryRun      = "dry-run"
+	PruneVerify      = "verify"
+	PruneCrypto      = "crypto"
+
+	RepackExcludeAddon    = "exclude-addon"
+	RepackExcludeFolder   = "exclude-folder"
+	RepackExcludeDatabase = "exclude-database"
+	RepackExclude         = "exclude"
+	RepackForce           = "force"
+	RepackCrypto          = "crypto"
 )
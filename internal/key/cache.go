@@ -0,0 +1,231 @@
+package key
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+const (
+	cacheDirName    = "ha-backup-tool"
+	cacheKeysDir    = "keys"
+	cacheSecretName = "key-cache.secret"
+	cacheSecretLen  = 32
+	cacheDirMode    = 0o700
+	cacheFileMode   = 0o600
+	cachePrefix     = "ha-backup-tool key cache v2\x00"
+	goosWindows     = "windows"
+)
+
+var (
+	ErrCacheDirNotPrivate = errors.New("key cache dir is accessible by other users")
+	ErrCacheSecretInvalid = errors.New("secret of key cache is not valid")
+)
+
+// DefaultCacheDir - dir of disk cache of derived keys in user cache dir.
+func DefaultCacheDir() (string, error) {
+	d, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(d, cacheDirName, cacheKeysDir), nil
+}
+
+// DefaultCacheSecret - file with secret for names of cache entries in user config dir,
+// it is kept out of cache dir, so cache dir alone is not enough for check of passwords.
+func DefaultCacheSecret() (string, error) {
+	d, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(d, cacheDirName, cacheSecretName), nil
+}
+
+// WithCacheDir - enable disk cache of derived keys in dir, names of entries are keyed by secret from file.
+// Empty dir disables disk cache, keys are cached only in memory.
+func (k *Storage) WithCacheDir(dir, secret string) *Storage {
+	k.cacheDir = dir
+	k.cacheSecret = secret
+
+	return k
+}
+
+// Get - get derived key from memory cache or from disk cache.
+func (k *Storage) Get(password string, salt []byte) ([]byte, bool) {
+	n := memoryCacheName(password, salt)
+
+	k.cmu.Lock()
+	defer k.cmu.Unlock()
+
+	if b, ok := k.cache[n]; ok {
+		return b, true
+	}
+
+	if k.cacheDir == "" {
+		return nil, false
+	}
+
+	// without secret disk cache is empty
+	s, err := readCacheSecret(k.cacheSecret)
+	if err != nil {
+		return nil, false
+	}
+
+	b, err := os.ReadFile(filepath.Join(k.cacheDir, diskCacheName(s, password, salt)))
+	if err != nil {
+		return nil, false
+	}
+
+	k.log.Debug("Derived key found in disk cache", "dir", k.cacheDir)
+	k.cache[n] = b
+
+	return b, true
+}
+
+// Put - save derived key into memory cache and disk cache, error of disk cache disables it for this run.
+func (k *Storage) Put(password string, salt, key []byte) {
+	k.cmu.Lock()
+	defer k.cmu.Unlock()
+
+	k.cache[memoryCacheName(password, salt)] = key
+
+	if k.cacheDir == "" {
+		return
+	}
+
+	err := checkCacheDir(k.cacheDir)
+
+	var s []byte
+	if err == nil {
+		s, err = getCacheSecret(k.cacheSecret)
+	}
+
+	if err == nil {
+		err = writeCacheFile(k.cacheDir, diskCacheName(s, password, salt), key)
+	}
+
+	if err != nil {
+		k.log.Warn("Disk cache of derived keys disabled", "dir", k.cacheDir, "error", err)
+
+		k.cacheDir = ""
+	}
+}
+
+// memoryCacheName - name of entry in memory cache, it is not written on disk.
+func memoryCacheName(password string, salt []byte) string {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(password))
+
+	return string(h.Sum(nil))
+}
+
+// diskCacheName - name of entry on disk is keyed MAC, so without secret names can't be used for check of password.
+func diskCacheName(secret []byte, password string, salt []byte) string {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(cachePrefix))
+	h.Write(salt)
+	h.Write([]byte(password))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func readCacheSecret(p string) ([]byte, error) {
+	s, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s) != cacheSecretLen {
+		return nil, ErrCacheSecretInvalid
+	}
+
+	return s, nil
+}
+
+// getCacheSecret - read secret or create new random secret readable only by owner.
+func getCacheSecret(p string) ([]byte, error) {
+	s, err := readCacheSecret(p)
+	if !errors.Is(err, os.ErrNotExist) {
+		return s, err
+	}
+
+	s = make([]byte, cacheSecretLen)
+	if _, err = rand.Read(s); err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(p), cacheDirMode); err != nil {
+		return nil, err
+	}
+
+	// secret is not replaced, if other run created it before
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_EXCL|os.O_WRONLY, cacheFileMode)
+	if errors.Is(err, os.ErrExist) {
+		return readCacheSecret(p)
+	} else if err != nil {
+		return nil, err
+	}
+
+	_, err = f.Write(s)
+	if errC := f.Close(); err == nil {
+		err = errC
+	}
+
+	if err != nil {
+		return nil, errors.Join(err, os.Remove(p))
+	}
+
+	return s, nil
+}
+
+// checkCacheDir - create cache dir, dir accessible by other users is not used.
+func checkCacheDir(dir string) error {
+	if err := os.MkdirAll(dir, cacheDirMode); err != nil {
+		return err
+	}
+
+	s, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+
+	// permissions of windows are not unix mode
+	if runtime.GOOS != goosWindows && s.Mode().Perm()&^cacheDirMode != 0 {
+		return ErrCacheDirNotPrivate
+	}
+
+	return nil
+}
+
+// writeCacheFile - write entry of cache readable only by owner, entry has derived key.
+func writeCacheFile(dir, n string, b []byte) error {
+	// temp file is created with mode 0600
+	f, err := os.CreateTemp(dir, n+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(b)
+
+	if errC := f.Close(); err == nil {
+		err = errC
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(dir, n))
+	}
+
+	if err != nil {
+		return errors.Join(err, os.Remove(f.Name()))
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package key_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/librun/ha-backup-tool/internal/key"
+)
+
+func TestStorage_Cache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "keys")
+	secret := filepath.Join(t.TempDir(), "config", "key-cache.secret")
+	salt := []byte("0123456789abcdef")
+	derived := []byte("derived key")
+
+	s := key.NewStorage(key.Sources{PasswordFD: key.NoFD}).WithCacheDir(dir, secret)
+	s.Put(keyA, salt, derived)
+
+	// new run reads key from disk
+	s = key.NewStorage(key.Sources{PasswordFD: key.NoFD}).WithCacheDir(dir, secret)
+
+	if got, ok := s.Get(keyA, salt); !ok || !bytes.Equal(got, derived) {
+		t.Errorf("Get() = %q, %v, want %q, true", got, ok, derived)
+	}
+
+	if _, ok := s.Get(keyB, salt); ok {
+		t.Error("Get() of other password found key")
+	}
+
+	// without disk cache key is only in memory
+	s = key.NewStorage(key.Sources{PasswordFD: key.NoFD})
+
+	if _, ok := s.Get(keyA, salt); ok {
+		t.Error("Get() without disk cache found key of other run")
+	}
+
+	// names of entries are keyed by secret, without it entries are not found
+	if err := os.Remove(secret); err != nil {
+		t.Fatal(err)
+	}
+
+	s = key.NewStorage(key.Sources{PasswordFD: key.NoFD}).WithCacheDir(dir, secret)
+
+	if _, ok := s.Get(keyA, salt); ok {
+		t.Error("Get() found key without secret of cache")
+	}
+}
+
+func TestStorage_CacheNameNotPasswordHash(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "keys")
+	salt := []byte("0123456789abcdef")
+
+	key.NewStorage(key.Sources{PasswordFD: key.NoFD}).WithCacheDir(dir, filepath.Join(t.TempDir(), "secret")).
+		Put(keyA, salt, []byte("derived key"))
+
+	// name of previous version of cache, it was fast oracle for password
+	h := sha256.New()
+	h.Write([]byte("ha-backup-tool key cache v1\x00"))
+	h.Write(salt)
+	h.Write([]byte(keyA))
+
+	es, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(es) != 1 || es[0].Name() == hex.EncodeToString(h.Sum(nil)) {
+		t.Errorf("Expected one entry not named by hash of password, got %v", es)
+	}
+}
+
+func TestStorage_CacheNotPrivateDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permissions of windows are not unix mode")
+	}
+
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0o755); err != nil { //nolint:gosec // test of not private dir
+		t.Fatal(err)
+	}
+
+	key.NewStorage(key.Sources{PasswordFD: key.NoFD}).WithCacheDir(dir, filepath.Join(t.TempDir(), "secret")).
+		Put(keyA, []byte("salt"), []byte("derived"))
+
+	es, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(es) != 0 {
+		t.Errorf("key is saved in not private dir: %v", es)
+	}
+}
@@ -0,0 +1,79 @@
+package key
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	keyAlphabet    = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	keyGroups      = 7
+	keyGroupLength = 4
+
+	// KitFileName - default name of emergency kit file.
+	KitFileName = "home_assistant_backup_emergency_kit.txt"
+	kitInfoURL  = "https://www.home-assistant.io/more-info/backup-emergency-kit"
+)
+
+// Generate - generate new key in emergency kit format by crypto/rand.
+func Generate() (string, error) {
+	var sb strings.Builder
+
+	n := big.NewInt(int64(len(keyAlphabet)))
+
+	for g := range keyGroups {
+		if g > 0 {
+			sb.WriteByte('-')
+		}
+
+		for range keyGroupLength {
+			i, err := rand.Int(rand.Reader, n)
+			if err != nil {
+				return "", err
+			}
+
+			sb.WriteByte(keyAlphabet[i.Int64()])
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// FormatKit - text of emergency kit in layout of Home Assistant, empty values are not written.
+func FormatKit(k *Kit) []byte {
+	var b bytes.Buffer
+
+	b.WriteString("Home Assistant Backup Emergency Kit\n\n")
+	b.WriteString("This emergency kit contains your backup encryption key. You need this key\n")
+	b.WriteString("to restore your Home Assistant backups.\n\n")
+	b.WriteString("Keep this key in a safe place, such as a password manager. Anyone who has\n")
+	b.WriteString("access to this key can access your backups.\n\n")
+
+	writeKitLabel(&b, KitLabelInstance, k.Instance)
+	writeKitLabel(&b, KitLabelInstanceID, k.InstanceID)
+	writeKitLabel(&b, KitLabelURL, k.URL)
+
+	if !k.Created.IsZero() {
+		writeKitLabel(&b, KitLabelCreated, k.Created.Format(time.RFC3339))
+	}
+
+	for _, t := range k.Keys {
+		writeKitLabel(&b, KitLabelKey, t)
+	}
+
+	fmt.Fprintf(&b, "For more information visit: %s\n", kitInfoURL)
+
+	return b.Bytes()
+}
+
+func writeKitLabel(b *bytes.Buffer, label, v string) {
+	if v == "" {
+		return
+	}
+
+	fmt.Fprintf(b, "%s:\n%s\n\n", label, v)
+}
@@ -0,0 +1,55 @@
+package key_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/librun/ha-backup-tool/internal/key"
+)
+
+func TestGenerate(t *testing.T) {
+	a, err := key.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := key.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a == b {
+		t.Errorf("Generate() returned same key twice: %s", a)
+	}
+
+	// generated key is accepted as key in emergency kit format
+	ks, err := key.NewStorage(key.Sources{Password: []string{a}, PasswordFD: key.NoFD}).Keys()
+	if err != nil {
+		t.Fatalf("Keys() of generated key error = %v", err)
+	}
+
+	if ks[0].Value != a {
+		t.Errorf("Keys() = %q, want %q", ks[0].Value, a)
+	}
+}
+
+func TestFormatKit(t *testing.T) {
+	want := key.Kit{
+		Instance:   "Home",
+		InstanceID: "abc",
+		URL:        "http://homeassistant.local:8123/",
+		Created:    time.Date(2025, 5, 19, 22, 0, 0, 0, time.UTC),
+		Keys:       []string{keyA},
+	}
+
+	got, err := key.ParseKit(key.FormatKit(&want))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Instance != want.Instance || got.InstanceID != want.InstanceID || got.URL != want.URL ||
+		!got.Created.Equal(want.Created) || !slices.Equal(got.Keys, want.Keys) {
+		t.Errorf("ParseKit(FormatKit()) = %+v, want %+v", *got, want)
+	}
+}
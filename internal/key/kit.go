@@ -0,0 +1,171 @@
+package key
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Labels of Home Assistant emergency kit.
+const (
+	KitLabelInstance   = "Instance"
+	KitLabelInstanceID = "Instance ID"
+	KitLabelURL        = "URL"
+	KitLabelCreated    = "Created"
+	KitLabelKey        = "Encryption key"
+
+	kitPDFMagic = "%PDF-"
+)
+
+//nolint:gochecknoglobals // This is const varible
+var (
+	kitDateLayouts = []string{time.RFC3339, time.DateTime, time.DateOnly}
+)
+
+var (
+	ErrKitPDFNotHaveKey = errors.New("key not found in pdf emergency kit, export pdf to text and use text file")
+)
+
+// Kit - emergency kit of Home Assistant backup.
+// Text of kit has labels with value on same line or on next line:
+//
+//	Instance:
+//	Home
+//
+//	Encryption key:
+//	XXXX-XXXX-XXXX-XXXX-XXXX-XXXX-XXXX
+//
+// Instance ID and creation date are not in kit of Home Assistant UI, they are set by keygen.
+type Kit struct {
+	Path       string
+	Instance   string
+	InstanceID string
+	URL        string
+	Created    time.Time
+	Keys       []string
+}
+
+// ReadKit - read and parse emergency kit file.
+func ReadKit(p string) (*Kit, error) {
+	s, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.IsDir() {
+		return nil, ErrFileNotValid
+	}
+
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	k, err := ParseKit(b)
+	if err != nil {
+		return nil, err
+	}
+
+	k.Path = p
+
+	return k, nil
+}
+
+// ParseKit - parse text of emergency kit, key under label is first, other keys in text are added after it.
+// Text of PDF is not parsed, only keys from not compressed text of PDF are found.
+func ParseKit(b []byte) (*Kit, error) {
+	var k Kit
+
+	if bytes.HasPrefix(b, []byte(kitPDFMagic)) {
+		k.addKeys(b)
+
+		if len(k.Keys) == 0 {
+			return nil, ErrKitPDFNotHaveKey
+		}
+
+		return &k, nil
+	}
+
+	var label string
+
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		l := strings.TrimSpace(sc.Text())
+		if l == "" {
+			continue
+		}
+
+		if n, v, ok := strings.Cut(l, ":"); ok && isKitLabel(n) {
+			label = strings.TrimSpace(n)
+			l = strings.TrimSpace(v)
+
+			if l == "" {
+				continue
+			}
+		}
+
+		if label != "" {
+			k.set(label, l)
+			label = ""
+		}
+	}
+
+	k.addKeys(b)
+
+	if len(k.Keys) == 0 {
+		return nil, ErrEmergencyFileNotHaveKey
+	}
+
+	return &k, nil
+}
+
+// set - set value of label.
+func (k *Kit) set(label, v string) {
+	switch {
+	case strings.EqualFold(label, KitLabelInstance):
+		k.Instance = v
+	case strings.EqualFold(label, KitLabelInstanceID):
+		k.InstanceID = v
+	case strings.EqualFold(label, KitLabelURL):
+		k.URL = v
+	case strings.EqualFold(label, KitLabelCreated):
+		k.Created = parseKitDate(v)
+	case strings.EqualFold(label, KitLabelKey):
+		k.addKeys([]byte(v))
+	}
+}
+
+// addKeys - add keys found in text, same key is added once.
+func (k *Kit) addKeys(b []byte) {
+	for _, t := range regexpKeyExtract.FindAll(b, -1) {
+		s := string(t)
+
+		if !slices.Contains(k.Keys, s) {
+			k.Keys = append(k.Keys, s)
+		}
+	}
+}
+
+func isKitLabel(n string) bool {
+	for _, l := range []string{KitLabelInstance, KitLabelInstanceID, KitLabelURL, KitLabelCreated, KitLabelKey} {
+		if strings.EqualFold(strings.TrimSpace(n), l) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseKitDate(v string) time.Time {
+	for _, l := range kitDateLayouts {
+		if t, err := time.Parse(l, v); err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
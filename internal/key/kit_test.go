@@ -0,0 +1,76 @@
+package key_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/librun/ha-backup-tool/internal/key"
+)
+
+const kitText = `Home Assistant Backup Emergency Kit
+
+This emergency kit contains your backup encryption key. You need this key
+to restore your Home Assistant backups.
+
+Instance:
+My Home: main
+
+URL:
+http://homeassistant.local:8123/
+
+Encryption key:
+BBBB-BBBB-BBBB-BBBB-BBBB-BBBB-BBBB
+
+For more information visit: https://www.home-assistant.io/more-info/backup-emergency-kit
+`
+
+func TestParseKit(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    key.Kit
+		wantErr error
+	}{
+		{
+			name: "home assistant kit",
+			text: kitText,
+			want: key.Kit{Instance: "My Home: main", URL: "http://homeassistant.local:8123/", Keys: []string{keyB}},
+		},
+		{
+			name: "labels with value on same line",
+			text: "Instance ID: abc\nCreated: 2025-05-19\nEncryption key: " + keyA + "\n",
+			want: key.Kit{
+				InstanceID: "abc", Created: time.Date(2025, 5, 19, 0, 0, 0, 0, time.UTC), Keys: []string{keyA},
+			},
+		},
+		{
+			name: "key under label is first",
+			text: "Old key " + keyA + "\nEncryption key:\n" + keyB + "\n" + keyA + "\n",
+			want: key.Kit{Keys: []string{keyB, keyA}},
+		},
+		{name: "plain key", text: keyC + "\n", want: key.Kit{Keys: []string{keyC}}},
+		{name: "pdf", text: "%PDF-1.4\n(" + keyA + ") Tj\n", want: key.Kit{Keys: []string{keyA}}},
+		{name: "compressed pdf", text: "%PDF-1.4\nstream\n", wantErr: key.ErrKitPDFNotHaveKey},
+		{name: "without key", text: "Encryption key:\n\n", wantErr: key.ErrEmergencyFileNotHaveKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := key.ParseKit([]byte(tt.text))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseKit() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if got.Instance != tt.want.Instance || got.InstanceID != tt.want.InstanceID || got.URL != tt.want.URL ||
+				!got.Created.Equal(tt.want.Created) || !slices.Equal(got.Keys, tt.want.Keys) {
+				t.Errorf("ParseKit() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -1,17 +1,45 @@
 package key
 
 import (
-	"bufio"
+	"cmp"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
+
+	"golang.org/x/term"
+
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+	"github.com/librun/ha-backup-tool/internal/keystore"
 )
 
 const (
+	// EnvKey - environment variable with key.
+	EnvKey = "HA_BACKUP_KEY"
+
+	// NoFD - file descriptor not set.
+	NoFD = -1
+
 	regexpKeyFormat = "([A-Z0-9]{4}-){6}[A-Z0-9]{4}"
+	maxSourceSize   = 4096
+)
+
+// Names of key sources for messages.
+const (
+	SourcePassword     = "password"
+	SourcePasswordFD   = "password-fd"
+	SourcePasswordFile = "password-file"
+	SourceEmergency    = "emergency"
+	SourceKeyring      = "keyring"
+	SourceEnv          = "env"
+	SourceStore        = "store"
+	SourcePrompt       = "prompt"
 )
 
 var (
@@ -20,140 +48,539 @@ var (
 
 	ErrEmergencyFileNotHaveKey = errors.New("emergency file not have key")
 	ErrPasswordNotValid        = errors.New("password not valid format")
+	ErrPasswordEmpty           = errors.New("password is empty")
 	ErrFileNotValid            = errors.New("file not valid")
+	ErrKeyringEmpty            = errors.New("keyring dir not have keys")
+	ErrKeyNotSet               = errors.New("key not set and stdin is not a terminal for prompt, " +
+		"use --password-file, --password-fd, --emergency, --keyring or " + EnvKey)
 )
 
+// Sources - sources of key, keys of all set sources are tried by order:
+// passwords, password fd, password file, emergency kits, keyring dir, environment, key store.
+// Prompt is used only if no source is set.
+// Raw allows free-form passwords, without it key must be in emergency kit format.
+// StoreImplicit is set when Store is default key store found without flag.
+type Sources struct {
+	Password      []string
+	PasswordFD    int
+	PasswordFile  string
+	Emergency     []string
+	Keyring       string
+	Env           string
+	Raw           bool
+	Store         string
+	StoreImplicit bool
+}
+
+// IsSet - key is set by any source except prompt, default key store found without flag is not counted,
+// so backup without backup.json is not guessed as protected only because key store exists.
+func (s Sources) IsSet() bool {
+	return len(s.Password) > 0 || s.PasswordFD != NoFD || s.PasswordFile != "" || len(s.Emergency) > 0 ||
+		s.Keyring != "" || s.Env != "" || (s.Store != "" && !s.StoreImplicit)
+}
+
+// Key - key for decrypt archive, source is name of key for messages and report instead of key.
+// Label is instance ID of Home Assistant for key from key store.
+type Key struct {
+	Value  string
+	Source string
+	Label  string
+}
+
 type Storage struct {
-	mu     sync.Mutex
-	emKit  string
-	passwd string
-	key    string
-	inited bool
+	mu           sync.Mutex
+	src          Sources
+	keys         []Key
+	kits         []*Kit
+	storeDefault string
+	err          error
+	inited       bool
+	in           *os.File
+	out          io.Writer
+	log          *slog.Logger
+
+	cmu         sync.Mutex
+	cache       map[string][]byte
+	cacheDir    string
+	cacheSecret string
 }
 
-// GetKey - get password key for decrypt archive.
-func NewStorage(e, p string) *Storage {
+// NewStorage - create storage of keys for decrypt archive.
+func NewStorage(s Sources) *Storage {
 	return &Storage{
-		mu:     sync.Mutex{},
-		emKit:  e,
-		passwd: p,
+		mu:    sync.Mutex{},
+		src:   s,
+		in:    os.Stdin,
+		out:   os.Stdout,
+		log:   slog.New(slog.DiscardHandler),
+		cmu:   sync.Mutex{},
+		cache: make(map[string][]byte),
 	}
 }
 
-func (k *Storage) IsPasswordSet() bool {
-	return k.passwd != ""
+// WithInput - set terminal for prompt of key.
+func (k *Storage) WithInput(f *os.File) *Storage {
+	k.in = f
+
+	return k
+}
+
+// WithOutput - set writer for prompt of key.
+func (k *Storage) WithOutput(w io.Writer) *Storage {
+	k.out = w
+
+	return k
+}
+
+// WithLogger - set logger for messages about key.
+func (k *Storage) WithLogger(l *slog.Logger) *Storage {
+	k.log = l
+
+	return k
 }
-func (k *Storage) IsEmKitPathSet() bool {
-	return k.emKit != ""
+
+// IsSet - key is set by any source except prompt.
+func (k *Storage) IsSet() bool {
+	return k.src.IsSet()
 }
 
-func (k *Storage) GetKey() (string, error) {
+// Keys - get keys for try decrypt archive, last used key is first.
+func (k *Storage) Keys() ([]Key, error) {
 	k.mu.Lock()
 	defer k.mu.Unlock()
 
+	// error is saved too, sources like file descriptor can be read only once
 	if !k.inited {
-		key, err := GetKey(k.emKit, k.passwd)
-		if err != nil {
-			return "", err
+		k.keys, k.err = k.load()
+		k.inited = true
+	}
+
+	if k.err != nil {
+		return nil, k.err
+	}
+
+	return slices.Clone(k.keys), nil
+}
+
+// KeysFor - get keys for backup of instance: key from key store with label of instance is first,
+// then default key of key store, then other keys with last used key first.
+func (k *Storage) KeysFor(instanceID string) ([]Key, error) {
+	ks, err := k.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	def := k.storeDefault
+	k.mu.Unlock()
+
+	const (
+		rankInstance = iota
+		rankDefault
+		rankOther
+	)
+
+	rank := func(e Key) int {
+		switch {
+		case e.Label == "":
+			return rankOther
+		case e.Label == instanceID:
+			return rankInstance
+		case e.Label == def:
+			return rankDefault
 		}
 
-		k.key = key
-		k.inited = true
+		return rankOther
 	}
 
-	return k.key, nil
+	slices.SortStableFunc(ks, func(a, b Key) int { return cmp.Compare(rank(a), rank(b)) })
+
+	return ks, nil
 }
 
-func GetKey(e, p string) (string, error) {
-	var key string
+// Len - count of loaded keys.
+func (k *Storage) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
 
-	switch {
-	case p != "":
-		p = strings.TrimSpace(p)
+	return len(k.keys)
+}
+
+// Use - mark key as opened archive, so it is tried first for next archives.
+func (k *Storage) Use(key Key) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	i := slices.Index(k.keys, key)
+	if i <= 0 {
+		return
+	}
+
+	k.keys = slices.Insert(slices.Delete(k.keys, i, i+1), 0, key)
+}
+
+// load - get keys from all set sources, same keys from different sources are used once.
+func (k *Storage) load() ([]Key, error) {
+	if !k.src.IsSet() && k.src.Store == "" {
+		t, err := k.prompt()
+		if err != nil {
+			return nil, err
+		}
+
+		return []Key{{Value: t, Source: SourcePrompt}}, nil
+	}
+
+	var ks []Key
 
-		if !keyValidate(p) {
-			fmt.Println("❌ Invalid key format.")
+	add := func(src, t string) error {
+		return k.addKey(&ks, Key{Value: t, Source: src})
+	}
 
-			return "", ErrPasswordNotValid
+	for i, p := range k.src.Password {
+		if err := add(fmt.Sprintf("%s#%d", SourcePassword, i+1), p); err != nil {
+			return nil, err
 		}
+	}
 
-		key = p
-		fmt.Println("✅ Key format verified")
-	case e != "":
-		t, err := extractKeyFromKit(e)
+	if k.src.PasswordFD != NoFD {
+		t, err := readFD(k.src.PasswordFD)
 		if err != nil {
-			fmt.Println("⚠️  Could not find encryption key in emergency kit file.")
+			k.log.Error("Could not read key from file descriptor", "fd", k.src.PasswordFD, "error", err)
 
-			return "", err
+			return nil, err
+		}
+
+		if err = add(SourcePasswordFD, t); err != nil {
+			return nil, err
+		}
+	}
+
+	if k.src.PasswordFile != "" {
+		t, err := readFile(k.src.PasswordFile)
+		if err != nil {
+			k.log.Error("Could not read key from file", "file", k.src.PasswordFile, "error", err)
+
+			return nil, err
 		}
 
-		t = strings.TrimSpace(t)
+		if err = add(SourcePasswordFile+":"+k.src.PasswordFile, t); err != nil {
+			return nil, err
+		}
+	}
 
-		key = t
+	for _, e := range k.src.Emergency {
+		kt, err := ReadKit(e)
+		if err != nil {
+			k.log.Error("Could not find encryption key in emergency kit file", "file", e, "error", err)
 
-		fmt.Println("✅ Found encryption key in " + key)
-	default:
-		fmt.Println("\nPlease enter your encryption key manually.")
-		fmt.Println("It should be in the format: XXXX-XXXX-XXXX-XXXX-XXXX-XXXX-XXXX")
+			return nil, wrapKitError(err)
+		}
 
-		for {
-			t, err := getKetManual()
-			if err != nil {
-				return "", err
+		for _, t := range k.addKit(SourceEmergency, kt) {
+			if err = add(t.Source, t.Value); err != nil {
+				return nil, err
 			}
+		}
+	}
 
-			t = strings.TrimSpace(t)
-
-			if !keyValidate(t) {
-				fmt.Println("❌ Invalid key format. Please try again.")
+	if k.src.Keyring != "" {
+		kr, err := k.readKeyring(k.src.Keyring)
+		if err != nil {
+			return nil, err
+		}
 
-				continue
+		for _, e := range kr {
+			if err = add(e.Source, e.Value); err != nil {
+				return nil, err
 			}
+		}
+	}
+
+	if k.src.Env != "" {
+		if err := add(SourceEnv+":"+EnvKey, k.src.Env); err != nil {
+			return nil, err
+		}
+	}
 
-			key = t
-			fmt.Println("✅ Key format verified")
+	if k.src.Store != "" {
+		st, err := k.loadStore()
+		if err != nil {
+			return nil, err
+		}
 
-			break
+		for _, e := range st.Entries {
+			if err = k.addKey(&ks, Key{Value: e.Key, Source: SourceStore + ":" + e.Label, Label: e.Label}); err != nil {
+				return nil, err
+			}
 		}
 	}
 
-	return key, nil
+	k.log.Info("Keys loaded", "count", len(ks))
+
+	return ks, nil
 }
 
-func getKetManual() (string, error) {
-	reader := bufio.NewReader(os.Stdin)
-	text, err := reader.ReadString('\n')
+// addKey - validate key and add it, same key is added once, label of key from store is kept.
+func (k *Storage) addKey(ks *[]Key, key Key) error {
+	t, err := k.validate(key.Source, key.Value)
 	if err != nil {
-		return "", err
+		return err
+	}
+
+	key.Value = t
+
+	i := slices.IndexFunc(*ks, func(e Key) bool { return e.Value == t })
+	switch {
+	case i < 0:
+		*ks = append(*ks, key)
+	case (*ks)[i].Label == "":
+		(*ks)[i].Label = key.Label
 	}
 
-	return text, nil
+	return nil
 }
 
-func keyValidate(k string) bool {
-	return regexpKeyValidate.MatchString(k)
+// loadStore - decrypt key store by passphrase from environment or prompt.
+func (k *Storage) loadStore() (*keystore.Store, error) {
+	p, err := keystore.Passphrase(k.in, k.out, false)
+	if err != nil {
+		return nil, err
+	}
+
+	st, err := keystore.Load(k.src.Store, p)
+	if err != nil {
+		k.log.Error("Could not open key store", "file", k.src.Store, "error", err)
+
+		return nil, err
+	}
+
+	k.storeDefault = st.Default
+	k.log.Info("Found encryption keys in key store", "file", k.src.Store, "count", len(st.Entries))
+
+	return st, nil
+}
+
+// readKeyring - read keys from all files of dir, file can be emergency kit or have several keys.
+func (k *Storage) readKeyring(dir string) ([]Key, error) {
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		k.log.Error("Could not read keyring dir", "dir", dir, "error", err)
+
+		return nil, err
+	}
+
+	var ks []Key
+
+	for _, de := range des {
+		if !de.Type().IsRegular() {
+			continue
+		}
+
+		p := filepath.Join(dir, de.Name())
+
+		kt, err := ReadKit(p)
+		switch {
+		case errors.Is(err, ErrEmergencyFileNotHaveKey), errors.Is(err, ErrKitPDFNotHaveKey):
+			k.log.Debug("Skip keyring file without key", "file", p, "error", err)
+
+			continue
+		case err != nil:
+			k.log.Error("Could not read keyring file", "file", p, "error", err)
+
+			return nil, err
+		}
+
+		ks = append(ks, k.addKit(SourceKeyring, kt)...)
+	}
+
+	if len(ks) == 0 {
+		return nil, exitcode.Wrap(exitcode.WrongKey, fmt.Errorf("%w: %s", ErrKeyringEmpty, dir))
+	}
+
+	k.log.Info("Found encryption keys in keyring dir", "dir", dir, "count", len(ks))
+
+	return ks, nil
+}
+
+// addKit - save kit for check of instance and get its keys, name of key has number if kit has several keys.
+func (k *Storage) addKit(src string, kt *Kit) []Key {
+	k.kits = append(k.kits, kt)
+
+	k.log.Info("Found encryption key in emergency kit file", "file", kt.Path, "instance", kt.Instance,
+		"keys", len(kt.Keys))
+
+	if len(kt.Keys) > 1 {
+		k.log.Warn("Emergency kit has several keys, all keys are tried", "file", kt.Path, "keys", len(kt.Keys))
+	}
+
+	ks := make([]Key, 0, len(kt.Keys))
+
+	for i, t := range kt.Keys {
+		n := src + ":" + kt.Path
+		if len(kt.Keys) > 1 {
+			n = fmt.Sprintf("%s#%d", n, i+1)
+		}
+
+		ks = append(ks, Key{Value: t, Source: n})
+	}
+
+	return ks
+}
+
+// Kits - get loaded emergency kits, keys are loaded if they are not loaded yet.
+func (k *Storage) Kits() ([]*Kit, error) {
+	if _, err := k.Keys(); err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return slices.Clone(k.kits), nil
+}
+
+// validate - check format of key from source, raw password is used as is without check of format.
+func (k *Storage) validate(src, t string) (string, error) {
+	if k.src.Raw {
+		return k.validateRaw(src, t)
+	}
+
+	t = strings.TrimSpace(t)
+
+	if !keyValidate(t) {
+		k.log.Error("Invalid key format", "source", src)
+
+		return "", exitcode.Wrap(exitcode.WrongKey,
+			fmt.Errorf("%w: %s, use --password-raw for free-form password", ErrPasswordNotValid, src))
+	}
+
+	k.log.Debug("Key format verified", "source", src)
+
+	return t, nil
 }
 
-func extractKeyFromKit(p string) (string, error) {
+// validateRaw - check raw password, only line break is removed, spaces are part of password.
+func (k *Storage) validateRaw(src, t string) (string, error) {
+	t = strings.TrimRight(t, "\r\n")
+
+	if t == "" {
+		k.log.Error("Empty password", "source", src)
+
+		return "", exitcode.Wrap(exitcode.WrongKey, fmt.Errorf("%w: %s", ErrPasswordEmpty, src))
+	}
+
+	if !keyValidate(t) {
+		k.log.Debug("Key not in emergency kit format, used as raw password", "source", src)
+	}
+
+	return t, nil
+}
+
+// prompt - read key from terminal without echo, not terminal stdin is error and not blocked.
+func (k *Storage) prompt() (string, error) {
+	fd := int(k.in.Fd()) //nolint:gosec // file descriptor fit in int
+
+	if !term.IsTerminal(fd) {
+		return "", exitcode.Wrap(exitcode.WrongKey, ErrKeyNotSet)
+	}
+
+	if k.src.Raw {
+		fmt.Fprintln(k.out, "\nPlease enter your backup password or encryption key manually.")
+		fmt.Fprintln(k.out, "Password is used as is, encryption key is in the format: XXXX-XXXX-XXXX-XXXX-XXXX-XXXX-XXXX")
+	} else {
+		fmt.Fprintln(k.out, "\nPlease enter your encryption key manually.")
+		fmt.Fprintln(k.out, "It should be in the format: XXXX-XXXX-XXXX-XXXX-XXXX-XXXX-XXXX")
+		fmt.Fprintln(k.out, "For backup with free-form password run command with --password-raw.")
+	}
+
+	for {
+		fmt.Fprint(k.out, "Key: ")
+
+		b, err := term.ReadPassword(fd)
+		fmt.Fprintln(k.out)
+
+		if err != nil {
+			return "", err
+		}
+
+		t := string(b)
+		if !k.src.Raw {
+			t = strings.TrimSpace(t)
+		}
+
+		switch {
+		case t == "":
+			fmt.Fprintln(k.out, "❌ Empty key. Please try again.")
+
+			continue
+		case !k.src.Raw && !keyValidate(t):
+			fmt.Fprintln(k.out, "❌ Invalid key format. Please try again or run command with --password-raw.")
+
+			continue
+		}
+
+		k.log.Info("Key entered", "source", SourcePrompt)
+
+		return t, nil
+	}
+}
+
+// wrapKitError - set class of error of emergency kit, kit without key is wrong key.
+func wrapKitError(err error) error {
+	switch {
+	case errors.Is(err, ErrEmergencyFileNotHaveKey):
+		return exitcode.Wrap(exitcode.WrongKey, err)
+	case errors.Is(err, ErrFileNotValid):
+		return exitcode.Wrap(exitcode.BadArgs, err)
+	}
+
+	return err
+}
+
+// readFD - read key from file descriptor, descriptor is closed after read.
+func readFD(fd int) (string, error) {
+	if fd < 0 {
+		return "", exitcode.Wrap(exitcode.BadArgs, fmt.Errorf("%w: fd %d", ErrFileNotValid, fd))
+	}
+
+	f := os.NewFile(uintptr(fd), SourcePasswordFD)
+	defer f.Close()
+
+	return readSource(f)
+}
+
+// readFile - read key from file.
+func readFile(p string) (string, error) {
 	s, err := os.Stat(p)
 	if err != nil {
 		return "", err
 	}
 
 	if s.IsDir() {
-		return "", ErrFileNotValid
+		return "", exitcode.Wrap(exitcode.BadArgs, fmt.Errorf("%w: %s", ErrFileNotValid, p))
 	}
 
-	br, err := os.ReadFile(p)
+	f, err := os.Open(p)
 	if err != nil {
 		return "", err
 	}
+	defer f.Close()
 
-	b := regexpKeyExtract.Find(br)
+	return readSource(f)
+}
 
-	if b == nil {
-		return "", ErrEmergencyFileNotHaveKey
+// readSource - read first line of source.
+func readSource(r io.Reader) (string, error) {
+	b, err := io.ReadAll(io.LimitReader(r, maxSourceSize))
+	if err != nil {
+		return "", err
 	}
 
-	return string(b), nil
+	t, _, _ := strings.Cut(string(b), "\n")
+
+	return t, nil
+}
+
+func keyValidate(k string) bool {
+	return regexpKeyValidate.MatchString(k)
 }
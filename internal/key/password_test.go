@@ -0,0 +1,267 @@
+package key_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+	"github.com/librun/ha-backup-tool/internal/key"
+	"github.com/librun/ha-backup-tool/internal/keystore"
+)
+
+const (
+	keyA = "AAAA-AAAA-AAAA-AAAA-AAAA-AAAA-AAAA"
+	keyB = "BBBB-BBBB-BBBB-BBBB-BBBB-BBBB-BBBB"
+	keyC = "CCCC-CCCC-CCCC-CCCC-CCCC-CCCC-CCCC"
+)
+
+func TestStorage_Keys(t *testing.T) {
+	dir := t.TempDir()
+	pf := filepath.Join(dir, "password")
+	kit := filepath.Join(dir, "emergency.txt")
+	bad := filepath.Join(dir, "bad")
+
+	if err := os.WriteFile(pf, []byte(keyB+"\nsecond line\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(kit, []byte("Encryption key:\n"+keyC+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(bad, []byte("not a key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		src     key.Sources
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "all sources by order",
+			src: key.Sources{
+				Password: []string{keyA}, PasswordFD: key.NoFD, PasswordFile: pf, Emergency: []string{kit}, Env: keyC,
+			},
+			want: []string{keyA, keyB, keyC},
+		},
+		{
+			name: "emergency before env",
+			src:  key.Sources{PasswordFD: key.NoFD, Emergency: []string{kit}, Env: keyA},
+			want: []string{keyC, keyA},
+		},
+		{name: "env", src: key.Sources{PasswordFD: key.NoFD, Env: " " + keyA + "\n"}, want: []string{keyA}},
+		{
+			name: "keyring",
+			src:  key.Sources{PasswordFD: key.NoFD, Keyring: dir, Env: keyA},
+			want: []string{keyC, keyB, keyA},
+		},
+		{
+			name:    "not valid password file",
+			src:     key.Sources{PasswordFD: key.NoFD, PasswordFile: bad},
+			wantErr: key.ErrPasswordNotValid,
+		},
+		{
+			name:    "not valid env",
+			src:     key.Sources{PasswordFD: key.NoFD, Env: "secret"},
+			wantErr: key.ErrPasswordNotValid,
+		},
+		{
+			name: "raw password is used as is",
+			src:  key.Sources{Password: []string{" free form, "}, PasswordFD: key.NoFD, Env: keyA, Raw: true},
+			want: []string{" free form, ", keyA},
+		},
+		{
+			name: "raw password file without line break",
+			src:  key.Sources{PasswordFD: key.NoFD, PasswordFile: bad, Raw: true},
+			want: []string{"not a key"},
+		},
+		{
+			name:    "raw empty password",
+			src:     key.Sources{PasswordFD: key.NoFD, Env: "\r\n", Raw: true},
+			wantErr: key.ErrPasswordEmpty,
+		},
+		{
+			name:    "password file is dir",
+			src:     key.Sources{PasswordFD: key.NoFD, PasswordFile: dir},
+			wantErr: key.ErrFileNotValid,
+		},
+		{
+			name:    "keyring without keys",
+			src:     key.Sources{PasswordFD: key.NoFD, Keyring: t.TempDir()},
+			wantErr: key.ErrKeyringEmpty,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ks, err := key.NewStorage(tt.src).Keys()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Keys() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got := values(ks); !slices.Equal(got, tt.want) {
+				t.Errorf("Keys() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStorage_Use(t *testing.T) {
+	s := key.NewStorage(key.Sources{Password: []string{keyA, keyB, keyC}, PasswordFD: key.NoFD})
+
+	ks, err := s.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ks[1].Source != "password#2" {
+		t.Errorf("Source = %q, want %q", ks[1].Source, "password#2")
+	}
+
+	s.Use(ks[2])
+
+	ks, err = s.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := values(ks), []string{keyC, keyA, keyB}; !slices.Equal(got, want) {
+		t.Errorf("Keys() after Use() = %q, want %q", got, want)
+	}
+}
+
+func values(ks []key.Key) []string {
+	var vs []string
+	for _, k := range ks {
+		vs = append(vs, k.Value)
+	}
+
+	return vs
+}
+
+func TestStorage_KeysFD(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = w.WriteString(keyA + "\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// descriptor is closed by storage after read
+	fd := int(r.Fd()) //nolint:gosec // file descriptor fit in int
+
+	ks, err := key.NewStorage(key.Sources{PasswordFD: fd, Env: keyB}).Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := values(ks), []string{keyA, keyB}; !slices.Equal(got, want) {
+		t.Errorf("Keys() = %q, want %q", got, want)
+	}
+}
+
+func TestStorage_KeysNotTerminal(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer r.Close()
+	defer w.Close()
+
+	s := key.NewStorage(key.Sources{PasswordFD: key.NoFD}).WithInput(r)
+
+	if s.IsSet() {
+		t.Error("IsSet() = true, want false")
+	}
+
+	_, err = s.Keys()
+	if !errors.Is(err, key.ErrKeyNotSet) {
+		t.Fatalf("Keys() error = %v, want %v", err, key.ErrKeyNotSet)
+	}
+
+	if c := exitcode.Get(err); c != exitcode.WrongKey {
+		t.Errorf("exit code = %d, want %d", c, exitcode.WrongKey)
+	}
+}
+
+func TestStorage_ImplicitStore(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "keys.store")
+	st := &keystore.Store{}
+
+	if _, err := st.Add(&keystore.Entry{Label: "inst", Key: keyA}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := st.Save(p, "master"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(keystore.EnvPassphrase, "master")
+
+	s := key.NewStorage(key.Sources{PasswordFD: key.NoFD, Store: p, StoreImplicit: true})
+
+	// default key store is not key set by user, but its keys are used instead of prompt
+	if s.IsSet() {
+		t.Error("IsSet() = true, want false")
+	}
+
+	ks, err := s.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := values(ks); !slices.Equal(got, []string{keyA}) {
+		t.Errorf("Keys() = %q, want %q", got, []string{keyA})
+	}
+}
+
+func TestStorage_KeysFor(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "keys.store")
+	st := &keystore.Store{Default: "def"}
+
+	es := []*keystore.Entry{{Label: "other", Key: keyA}, {Label: "def", Key: keyB}, {Label: "inst", Key: keyC}}
+	for _, e := range es {
+		if _, err := st.Add(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := st.Save(p, "master"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(keystore.EnvPassphrase, "master")
+
+	s := key.NewStorage(key.Sources{PasswordFD: key.NoFD, Store: p})
+
+	tests := []struct {
+		id   string
+		want []string
+	}{
+		{id: "inst", want: []string{keyC, keyB, keyA}},
+		{id: "unknown", want: []string{keyB, keyA, keyC}},
+	}
+
+	for _, tt := range tests {
+		ks, err := s.KeysFor(tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := values(ks); !slices.Equal(got, tt.want) {
+			t.Errorf("KeysFor(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package keystore
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+)
+
+const (
+	// EnvPassphrase - environment variable with master passphrase of key store.
+	EnvPassphrase = "HA_BACKUP_STORE_PASSPHRASE"
+)
+
+var (
+	ErrPassphraseNotSet   = errors.New("passphrase of key store not set and stdin is not a terminal, use " + EnvPassphrase)
+	ErrPassphraseEmpty    = errors.New("passphrase of key store is empty")
+	ErrPassphraseMismatch = errors.New("passphrases do not match")
+)
+
+// Passphrase - get master passphrase from environment or prompt on terminal without echo.
+// For new store passphrase is asked twice.
+func Passphrase(in *os.File, out io.Writer, confirm bool) (string, error) {
+	if p := os.Getenv(EnvPassphrase); p != "" {
+		return p, nil
+	}
+
+	fd := int(in.Fd()) //nolint:gosec // file descriptor fit in int
+
+	if !term.IsTerminal(fd) {
+		return "", exitcode.Wrap(exitcode.WrongKey, ErrPassphraseNotSet)
+	}
+
+	p, err := readPassphrase(fd, out, "Key store passphrase: ")
+	if err != nil {
+		return "", err
+	}
+
+	if p == "" {
+		return "", exitcode.Wrap(exitcode.BadArgs, ErrPassphraseEmpty)
+	}
+
+	if !confirm {
+		return p, nil
+	}
+
+	c, err := readPassphrase(fd, out, "Repeat passphrase: ")
+	if err != nil {
+		return "", err
+	}
+
+	if c != p {
+		return "", exitcode.Wrap(exitcode.BadArgs, ErrPassphraseMismatch)
+	}
+
+	return p, nil
+}
+
+func readPassphrase(fd int, out io.Writer, prompt string) (string, error) {
+	fmt.Fprint(out, prompt)
+
+	b, err := term.ReadPassword(fd)
+	fmt.Fprintln(out)
+
+	return string(b), err
+}
@@ -0,0 +1,230 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/librun/ha-backup-tool/internal/exitcode"
+)
+
+// File of store: magic, log2 of scrypt N, scrypt salt, XChaCha20-Poly1305 nonce and encrypted json of entries.
+// Header is authenticated as additional data.
+const (
+	magic      = "HABTKEYSTORE1\n"
+	saltLen    = 16
+	scryptLogN = 15
+	scryptR    = 8
+	scryptP    = 1
+	headerLen  = len(magic) + 1 + saltLen + chacha20poly1305.NonceSizeX
+	maxLogN    = 22
+
+	dirName  = "ha-backup-tool"
+	fileName = "keys.store"
+	dirMode  = 0o700
+)
+
+var (
+	ErrStoreNotValid      = errors.New("key store file not valid")
+	ErrPassphraseNotValid = errors.New("wrong passphrase of key store or store is broken")
+	ErrLabelNotFound      = errors.New("key with label not found in key store")
+	ErrLabelEmpty         = errors.New("label of key is empty, set --label or use emergency kit with instance ID")
+)
+
+// Entry - key in store, label is instance ID of Home Assistant.
+type Entry struct {
+	Label string    `json:"label"`
+	Name  string    `json:"name,omitempty"`
+	Key   string    `json:"key"`
+	Added time.Time `json:"added"`
+}
+
+// Store - keys encrypted by master passphrase.
+type Store struct {
+	Default string   `json:"default,omitempty"`
+	Entries []*Entry `json:"keys"`
+}
+
+// DefaultPath - path of key store in user config dir.
+func DefaultPath() (string, error) {
+	d, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(d, dirName, fileName), nil
+}
+
+// Load - read and decrypt store, not exists file is empty store.
+func Load(p, passphrase string) (*Store, error) {
+	b, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Store{Entries: make([]*Entry, 0)}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return decrypt(b, passphrase)
+}
+
+// Save - encrypt and write store, file is readable only by owner.
+func (s *Store) Save(p, passphrase string) error {
+	b, err := encrypt(s, passphrase)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(p), dirMode); err != nil {
+		return err
+	}
+
+	// temp file is created with mode 0600
+	f, err := os.CreateTemp(filepath.Dir(p), filepath.Base(p)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(b)
+
+	if errC := f.Close(); err == nil {
+		err = errC
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), p)
+	}
+
+	if err != nil {
+		return errors.Join(err, os.Remove(f.Name()))
+	}
+
+	return nil
+}
+
+// Get - get entry by label.
+func (s *Store) Get(label string) (*Entry, bool) {
+	i := slices.IndexFunc(s.Entries, func(e *Entry) bool { return e.Label == label })
+	if i < 0 {
+		return nil, false
+	}
+
+	return s.Entries[i], true
+}
+
+// Add - add entry or replace key of entry with same label, return true if entry is replaced.
+func (s *Store) Add(e *Entry) (bool, error) {
+	if e.Label == "" {
+		return false, exitcode.Wrap(exitcode.BadArgs, ErrLabelEmpty)
+	}
+
+	if o, ok := s.Get(e.Label); ok {
+		*o = *e
+
+		return true, nil
+	}
+
+	s.Entries = append(s.Entries, e)
+
+	return false, nil
+}
+
+// Remove - remove entry by label, default is cleared if it is removed.
+func (s *Store) Remove(label string) error {
+	i := slices.IndexFunc(s.Entries, func(e *Entry) bool { return e.Label == label })
+	if i < 0 {
+		return exitcode.Wrap(exitcode.BadArgs, fmt.Errorf("%w: %s", ErrLabelNotFound, label))
+	}
+
+	s.Entries = slices.Delete(s.Entries, i, i+1)
+
+	if s.Default == label {
+		s.Default = ""
+	}
+
+	return nil
+}
+
+// SetDefault - set key which is tried first if no key has label of backup instance.
+func (s *Store) SetDefault(label string) error {
+	if _, ok := s.Get(label); !ok {
+		return exitcode.Wrap(exitcode.BadArgs, fmt.Errorf("%w: %s", ErrLabelNotFound, label))
+	}
+
+	s.Default = label
+
+	return nil
+}
+
+func encrypt(s *Store, passphrase string) ([]byte, error) {
+	pt, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+
+	h := make([]byte, headerLen)
+	copy(h, magic)
+	h[len(magic)] = scryptLogN
+
+	if _, err = rand.Read(h[len(magic)+1:]); err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(h, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, headerLen, headerLen+len(pt)+aead.Overhead())
+	copy(out, h)
+
+	return aead.Seal(out, h[headerLen-chacha20poly1305.NonceSizeX:], pt, h), nil
+}
+
+func decrypt(b []byte, passphrase string) (*Store, error) {
+	if len(b) < headerLen || !bytes.HasPrefix(b, []byte(magic)) || b[len(magic)] > maxLogN {
+		return nil, exitcode.Wrap(exitcode.Corrupt, ErrStoreNotValid)
+	}
+
+	h := b[:headerLen]
+
+	aead, err := newAEAD(h, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	pt, err := aead.Open(nil, h[headerLen-chacha20poly1305.NonceSizeX:], b[headerLen:], h)
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.WrongKey, ErrPassphraseNotValid)
+	}
+
+	var s Store
+	if err = json.Unmarshal(pt, &s); err != nil {
+		return nil, exitcode.Wrap(exitcode.Corrupt, fmt.Errorf("%w: %w", ErrStoreNotValid, err))
+	}
+
+	return &s, nil
+}
+
+// newAEAD - derive key from passphrase by scrypt with parameters from header.
+func newAEAD(h []byte, passphrase string) (cipher.AEAD, error) {
+	salt := h[len(magic)+1 : len(magic)+1+saltLen]
+
+	k, err := scrypt.Key([]byte(passphrase), salt, 1<<h[len(magic)], scryptR, scryptP, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	return chacha20poly1305.NewX(k)
+}
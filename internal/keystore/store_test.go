@@ -0,0 +1,86 @@
+package keystore_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/librun/ha-backup-tool/internal/keystore"
+)
+
+const testKey = "AAAA-AAAA-AAAA-AAAA-AAAA-AAAA-AAAA"
+
+func TestStore_SaveLoad(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "dir", "keys.store")
+
+	st, err := keystore.Load(p, "master")
+	if err != nil {
+		t.Fatalf("Load() of not exists store error = %v", err)
+	}
+
+	if _, err = st.Add(&keystore.Entry{Label: "a", Key: testKey}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = st.Add(&keystore.Entry{Label: "b", Key: testKey}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = st.SetDefault("b"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = st.Save(p, "master"); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Mode().Perm() != 0o600 {
+		t.Errorf("mode of store = %v, want 0600", s.Mode().Perm())
+	}
+
+	if _, err = keystore.Load(p, "wrong"); !errors.Is(err, keystore.ErrPassphraseNotValid) {
+		t.Errorf("Load() with wrong passphrase error = %v, want %v", err, keystore.ErrPassphraseNotValid)
+	}
+
+	st, err = keystore.Load(p, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e, ok := st.Get("a"); !ok || e.Key != testKey || st.Default != "b" || len(st.Entries) != 2 {
+		t.Errorf("Load() = %+v, want 2 keys and default b", st)
+	}
+
+	if err = st.Remove("b"); err != nil {
+		t.Fatal(err)
+	}
+
+	if st.Default != "" {
+		t.Errorf("Default after Remove() = %q, want empty", st.Default)
+	}
+
+	if err = st.Remove("b"); !errors.Is(err, keystore.ErrLabelNotFound) {
+		t.Errorf("Remove() of not exists label error = %v, want %v", err, keystore.ErrLabelNotFound)
+	}
+
+	if _, err = st.Add(&keystore.Entry{Key: testKey}); !errors.Is(err, keystore.ErrLabelEmpty) {
+		t.Errorf("Add() without label error = %v, want %v", err, keystore.ErrLabelEmpty)
+	}
+}
+
+func TestLoad_NotValid(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "keys.store")
+	if err := os.WriteFile(p, []byte("not a store"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := keystore.Load(p, "master"); !errors.Is(err, keystore.ErrStoreNotValid) {
+		t.Errorf("Load() error = %v, want %v", err, keystore.ErrStoreNotValid)
+	}
+}
@@ -0,0 +1,142 @@
+package limits
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	DefaultMaxEntries   = 10_000_000
+	DefaultMaxPathDepth = 256
+	DefaultMaxRatio     = 200
+
+	// minRatioCheckSize - decompression ratio is checked after this size, small archives can have big ratio.
+	minRatioCheckSize = 64 << 20 // 64 MiB
+)
+
+var (
+	ErrFileSizeExceeded  = errors.New("size of decoded data exceeds allowed size")
+	ErrTotalSizeExceeded = errors.New("total size of extracted data exceeds allowed size")
+	ErrEntriesExceeded   = errors.New("count of extracted entries exceeds allowed count")
+	ErrPathDepthExceeded = errors.New("path depth of entry exceeds allowed depth")
+	ErrRatioExceeded     = errors.New("decompression ratio exceeds allowed ratio")
+)
+
+// Limits - safeguards for all extracted data of one backup, shared between all extractors of backup.
+// Zero value of limit means without limit.
+type Limits struct {
+	MaxTotalSize int64
+	MaxEntries   int64
+	MaxPathDepth int
+	MaxRatio     int64
+
+	total   atomic.Int64
+	entries atomic.Int64
+}
+
+// Clone - get limits with same settings and empty counters.
+func (l *Limits) Clone() *Limits {
+	if l == nil {
+		return nil
+	}
+
+	return &Limits{
+		MaxTotalSize: l.MaxTotalSize,
+		MaxEntries:   l.MaxEntries,
+		MaxPathDepth: l.MaxPathDepth,
+		MaxRatio:     l.MaxRatio,
+	}
+}
+
+// CheckEntry - count entry and check its path depth.
+func (l *Limits) CheckEntry(name string) error {
+	if l == nil {
+		return nil
+	}
+
+	if n := l.entries.Add(1); l.MaxEntries > 0 && n > l.MaxEntries {
+		return fmt.Errorf("%w %d", ErrEntriesExceeded, l.MaxEntries)
+	}
+
+	d := len(strings.Split(filepath.ToSlash(filepath.Clean(name)), "/"))
+	if l.MaxPathDepth > 0 && d > l.MaxPathDepth {
+		return fmt.Errorf("%w %d: %s", ErrPathDepthExceeded, l.MaxPathDepth, name)
+	}
+
+	return nil
+}
+
+// Writer - wrap writer for count total size of extracted data.
+func (l *Limits) Writer(w io.Writer) io.Writer {
+	if l == nil {
+		return w
+	}
+
+	return &totalWriter{w: w, l: l}
+}
+
+// RatioReader - wrap reader with decompressed data, compressed is reader with source data of the same archive.
+func (l *Limits) RatioReader(decompressed io.Reader, compressed *CountReader) io.Reader {
+	if l == nil || l.MaxRatio <= 0 {
+		return decompressed
+	}
+
+	return &ratioReader{r: decompressed, c: compressed, max: l.MaxRatio}
+}
+
+type totalWriter struct {
+	w io.Writer
+	l *Limits
+}
+
+func (w *totalWriter) Write(p []byte) (int, error) {
+	if t := w.l.total.Add(int64(len(p))); w.l.MaxTotalSize > 0 && t > w.l.MaxTotalSize {
+		return 0, fmt.Errorf("%w %d", ErrTotalSizeExceeded, w.l.MaxTotalSize)
+	}
+
+	return w.w.Write(p)
+}
+
+// CountReader - reader which count read bytes.
+type CountReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func NewCountReader(r io.Reader) *CountReader {
+	return &CountReader{r: r}
+}
+
+func (r *CountReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n.Add(int64(n))
+
+	return n, err
+}
+
+// Count - get count of read bytes.
+func (r *CountReader) Count() int64 {
+	return r.n.Load()
+}
+
+type ratioReader struct {
+	r   io.Reader
+	c   *CountReader
+	n   int64
+	max int64
+}
+
+func (r *ratioReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+
+	if r.n > minRatioCheckSize && r.n > r.max*r.c.Count() {
+		return n, fmt.Errorf("%w %d", ErrRatioExceeded, r.max)
+	}
+
+	return n, err
+}
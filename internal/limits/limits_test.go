@@ -0,0 +1,62 @@
+package limits_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/librun/ha-backup-tool/internal/limits"
+)
+
+func TestLimits_RatioReader(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(make([]byte, 128<<20)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		ratio   int64
+		wantErr bool
+	}{
+		{name: "ratio exceeds limit", ratio: 100, wantErr: true},
+		{name: "ratio in limit", ratio: 2000},
+		{name: "without limit", ratio: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &limits.Limits{MaxRatio: tt.ratio}
+
+			cr := limits.NewCountReader(bytes.NewReader(buf.Bytes()))
+			zr, err := gzip.NewReader(cr)
+			if err != nil {
+				t.Fatalf("NewReader failed: %v", err)
+			}
+
+			_, err = io.Copy(io.Discard, l.RatioReader(zr, cr))
+			if tt.wantErr != errors.Is(err, limits.ErrRatioExceeded) {
+				t.Errorf("Expected ratio error %t, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLimits_Nil(t *testing.T) {
+	var l *limits.Limits
+
+	if err := l.CheckEntry("a/b/c"); err != nil {
+		t.Errorf("CheckEntry failed: %v", err)
+	}
+
+	if l.Clone() != nil {
+		t.Error("Expected nil clone")
+	}
+}
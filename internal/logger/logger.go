@@ -1,12 +1,92 @@
 package logger
 
 import (
-	"fmt"
+	"errors"
+	"io"
+	"log/slog"
 	"os"
+	"strings"
 )
 
-func Fatalf(format string, a ...any) {
-	fmt.Printf("❌❌❌ Fatal Error ❌❌❌ "+format+"\n", a...)
+const (
+	FormatText = "text"
+	FormatJSON = "json"
 
-	os.Exit(1)
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+
+	logFileMod = 0644
+)
+
+var (
+	ErrFormatUnknown = errors.New("log format not support")
+	ErrLevelUnknown  = errors.New("log level not support")
+)
+
+// New - create logger with level and format, log is written to stderr writer when log file not set.
+// Returned closer must be closed after command, it close log file.
+func New(level, format, file string, stderr io.Writer) (*slog.Logger, io.Closer, error) {
+	l, err := ParseLevel(level)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var w io.WriteCloser = nopCloser{stderr}
+	if file != "" {
+		if w, err = os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, logFileMod); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	h, err := NewHandler(w, l, format)
+	if err != nil {
+		return nil, nil, errors.Join(err, w.Close())
+	}
+
+	return slog.New(h), w, nil
+}
+
+// NewHandler - create slog handler by format.
+func NewHandler(w io.Writer, level slog.Level, format string) (slog.Handler, error) {
+	ho := &slog.HandlerOptions{Level: level}
+
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		return slog.NewTextHandler(w, ho), nil
+	case FormatJSON:
+		return slog.NewJSONHandler(w, ho), nil
+	}
+
+	return nil, ErrFormatUnknown
+}
+
+// ParseLevel - parse level from string, empty string is info.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case LevelDebug:
+		return slog.LevelDebug, nil
+	case "", LevelInfo:
+		return slog.LevelInfo, nil
+	case LevelWarn:
+		return slog.LevelWarn, nil
+	case LevelError:
+		return slog.LevelError, nil
+	}
+
+	return 0, ErrLevelUnknown
+}
+
+// Discard - logger without output, used when logger not set.
+func Discard() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
 }
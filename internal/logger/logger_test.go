@@ -0,0 +1,92 @@
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/librun/ha-backup-tool/internal/logger"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    slog.Level
+		wantErr error
+	}{
+		{in: "", want: slog.LevelInfo},
+		{in: "debug", want: slog.LevelDebug},
+		{in: "WARN", want: slog.LevelWarn},
+		{in: "error", want: slog.LevelError},
+		{in: "trace", wantErr: logger.ErrLevelUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := logger.ParseLevel(tt.in)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Expected level %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewHandler_JSON(t *testing.T) {
+	var buf bytes.Buffer
+
+	h, err := logger.NewHandler(&buf, slog.LevelInfo, logger.FormatJSON)
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+
+	l := slog.New(h)
+	l.Debug("hidden")
+	l.Info("Extract success", "archive", "test.tar.gz")
+
+	var r map[string]any
+	if err = json.Unmarshal(buf.Bytes(), &r); err != nil {
+		t.Fatalf("Expected one json record, got %q: %v", buf.String(), err)
+	}
+
+	if r["msg"] != "Extract success" || r["archive"] != "test.tar.gz" {
+		t.Errorf("Unexpected record %v", r)
+	}
+
+	if _, err = logger.NewHandler(&buf, slog.LevelInfo, "xml"); !errors.Is(err, logger.ErrFormatUnknown) {
+		t.Errorf("Expected error %v, got %v", logger.ErrFormatUnknown, err)
+	}
+}
+
+func TestNew_File(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "ha.log")
+
+	l, c, err := logger.New(logger.LevelWarn, logger.FormatText, p, os.Stderr)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	l.Info("hidden")
+	l.Warn("Partial extract kept")
+
+	if err = c.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	b, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+
+	if strings.Contains(string(b), "hidden") || !strings.Contains(string(b), "Partial extract kept") {
+		t.Errorf("Unexpected log %q", string(b))
+	}
+}
@@ -1,64 +1,227 @@
 package options
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
+	"github.com/hashicorp/go-version"
 	"github.com/urfave/cli/v3"
 
+	"github.com/librun/ha-backup-tool/internal/backup"
+	"github.com/librun/ha-backup-tool/internal/catalog"
+	"github.com/librun/ha-backup-tool/internal/conflict"
 	"github.com/librun/ha-backup-tool/internal/datasize"
 	"github.com/librun/ha-backup-tool/internal/decryptor"
 	"github.com/librun/ha-backup-tool/internal/flags"
 	"github.com/librun/ha-backup-tool/internal/key"
+	"github.com/librun/ha-backup-tool/internal/keystore"
+	"github.com/librun/ha-backup-tool/internal/limits"
+	"github.com/librun/ha-backup-tool/internal/logger"
+	"github.com/librun/ha-backup-tool/internal/output"
+	"github.com/librun/ha-backup-tool/internal/progress"
+	"github.com/librun/ha-backup-tool/internal/retention"
 )
 
 const (
-	maxDecompressionSize int64 = 500 * int64(datasize.GigabyteSize) // 500GB
-	BackupJSON                 = "backup.json"
+	maxDecompressionSize int64 = 500 * int64(datasize.GigabyteSize/datasize.ByteSize) // 500GB
+	maxHistoryFileSize   int64 = 64 << 20                                             // 64MiB
+	maxDiffFileSize      int64 = 1 << 20                                              // 1MiB
+	BackupJSON                 = backup.JSONName
+)
+
+var (
+	ErrJobsNotValid    = errors.New("count of jobs must be 0 or more")
+	ErrDateNotValid    = errors.New("date not valid, use format YYYY-MM-DD or RFC3339")
+	ErrLimitNotValid   = errors.New("limit must be 0 or more")
+	ErrKeepNotValid    = errors.New("count of kept backups must be 0 or more")
+	ErrPolicyEmpty     = errors.New("policy keeps no backups, set --keep-daily, --keep-weekly or --keep-monthly")
+	ErrNewerNoPreserve = errors.New("--on-exists newer compares times of files, which are not restored " +
+		"with --no-preserve")
+	ErrRepackEmpty = errors.New("nothing to remove, set --exclude-addon, --exclude-folder, --exclude-database " +
+		"or --exclude")
 )
 
 type GlobalOptions struct {
 	Key            *key.Storage
-	Verbose        bool
 	MaxArchiveSize int64
+	Limits         *limits.Limits
+	Out            *output.Printer
+	Log            *slog.Logger
+	Progress       *progress.Progress
+	logCloser      io.Closer
 }
 
 type CmdExtractOptions struct {
 	GlobalOptions
-	Include         []*regexp.Regexp
-	Exclude         []*regexp.Regexp
-	Decryptor       *decryptor.Decryptor
-	OutputDir       string
-	ExtractToSubDir bool
-	SkipCreateLinks bool
+	Include          []*regexp.Regexp
+	Exclude          []*regexp.Regexp
+	Decryptor        *decryptor.Decryptor
+	OutputDir        string
+	ExtractToSubDir  bool
+	SkipCreateLinks  bool
+	KeepPartial      bool
+	Resume           bool
+	OnExists         conflict.Policy
+	NoPreserve       bool
+	SameOwner        bool
+	CreateSpecial    bool
+	AllowUnsafeLinks bool
+	SkipSpaceCheck   bool
+	Jobs             int
+	jobs             chan struct{}
+}
+
+type CmdKeygenOptions struct {
+	GlobalOptions
+	Output     string
+	Instance   string
+	InstanceID string
+	URL        string
+	Date       bool
+	Force      bool
+}
+
+type CmdKeysOptions struct {
+	GlobalOptions
+	StorePath string
+}
+
+type CmdCatalogOptions struct {
+	GlobalOptions
+	Path   string
+	Dir    string
+	Rescan bool
+	Filter catalog.Filter
+}
+
+// BackupsOptions - backup files and dirs from arguments, if they are not set backups are taken from catalog.
+type BackupsOptions struct {
+	Files       []string
+	CatalogPath string
+	Filter      catalog.Filter
+}
+
+type CmdFindOptions struct {
+	GlobalOptions
+	BackupsOptions
+	Pattern   *backup.Pattern
+	Decryptor decryptor.Decryptor
+}
+
+type CmdHistoryOptions struct {
+	GlobalOptions
+	BackupsOptions
+	Path        string
+	OutputDir   string
+	NoDiff      bool
+	MaxFileSize int64
+	Decryptor   decryptor.Decryptor
 }
 
 func NewOptionFromGlobalFlags(c *cli.Command) (*GlobalOptions, error) {
 	var op GlobalOptions
 
-	var e = c.String(flags.GlobalEmergency)
-	var p = c.String(flags.GlobalPassword)
+	// output format is global flag of root command
+	f, err := output.ParseFromString(c.Root().String(flags.GlobalFormat))
+	if err != nil {
+		return nil, err
+	}
 
-	op.Key = key.NewStorage(e, p)
+	op.Out = output.NewPrinter(f)
 
-	if c.Bool(flags.GlobalVerbose) {
-		op.Verbose = true
+	if op.MaxArchiveSize, err = parseSize(c.String(flags.GlobalMaxArchiveSize), maxDecompressionSize); err != nil {
+		return nil, err
+	}
+
+	op.Limits = &limits.Limits{
+		MaxEntries:   c.Int64(flags.GlobalMaxEntries),
+		MaxPathDepth: c.Int(flags.GlobalMaxPathDepth),
+		MaxRatio:     c.Int64(flags.GlobalMaxRatio),
 	}
 
-	op.MaxArchiveSize = maxDecompressionSize
-	var msa = c.String(flags.GlobalMaxArchiveSize)
-	if msa != "" {
-		s, err := datasize.ParseDataSize(msa)
-		if err != nil {
-			return nil, err
+	if op.Limits.MaxTotalSize, err = parseSize(c.String(flags.GlobalMaxTotalSize), 0); err != nil {
+		return nil, err
+	}
+
+	pm, err := progress.ParseFromString(c.String(flags.GlobalProgress))
+	if err != nil {
+		return nil, err
+	}
+
+	op.Progress = progress.New(pm, os.Stderr)
+
+	// log file is opened last, so it is not leaked on errors of other flags
+	var ll = c.String(flags.GlobalLogLevel)
+	if c.Bool(flags.GlobalVerbose) && !c.IsSet(flags.GlobalLogLevel) {
+		// verbose is short for debug level
+		ll = logger.LevelDebug
+	}
+
+	// bars are redrawn on stderr, so log lines to stderr are written above bars
+	op.Log, op.logCloser, err = logger.New(ll, c.String(flags.GlobalLogFormat), c.String(flags.GlobalLogFile),
+		op.Progress.LogWriter(os.Stderr))
+	if err != nil {
+		return nil, err
+	}
+
+	op.Progress.WithLogger(op.Log)
+
+	var ks = key.Sources{
+		Password:     c.StringSlice(flags.GlobalPassword),
+		PasswordFD:   key.NoFD,
+		PasswordFile: c.String(flags.GlobalPasswordFile),
+		Emergency:    c.StringSlice(flags.GlobalEmergency),
+		Keyring:      c.String(flags.GlobalKeyring),
+		Env:          os.Getenv(key.EnvKey),
+		Raw:          c.Bool(flags.GlobalPasswordRaw),
+	}
+	if c.IsSet(flags.GlobalPasswordFD) {
+		ks.PasswordFD = c.Int(flags.GlobalPasswordFD)
+	}
+
+	// default key store is used only if no other key is set
+	ks.Store = c.String(flags.GlobalKeyStore)
+	if ks.Store == "" && !ks.IsSet() {
+		if sp, errS := keystore.DefaultPath(); errS == nil && isExists(sp) {
+			ks.Store = sp
+			ks.StoreImplicit = true
 		}
+	}
+
+	op.Key = key.NewStorage(ks).WithOutput(op.Out.Writer()).WithLogger(op.Log)
 
-		op.MaxArchiveSize = s
+	// disk cache has derived keys, so it is used only if enabled
+	if c.Bool(flags.GlobalKeyCache) {
+		d, errD := key.DefaultCacheDir()
+		sp, errS := key.DefaultCacheSecret()
+
+		if err = errors.Join(errD, errS); err != nil {
+			op.Log.Warn("Disk cache of derived keys disabled", "error", err)
+		} else {
+			op.Key.WithCacheDir(d, sp)
+		}
 	}
 
 	return &op, nil
 }
 
+// Close - close log file, must be called after command.
+func (o *GlobalOptions) Close() error {
+	if o.logCloser == nil {
+		return nil
+	}
+
+	return o.logCloser.Close()
+}
+
 func NewCmdExtractOptions(c *cli.Command) (*CmdExtractOptions, error) {
 	opg, err := NewOptionFromGlobalFlags(c)
 	if err != nil {
@@ -67,23 +230,456 @@ func NewCmdExtractOptions(c *cli.Command) (*CmdExtractOptions, error) {
 
 	var op = CmdExtractOptions{GlobalOptions: *opg}
 
+	if err = op.parseExtractFlags(c); err != nil {
+		return nil, errors.Join(err, op.Close())
+	}
+
+	return &op, nil
+}
+
+func (op *CmdExtractOptions) parseExtractFlags(c *cli.Command) error {
+	var err error
+
 	op.OutputDir = c.String(flags.ExtractOutput)
 	op.Include, op.Exclude = parseIncudeExclude(c.String(flags.ExtractInclude), c.String(flags.ExtractExclude))
 	op.SkipCreateLinks = c.Bool(flags.ExtractSkipCreateLinks)
+	op.KeepPartial = c.Bool(flags.ExtractKeepPartial)
+	op.Resume = c.Bool(flags.ExtractResume)
+	op.NoPreserve = c.Bool(flags.ExtractNoPreserve)
+	op.SameOwner = c.Bool(flags.ExtractSameOwner)
+	op.CreateSpecial = c.Bool(flags.ExtractCreateSpecial)
+	op.AllowUnsafeLinks = c.Bool(flags.ExtractAllowUnsafeLinks)
+	op.SkipSpaceCheck = c.Bool(flags.ExtractSkipSpaceCheck)
+
+	op.Jobs = c.Int(flags.ExtractJobs)
+	if op.Jobs < 0 {
+		return ErrJobsNotValid
+	}
+
+	if op.Jobs > 0 {
+		op.jobs = make(chan struct{}, op.Jobs)
+	}
+
+	if op.OnExists, err = conflict.ParseFromString(c.String(flags.ExtractOnExists)); err != nil {
+		return err
+	}
+
+	if op.OnExists == conflict.PolicyNewer && op.NoPreserve {
+		return ErrNewerNoPreserve
+	}
 
 	decr := c.String(flags.ExtractCrypto)
 	if decr != "" {
 		d, errD := decryptor.ParseFromString(decr)
 		if errD != nil {
-			return nil, errD
+			return errD
 		}
 
 		op.Decryptor = &d
 	}
 
+	return nil
+}
+
+// Acquire - wait free job for extract archive, without limit of jobs it returns at once.
+func (op *CmdExtractOptions) Acquire() {
+	if op.jobs != nil {
+		op.jobs <- struct{}{}
+	}
+}
+
+// Release - free job acquired by Acquire.
+func (op *CmdExtractOptions) Release() {
+	if op.jobs != nil {
+		<-op.jobs
+	}
+}
+
+func NewCmdKeygenOptions(c *cli.Command) (*CmdKeygenOptions, error) {
+	opg, err := NewOptionFromGlobalFlags(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CmdKeygenOptions{
+		GlobalOptions: *opg,
+		Output:        c.String(flags.KeygenOutput),
+		Instance:      c.String(flags.KeygenInstance),
+		InstanceID:    c.String(flags.KeygenInstanceID),
+		URL:           c.String(flags.KeygenURL),
+		Date:          c.Bool(flags.KeygenDate),
+		Force:         c.Bool(flags.KeygenForce),
+	}, nil
+}
+
+func NewCmdKeysOptions(c *cli.Command) (*CmdKeysOptions, error) {
+	opg, err := NewOptionFromGlobalFlags(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var op = CmdKeysOptions{GlobalOptions: *opg, StorePath: c.String(flags.GlobalKeyStore)}
+
+	if op.StorePath == "" {
+		if op.StorePath, err = keystore.DefaultPath(); err != nil {
+			return nil, errors.Join(err, op.Close())
+		}
+	}
+
+	return &op, nil
+}
+
+func NewCmdCatalogOptions(c *cli.Command) (*CmdCatalogOptions, error) {
+	opg, err := NewOptionFromGlobalFlags(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var op = CmdCatalogOptions{GlobalOptions: *opg}
+
+	if err = op.parseCatalogFlags(c); err != nil {
+		return nil, errors.Join(err, op.Close())
+	}
+
+	return &op, nil
+}
+
+func (op *CmdCatalogOptions) parseCatalogFlags(c *cli.Command) error {
+	var err error
+
+	if op.Path, err = CatalogPath(c); err != nil {
+		return err
+	}
+
+	if d := c.StringArg("dir"); d != "" {
+		if op.Dir, err = filepath.Abs(d); err != nil {
+			return err
+		}
+	}
+
+	op.Rescan = c.Bool(flags.CatalogRescan)
+
+	if op.Filter, err = parseCatalogFilter(c); err != nil {
+		return err
+	}
+
+	op.Filter.Dir = op.Dir
+
+	return nil
+}
+
+func NewCmdFindOptions(c *cli.Command) (*CmdFindOptions, error) {
+	opg, err := NewOptionFromGlobalFlags(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var op = CmdFindOptions{GlobalOptions: *opg}
+
+	if err = op.parseFindFlags(c); err != nil {
+		return nil, errors.Join(err, op.Close())
+	}
+
+	return &op, nil
+}
+
+func (op *CmdFindOptions) parseFindFlags(c *cli.Command) error {
+	var err error
+
+	if op.Pattern, err = backup.NewPattern(c.StringArg("pattern")); err != nil {
+		return err
+	}
+
+	if op.Decryptor, err = decryptor.ParseFromString(c.String(flags.FindCrypto)); err != nil {
+		return err
+	}
+
+	return op.parseBackupsFlags(c)
+}
+
+// CmdDiffOptions - options of compare of two backups, text diff is made only for config files.
+type CmdDiffOptions struct {
+	GlobalOptions
+	Old          string
+	New          string
+	Text         bool
+	MetadataOnly bool
+	MaxFileSize  int64
+	Decryptor    decryptor.Decryptor
+}
+
+// CmdPruneOptions - options of remove backups not kept by policy.
+type CmdPruneOptions struct {
+	GlobalOptions
+	Dirs      []string
+	Policy    retention.Policy
+	DryRun    bool
+	Verify    bool
+	Decryptor decryptor.Decryptor
+}
+
+// CmdRepackOptions - options of copy of backup without addons, folders and files.
+type CmdRepackOptions struct {
+	GlobalOptions
+	Backup    string
+	Output    string
+	Force     bool
+	Repack    backup.Repack
+	Decryptor decryptor.Decryptor
+}
+
+func NewCmdHistoryOptions(c *cli.Command) (*CmdHistoryOptions, error) {
+	opg, err := NewOptionFromGlobalFlags(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var op = CmdHistoryOptions{GlobalOptions: *opg}
+
+	if err = op.parseHistoryFlags(c); err != nil {
+		return nil, errors.Join(err, op.Close())
+	}
+
 	return &op, nil
 }
 
+func (op *CmdHistoryOptions) parseHistoryFlags(c *cli.Command) error {
+	var err error
+
+	// path in backup is always with slash, leading slash or dot are not part of path
+	op.Path = strings.TrimLeft(path.Clean("/"+filepath.ToSlash(c.StringArg("path"))), "/")
+	op.OutputDir = c.String(flags.HistoryOutputDir)
+	op.NoDiff = c.Bool(flags.HistoryNoDiff)
+
+	if op.MaxFileSize, err = parseSize(c.String(flags.HistoryMaxFileSize), maxHistoryFileSize); err != nil {
+		return err
+	}
+
+	if op.Decryptor, err = decryptor.ParseFromString(c.String(flags.HistoryCrypto)); err != nil {
+		return err
+	}
+
+	return op.parseBackupsFlags(c)
+}
+
+func NewCmdDiffOptions(c *cli.Command) (*CmdDiffOptions, error) {
+	opg, err := NewOptionFromGlobalFlags(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var op = CmdDiffOptions{GlobalOptions: *opg}
+
+	if err = op.parseDiffFlags(c); err != nil {
+		return nil, errors.Join(err, op.Close())
+	}
+
+	return &op, nil
+}
+
+func (op *CmdDiffOptions) parseDiffFlags(c *cli.Command) error {
+	var err error
+
+	op.Old = c.StringArg("old")
+	op.New = c.StringArg("new")
+	op.Text = c.Bool(flags.DiffText)
+	op.MetadataOnly = c.Bool(flags.DiffMetadataOnly)
+
+	if op.MaxFileSize, err = parseSize(c.String(flags.DiffMaxFileSize), maxDiffFileSize); err != nil {
+		return err
+	}
+
+	op.Decryptor, err = decryptor.ParseFromString(c.String(flags.DiffCrypto))
+
+	return err
+}
+
+func NewCmdPruneOptions(c *cli.Command) (*CmdPruneOptions, error) {
+	opg, err := NewOptionFromGlobalFlags(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var op = CmdPruneOptions{GlobalOptions: *opg}
+
+	if err = op.parsePruneFlags(c); err != nil {
+		return nil, errors.Join(err, op.Close())
+	}
+
+	return &op, nil
+}
+
+func (op *CmdPruneOptions) parsePruneFlags(c *cli.Command) error {
+	var err error
+
+	op.Dirs = c.StringArgs("dirs")
+	op.DryRun = c.Bool(flags.PruneDryRun)
+	op.Verify = c.Bool(flags.PruneVerify)
+	op.Policy = retention.Policy{
+		Daily:   c.Int(flags.PruneKeepDaily),
+		Weekly:  c.Int(flags.PruneKeepWeekly),
+		Monthly: c.Int(flags.PruneKeepMonthly),
+	}
+
+	if op.Policy.Daily < 0 || op.Policy.Weekly < 0 || op.Policy.Monthly < 0 {
+		return ErrKeepNotValid
+	}
+
+	if op.Policy.IsEmpty() {
+		return ErrPolicyEmpty
+	}
+
+	op.Decryptor, err = decryptor.ParseFromString(c.String(flags.PruneCrypto))
+
+	return err
+}
+
+func NewCmdRepackOptions(c *cli.Command) (*CmdRepackOptions, error) {
+	opg, err := NewOptionFromGlobalFlags(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var op = CmdRepackOptions{GlobalOptions: *opg}
+
+	if err = op.parseRepackFlags(c); err != nil {
+		return nil, errors.Join(err, op.Close())
+	}
+
+	return &op, nil
+}
+
+func (op *CmdRepackOptions) parseRepackFlags(c *cli.Command) error {
+	var err error
+
+	op.Backup = c.StringArg("backup")
+	op.Output = c.StringArg("output")
+	op.Force = c.Bool(flags.RepackForce)
+	op.Repack = backup.Repack{
+		Addons:   c.StringSlice(flags.RepackExcludeAddon),
+		Folders:  c.StringSlice(flags.RepackExcludeFolder),
+		Database: c.Bool(flags.RepackExcludeDatabase),
+		// temp files are near output, so rename of output is not move between disks
+		TempDir: filepath.Dir(op.Output),
+	}
+
+	for _, s := range c.StringSlice(flags.RepackExclude) {
+		p, errP := backup.NewPattern(s)
+		if errP != nil {
+			return errP
+		}
+
+		op.Repack.Files = append(op.Repack.Files, p)
+	}
+
+	if len(op.Repack.Addons)+len(op.Repack.Folders)+len(op.Repack.Files) == 0 && !op.Repack.Database {
+		return ErrRepackEmpty
+	}
+
+	op.Decryptor, err = decryptor.ParseFromString(c.String(flags.RepackCrypto))
+
+	return err
+}
+
+// parseSize - parse size in bytes, empty value is default size.
+func parseSize(s string, def int64) (int64, error) {
+	if s == "" {
+		return def, nil
+	}
+
+	ds, err := datasize.ParseDataSize(s)
+	if err != nil {
+		return 0, err
+	}
+
+	// data size is parsed in bits
+	return ds / int64(datasize.ByteSize), nil
+}
+
+// parseBackupsFlags - parse backups from arguments and filter of catalog.
+func (op *BackupsOptions) parseBackupsFlags(c *cli.Command) error {
+	var err error
+
+	op.Files = c.StringArgs("backups")
+
+	if op.CatalogPath, err = CatalogPath(c); err != nil {
+		return err
+	}
+
+	op.Filter, err = parseCatalogFilter(c)
+
+	return err
+}
+
+// parseCatalogFilter - parse flags of filter of backups in catalog.
+func parseCatalogFilter(c *cli.Command) (catalog.Filter, error) {
+	var err error
+
+	f := catalog.Filter{
+		Instance: c.String(flags.CatalogInstance),
+		Addon:    c.String(flags.CatalogAddon),
+		Type:     c.String(flags.CatalogType),
+		Limit:    c.Int(flags.CatalogLimit),
+	}
+
+	if f.Limit < 0 {
+		return f, ErrLimitNotValid
+	}
+
+	if f.From, err = parseDate(c.String(flags.CatalogFrom), false); err != nil {
+		return f, err
+	}
+
+	if f.To, err = parseDate(c.String(flags.CatalogTo), true); err != nil {
+		return f, err
+	}
+
+	if v := c.String(flags.CatalogBeforeVersion); v != "" {
+		if f.BeforeVersion, err = version.NewVersion(v); err != nil {
+			return f, err
+		}
+	}
+
+	return f, nil
+}
+
+// CatalogPath - path of catalog from flag or default path.
+func CatalogPath(c *cli.Command) (string, error) {
+	if p := c.String(flags.GlobalCatalog); p != "" {
+		return p, nil
+	}
+
+	return catalog.DefaultPath()
+}
+
+// parseDate - parse date or time, end of range is included in range, so date of end is last moment of day.
+func parseDate(s string, end bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation(time.DateOnly, s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrDateNotValid, s)
+	}
+
+	if end {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	return t, nil
+}
+
+func isExists(p string) bool {
+	_, err := os.Stat(p)
+
+	return err == nil
+}
+
 func parseIncudeExclude(include, exclude string) ([]*regexp.Regexp, []*regexp.Regexp) {
 	var ic []*regexp.Regexp
 	var ec []*regexp.Regexp
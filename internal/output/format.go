@@ -0,0 +1,46 @@
+package output
+
+import (
+	"errors"
+	"strings"
+)
+
+// Format - format of command output.
+type Format int
+
+const (
+	FormatText Format = iota
+	FormatJSON
+)
+
+const (
+	FormatTextString    = "text"
+	FormatJSONString    = "json"
+	FormatUnknownString = "unknown"
+)
+
+var (
+	ErrFormatUnknown = errors.New("output format not support")
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatText:
+		return FormatTextString
+	case FormatJSON:
+		return FormatJSONString
+	default:
+		return FormatUnknownString
+	}
+}
+
+func ParseFromString(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "", FormatTextString:
+		return FormatText, nil
+	case FormatJSONString:
+		return FormatJSON, nil
+	}
+
+	return 0, ErrFormatUnknown
+}
@@ -0,0 +1,61 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Printer - write messages for human and report for machine.
+// In json mode stdout has only report, messages are written to stderr.
+type Printer struct {
+	f   Format
+	msg io.Writer
+	out io.Writer
+}
+
+func NewPrinter(f Format) *Printer {
+	p := &Printer{f: f, msg: os.Stdout, out: os.Stdout}
+	if f == FormatJSON {
+		p.msg = os.Stderr
+	}
+
+	return p
+}
+
+// NewPrinterWithWriters - create printer with custom writers for messages and report.
+func NewPrinterWithWriters(f Format, msg, out io.Writer) *Printer {
+	return &Printer{f: f, msg: msg, out: out}
+}
+
+// IsJSON - check that report must be written in json.
+func (p *Printer) IsJSON() bool {
+	return p != nil && p.f == FormatJSON
+}
+
+// Writer - get writer for messages, nil printer write to stdout.
+func (p *Printer) Writer() io.Writer {
+	if p == nil {
+		return os.Stdout
+	}
+
+	return p.msg
+}
+
+func (p *Printer) Printf(format string, a ...any) {
+	_, _ = fmt.Fprintf(p.Writer(), format, a...)
+}
+
+func (p *Printer) Println(a ...any) {
+	_, _ = fmt.Fprintln(p.Writer(), a...)
+}
+
+// Report - write report or other result of command in json mode, in text mode report is not written.
+func (p *Printer) Report(v any) error {
+	if !p.IsJSON() {
+		return nil
+	}
+
+	return json.NewEncoder(p.out).Encode(v)
+}
@@ -0,0 +1,80 @@
+package output_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/librun/ha-backup-tool/internal/output"
+)
+
+func TestPrinter_Report(t *testing.T) {
+	ok := output.NewBackupReport("ok.tar")
+	ok.Finish(nil, "")
+
+	failed := output.NewBackupReport("failed.tar")
+	failed.Finish(errors.New("broken"), "corrupt") //nolint:err113 // Test error
+
+	tests := []struct {
+		name       string
+		format     output.Format
+		backups    []*output.BackupReport
+		wantStatus string
+		wantOut    bool
+	}{
+		{name: "text not write report", format: output.FormatText, backups: []*output.BackupReport{ok}},
+		{
+			name: "json success", format: output.FormatJSON,
+			backups: []*output.BackupReport{ok}, wantStatus: output.StatusSuccess, wantOut: true,
+		},
+		{
+			name: "json partial", format: output.FormatJSON,
+			backups: []*output.BackupReport{ok, failed}, wantStatus: output.StatusPartial, wantOut: true,
+		},
+		{
+			name: "json failed", format: output.FormatJSON,
+			backups: []*output.BackupReport{failed}, wantStatus: output.StatusFailed, wantOut: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var msg, out bytes.Buffer
+			p := output.NewPrinterWithWriters(tt.format, &msg, &out)
+
+			p.Printf("📦 message\n")
+
+			r := output.NewReport("extract")
+			r.Backups = tt.backups
+			r.Finish(time.Now())
+
+			if err := p.Report(r); err != nil {
+				t.Fatalf("Report failed: %v", err)
+			}
+
+			if msg.String() != "📦 message\n" {
+				t.Errorf("Expected message in message writer, got %q", msg.String())
+			}
+
+			if !tt.wantOut {
+				if out.Len() > 0 {
+					t.Errorf("Expected empty output, got %q", out.String())
+				}
+
+				return
+			}
+
+			var got output.Report
+			if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+				t.Fatalf("Unmarshal report failed: %v", err)
+			}
+
+			if got.Status != tt.wantStatus || got.Total != len(tt.backups) {
+				t.Errorf("Expected status %s total %d, got %s total %d",
+					tt.wantStatus, len(tt.backups), got.Status, got.Total)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package output
+
+import (
+	"time"
+)
+
+const (
+	StatusSuccess = "success"
+	StatusPartial = "partial"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// Report - final report of command.
+type Report struct {
+	Command    string          `json:"command"`
+	Status     string          `json:"status"`
+	Total      int             `json:"total"`
+	Success    int             `json:"success"`
+	ExitCode   int             `json:"exit_code"`
+	DurationMs int64           `json:"duration_ms"`
+	Backups    []*BackupReport `json:"backups"`
+}
+
+// BackupReport - report of processing one backup file.
+type BackupReport struct {
+	File       string           `json:"file"`
+	OutputDir  string           `json:"output_dir,omitempty"`
+	Status     string           `json:"status"`
+	Size       int64            `json:"size"`
+	Bytes      int64            `json:"bytes"`
+	DurationMs int64            `json:"duration_ms"`
+	Skipped    []string         `json:"skipped"`
+	Keys       []string         `json:"keys,omitempty"`
+	Archives   []*ArchiveReport `json:"archives"`
+	Merge      *MergeReport     `json:"merge,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	ErrorCode  string           `json:"error_code,omitempty"`
+
+	start time.Time
+}
+
+// ArchiveReport - report of extract one archive inside backup.
+type ArchiveReport struct {
+	Name       string   `json:"name"`
+	Status     string   `json:"status"`
+	Encrypted  bool     `json:"encrypted"`
+	Key        string   `json:"key,omitempty"`
+	Bytes      int64    `json:"bytes"`
+	DurationMs int64    `json:"duration_ms"`
+	Skipped    []string `json:"skipped"`
+	Error      string   `json:"error,omitempty"`
+	ErrorCode  string   `json:"error_code,omitempty"`
+
+	start time.Time
+}
+
+// MergeReport - report of merge backup into existing dir.
+type MergeReport struct {
+	Added    int      `json:"added"`
+	Replaced []string `json:"replaced"`
+	Kept     int      `json:"kept"`
+}
+
+func NewReport(command string) *Report {
+	return &Report{Command: command, Backups: make([]*BackupReport, 0)}
+}
+
+// Finish - calculate status and summary of report by backups.
+func (r *Report) Finish(start time.Time) {
+	r.Total = len(r.Backups)
+	r.Success = 0
+
+	for _, b := range r.Backups {
+		if b.Status == StatusSuccess {
+			r.Success++
+		}
+	}
+
+	switch {
+	case r.Total > 0 && r.Success == r.Total:
+		r.Status = StatusSuccess
+	case r.Success > 0:
+		r.Status = StatusPartial
+	default:
+		r.Status = StatusFailed
+	}
+
+	r.DurationMs = time.Since(start).Milliseconds()
+}
+
+func NewBackupReport(file string) *BackupReport {
+	return &BackupReport{
+		File:     file,
+		Skipped:  make([]string, 0),
+		Archives: make([]*ArchiveReport, 0),
+		start:    time.Now(),
+	}
+}
+
+// Finish - set status of backup by error.
+func (r *BackupReport) Finish(err error, code string) {
+	r.Status, r.Error, r.ErrorCode = finishStatus(err, code)
+	r.DurationMs = time.Since(r.start).Milliseconds()
+}
+
+func NewArchiveReport(name string, encrypted bool) *ArchiveReport {
+	return &ArchiveReport{Name: name, Encrypted: encrypted, Skipped: make([]string, 0), start: time.Now()}
+}
+
+// Finish - set status of archive by error.
+func (r *ArchiveReport) Finish(err error, code string) {
+	r.Status, r.Error, r.ErrorCode = finishStatus(err, code)
+	r.DurationMs = time.Since(r.start).Milliseconds()
+}
+
+func finishStatus(err error, code string) (string, string, string) {
+	if err != nil {
+		return StatusFailed, err.Error(), code
+	}
+
+	return StatusSuccess, "", ""
+}
@@ -0,0 +1,114 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Bar - progress of one archive.
+type Bar struct {
+	name  string
+	start time.Time
+	total atomic.Int64
+	n     atomic.Int64
+	end   atomic.Int64
+}
+
+type stat struct {
+	done    int64
+	total   int64
+	percent float64
+	speed   float64
+	eta     time.Duration
+}
+
+// SetTotal - set size of archive.
+func (b *Bar) SetTotal(n int64) {
+	if b != nil {
+		b.total.Store(n)
+	}
+}
+
+// Add - add count of processed bytes.
+func (b *Bar) Add(n int64) {
+	if b != nil {
+		b.n.Add(n)
+	}
+}
+
+// Done - mark archive as finished.
+func (b *Bar) Done() {
+	if b != nil {
+		b.end.CompareAndSwap(0, time.Now().UnixNano())
+	}
+}
+
+func (b *Bar) isDone() bool {
+	return b.end.Load() != 0
+}
+
+// Reader - wrap reader for count processed bytes.
+func (b *Bar) Reader(r io.Reader) io.Reader {
+	if b == nil {
+		return r
+	}
+
+	return &reader{r: r, b: b}
+}
+
+func (b *Bar) stat() stat {
+	s := stat{done: b.n.Load(), total: b.total.Load()}
+
+	end := time.Now()
+	if e := b.end.Load(); e != 0 {
+		end = time.Unix(0, e)
+	}
+
+	if e := end.Sub(b.start).Seconds(); e > 0 {
+		s.speed = float64(s.done) / e
+	}
+
+	if s.total > 0 {
+		s.percent = min(float64(s.done)/float64(s.total)*100, 100) //nolint:mnd // percent
+	}
+
+	if s.speed > 0 && s.total > s.done {
+		s.eta = time.Duration(float64(s.total-s.done)/s.speed) * time.Second
+	}
+
+	return s
+}
+
+func (b *Bar) line() string {
+	s := b.stat()
+	f := int(s.percent / 100 * barWidth) //nolint:mnd // percent
+
+	var sb strings.Builder
+	sb.WriteString("[" + strings.Repeat("=", f) + strings.Repeat(" ", barWidth-f) + "] ")
+
+	fmt.Fprintf(&sb, "%5.1f%% %s %s/%s %s/s", s.percent, b.name,
+		FormatBytes(s.done), FormatBytes(s.total), FormatBytes(int64(s.speed)))
+
+	if e := b.end.Load(); e != 0 {
+		sb.WriteString(" done in " + time.Unix(0, e).Sub(b.start).Round(time.Second).String())
+	} else if s.eta > 0 {
+		sb.WriteString(" ETA " + s.eta.Round(time.Second).String())
+	}
+
+	return sb.String()
+}
+
+type reader struct {
+	r io.Reader
+	b *Bar
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.b.Add(int64(n))
+
+	return n, err
+}
@@ -0,0 +1,283 @@
+package progress
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Mode - how progress is shown.
+type Mode int
+
+const (
+	ModeAuto Mode = iota
+	ModeBar
+	ModeLine
+	ModeNone
+)
+
+const (
+	ModeAutoString    = "auto"
+	ModeBarString     = "bar"
+	ModeLineString    = "line"
+	ModeNoneString    = "none"
+	ModeUnknownString = "unknown"
+)
+
+const (
+	barRefresh   = 200 * time.Millisecond
+	LineInterval = 10 * time.Second
+	barWidth     = 30
+)
+
+var (
+	ErrModeUnknown = errors.New("progress mode not support")
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeAuto:
+		return ModeAutoString
+	case ModeBar:
+		return ModeBarString
+	case ModeLine:
+		return ModeLineString
+	case ModeNone:
+		return ModeNoneString
+	default:
+		return ModeUnknownString
+	}
+}
+
+func ParseFromString(s string) (Mode, error) {
+	switch strings.ToLower(s) {
+	case "", ModeAutoString:
+		return ModeAuto, nil
+	case ModeBarString:
+		return ModeBar, nil
+	case ModeLineString:
+		return ModeLine, nil
+	case ModeNoneString:
+		return ModeNone, nil
+	}
+
+	return 0, ErrModeUnknown
+}
+
+// Progress - show progress of all archives in work: bars on terminal or periodic log lines.
+type Progress struct {
+	mode     Mode
+	w        io.Writer
+	log      *slog.Logger
+	interval time.Duration
+
+	mu    sync.Mutex
+	bars  []*Bar
+	lines int
+	stop  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// New - create progress, auto mode selects bars if w is terminal and log lines otherwise.
+// Logger for log lines is set by WithLogger.
+func New(m Mode, w *os.File) *Progress {
+	if m == ModeAuto {
+		m = ModeLine
+		if term.IsTerminal(int(w.Fd())) { //nolint:gosec // file descriptor fits in int
+			m = ModeBar
+		}
+	}
+
+	return NewWithWriter(m, w, slog.New(slog.DiscardHandler), LineInterval)
+}
+
+// NewWithWriter - create progress with custom writer for bars and interval of log lines.
+func NewWithWriter(m Mode, w io.Writer, l *slog.Logger, interval time.Duration) *Progress {
+	if m == ModeNone {
+		return nil
+	}
+
+	return &Progress{mode: m, w: w, log: l, interval: interval}
+}
+
+// WithLogger - set logger for progress lines.
+func (p *Progress) WithLogger(l *slog.Logger) *Progress {
+	if p != nil {
+		p.log = l
+	}
+
+	return p
+}
+
+// LogWriter - writer for log on same output as progress, with bars log lines are written above bars.
+func (p *Progress) LogWriter(w io.Writer) io.Writer {
+	if p == nil || p.mode != ModeBar || p.w != w {
+		return w
+	}
+
+	return p
+}
+
+// Write - clear active bars and write log lines in their place, bars are drawn again below on next refresh.
+func (p *Progress) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.lines > 0 {
+		if _, err := fmt.Fprintf(p.w, "\x1b[%dA\x1b[J", p.lines); err != nil {
+			return 0, err
+		}
+
+		p.lines = 0
+	}
+
+	return p.w.Write(b)
+}
+
+// Start - start render progress in background.
+func (p *Progress) Start() {
+	if p == nil {
+		return
+	}
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+
+	go func() {
+		defer p.wg.Done()
+
+		d := p.interval
+		if p.mode == ModeBar {
+			d = barRefresh
+		}
+
+		t := time.NewTicker(d)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-p.stop:
+				p.render()
+
+				return
+			case <-t.C:
+				p.render()
+			}
+		}
+	}()
+}
+
+// Stop - stop render progress, last state is rendered.
+func (p *Progress) Stop() {
+	if p == nil || p.stop == nil {
+		return
+	}
+
+	close(p.stop)
+	p.wg.Wait()
+	p.stop = nil
+
+	// last state of bars is kept above next log lines
+	p.mu.Lock()
+	p.lines = 0
+	p.mu.Unlock()
+}
+
+// NewBar - add bar for archive, total can be set later.
+func (p *Progress) NewBar(name string, total int64) *Bar {
+	if p == nil {
+		return nil
+	}
+
+	b := &Bar{name: name, start: time.Now()}
+	b.total.Store(total)
+
+	p.mu.Lock()
+	p.bars = append(p.bars, b)
+	p.mu.Unlock()
+
+	return b
+}
+
+func (p *Progress) render() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.mode == ModeBar {
+		p.renderBars()
+	} else {
+		p.renderLines()
+	}
+
+	// finished bars are rendered last time and removed
+	active := p.bars[:0]
+	for _, b := range p.bars {
+		if !b.isDone() {
+			active = append(active, b)
+		}
+	}
+
+	p.bars = active
+}
+
+// renderBars - redraw bars in place, finished bars are printed last time above active bars.
+func (p *Progress) renderBars() {
+	var sb strings.Builder
+
+	if p.lines > 0 {
+		fmt.Fprintf(&sb, "\x1b[%dA", p.lines)
+	}
+
+	p.lines = 0
+	for _, d := range []bool{true, false} {
+		for _, b := range p.bars {
+			if b.isDone() != d {
+				continue
+			}
+
+			sb.WriteString("\x1b[2K" + b.line() + "\n")
+
+			if !d {
+				p.lines++
+			}
+		}
+	}
+
+	_, _ = io.WriteString(p.w, sb.String())
+}
+
+func (p *Progress) renderLines() {
+	for _, b := range p.bars {
+		if b.isDone() {
+			continue
+		}
+
+		s := b.stat()
+		p.log.Info("Progress", "archive", b.name, "done", FormatBytes(s.done), "total", FormatBytes(s.total),
+			"percent", fmt.Sprintf("%.1f", s.percent), "speed", FormatBytes(int64(s.speed))+"/s", "eta", s.eta)
+	}
+}
+
+// FormatBytes - format size in binary units.
+func FormatBytes(n int64) string {
+	const unit = 1024
+
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
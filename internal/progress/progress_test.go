@@ -0,0 +1,145 @@
+package progress_test
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/librun/ha-backup-tool/internal/progress"
+)
+
+func TestProgress_Bar(t *testing.T) {
+	var buf syncBuffer
+
+	p := progress.NewWithWriter(progress.ModeBar, &buf, slog.New(slog.DiscardHandler), time.Second)
+	p.Start()
+
+	b := p.NewBar("backup.tar/homeassistant.tar.gz", 0)
+	b.SetTotal(2048)
+
+	if _, err := io.Copy(io.Discard, b.Reader(bytes.NewReader(make([]byte, 2048)))); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+
+	b.Done()
+	p.Stop()
+
+	out := buf.String()
+	if !strings.Contains(out, "100.0% backup.tar/homeassistant.tar.gz 2.0 KiB/2.0 KiB") {
+		t.Errorf("Expected finished bar, got %q", out)
+	}
+
+	if !strings.Contains(out, "done in") {
+		t.Errorf("Expected done bar, got %q", out)
+	}
+}
+
+func TestProgress_LogWriter(t *testing.T) {
+	var buf syncBuffer
+
+	p := progress.NewWithWriter(progress.ModeBar, &buf, slog.New(slog.DiscardHandler), time.Second)
+	p.Start()
+
+	b := p.NewBar("backup.tar", 4096)
+	b.Add(1024)
+
+	// wait first render of bar
+	for !strings.Contains(buf.String(), "backup.tar") {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	l := slog.New(slog.NewTextHandler(p.LogWriter(&buf), nil))
+	l.Info("Extract success")
+
+	b.Done()
+	p.Stop()
+
+	// bar is cleared before log line and drawn again below it
+	out := buf.String()
+	i := strings.Index(out, "\x1b[1A\x1b[Jtime=")
+	if i < 0 || !strings.Contains(out[i:], "msg=\"Extract success\"") || !strings.Contains(out[i:], "done in") {
+		t.Errorf("Expected log line above bar, got %q", out)
+	}
+
+	if w := p.LogWriter(io.Discard); w != io.Discard {
+		t.Error("Expected log on other writer is not written through progress")
+	}
+}
+
+func TestProgress_Line(t *testing.T) {
+	var buf syncBuffer
+
+	l := slog.New(slog.NewTextHandler(&buf, nil))
+	p := progress.NewWithWriter(progress.ModeLine, io.Discard, l, 10*time.Millisecond)
+	p.Start()
+
+	b := p.NewBar("backup.tar", 4096)
+	b.Add(1024)
+
+	time.Sleep(50 * time.Millisecond)
+	p.Stop()
+
+	out := buf.String()
+	if !strings.Contains(out, "msg=Progress archive=backup.tar") || !strings.Contains(out, "percent=25.0") {
+		t.Errorf("Expected progress line, got %q", out)
+	}
+}
+
+func TestProgress_None(t *testing.T) {
+	p := progress.NewWithWriter(progress.ModeNone, io.Discard, nil, time.Second)
+	if p != nil {
+		t.Fatal("Expected nil progress for mode none")
+	}
+
+	// nil progress and bar must be safe for use
+	p.Start()
+	b := p.NewBar("backup.tar", 1)
+	b.Add(1)
+	b.Done()
+	p.Stop()
+
+	r := strings.NewReader("data")
+	if b.Reader(r) != r {
+		t.Error("Expected original reader for nil bar")
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1536:            "1.5 KiB",
+		50 << 30:        "50.0 GiB",
+		3 << 40:         "3.0 TiB",
+		5<<20 + 100<<10: "5.1 MiB",
+	}
+
+	for in, want := range tests {
+		if got := progress.FormatBytes(in); got != want {
+			t.Errorf("FormatBytes(%d) expected %q, got %q", in, want, got)
+		}
+	}
+}
+
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.b.String()
+}
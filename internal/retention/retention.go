@@ -0,0 +1,113 @@
+package retention
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	ReasonDaily   = "daily"
+	ReasonWeekly  = "weekly"
+	ReasonMonthly = "monthly"
+)
+
+// Policy - grandfather-father-son policy, count of kept backups for every period, 0 is period not used.
+type Policy struct {
+	Daily   int
+	Weekly  int
+	Monthly int
+}
+
+// Backup - backup for policy, date is from backup.json.
+type Backup struct {
+	Path     string
+	Instance string
+	Date     time.Time
+}
+
+// Decision - backup with reasons for keep, backup without reasons is removed.
+type Decision struct {
+	Backup
+	Reasons []string
+}
+
+// period - period of policy, backup is kept for period if it is newest backup in period.
+type period struct {
+	reason string
+	count  int
+	key    func(t time.Time) string
+}
+
+// IsEmpty - policy keeps no backups.
+func (p Policy) IsEmpty() bool {
+	return p.Daily <= 0 && p.Weekly <= 0 && p.Monthly <= 0
+}
+
+// Apply - decide which backups are kept, policy is applied for every instance separately.
+// Periods are calculated in location of date of backup. Decisions are sorted by instance, newest backup is first.
+func (p Policy) Apply(bs []Backup) []*Decision {
+	ds := make([]*Decision, 0, len(bs))
+	for _, b := range bs {
+		ds = append(ds, &Decision{Backup: b, Reasons: make([]string, 0)})
+	}
+
+	slices.SortStableFunc(ds, func(a, b *Decision) int {
+		return cmp.Or(cmp.Compare(a.Instance, b.Instance), b.Date.Compare(a.Date))
+	})
+
+	ps := []period{
+		{reason: ReasonDaily, count: p.Daily, key: func(t time.Time) string { return t.Format(time.DateOnly) }},
+		{reason: ReasonWeekly, count: p.Weekly, key: func(t time.Time) string {
+			y, w := t.ISOWeek()
+
+			return fmt.Sprintf("%d-W%02d", y, w)
+		}},
+		{reason: ReasonMonthly, count: p.Monthly, key: func(t time.Time) string { return t.Format("2006-01") }},
+	}
+
+	for start := 0; start < len(ds); {
+		end := start + 1
+		for end < len(ds) && ds[end].Instance == ds[start].Instance {
+			end++
+		}
+
+		for _, pr := range ps {
+			pr.apply(ds[start:end])
+		}
+
+		start = end
+	}
+
+	return ds
+}
+
+// apply - keep newest backup of every period until count of periods, decisions are sorted by date newest first.
+func (pr period) apply(ds []*Decision) {
+	var (
+		last string
+		n    int
+	)
+
+	for _, d := range ds {
+		if n >= pr.count {
+			return
+		}
+
+		k := pr.key(d.Date)
+		if k == last {
+			continue
+		}
+
+		last = k
+		n++
+
+		d.Reasons = append(d.Reasons, pr.reason)
+	}
+}
+
+// Keep - backup is kept by policy.
+func (d *Decision) Keep() bool {
+	return len(d.Reasons) > 0
+}
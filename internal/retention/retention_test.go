@@ -0,0 +1,100 @@
+package retention_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/librun/ha-backup-tool/internal/retention"
+)
+
+// dailyBackups - one backup every day at 03:00 from date, newest is last.
+func dailyBackups(instance string, from time.Time, days int) []retention.Backup {
+	bs := make([]retention.Backup, 0, days)
+	for i := range days {
+		d := from.AddDate(0, 0, i).Add(3 * time.Hour)
+		bs = append(bs, retention.Backup{Path: instance + "_" + d.Format(time.DateOnly), Instance: instance, Date: d})
+	}
+
+	return bs
+}
+
+func kept(ds []*retention.Decision) []string {
+	var ps []string
+
+	for _, d := range ds {
+		if d.Keep() {
+			ps = append(ps, d.Path)
+		}
+	}
+
+	return ps
+}
+
+func TestPolicy_Apply(t *testing.T) {
+	// 2025-01-01 is Wednesday
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		policy  retention.Policy
+		backups []retention.Backup
+		want    []string
+	}{
+		{
+			name:    "daily",
+			policy:  retention.Policy{Daily: 3},
+			backups: dailyBackups("home", from, 10),
+			want:    []string{"home_2025-01-10", "home_2025-01-09", "home_2025-01-08"},
+		},
+		{
+			name:    "weekly keeps newest backup of week",
+			policy:  retention.Policy{Daily: 1, Weekly: 3},
+			backups: dailyBackups("home", from, 20),
+			// 2025-01-20 is Monday, weeks end on Sunday 2025-01-19 and 2025-01-12
+			want: []string{"home_2025-01-20", "home_2025-01-19", "home_2025-01-12"},
+		},
+		{
+			name:    "monthly",
+			policy:  retention.Policy{Monthly: 2},
+			backups: dailyBackups("home", from, 70),
+			want:    []string{"home_2025-03-11", "home_2025-02-28"},
+		},
+		{
+			name:   "two backups in day",
+			policy: retention.Policy{Daily: 2},
+			backups: append(dailyBackups("home", from, 2),
+				retention.Backup{Path: "late", Instance: "home", Date: from.Add(23 * time.Hour)}),
+			want: []string{"home_2025-01-02", "late"},
+		},
+		{
+			name:    "per instance",
+			policy:  retention.Policy{Daily: 1},
+			backups: append(dailyBackups("b", from, 3), dailyBackups("a", from, 2)...),
+			want:    []string{"a_2025-01-02", "b_2025-01-03"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds := tt.policy.Apply(tt.backups)
+			if len(ds) != len(tt.backups) {
+				t.Fatalf("Apply() = %d decisions, want %d", len(ds), len(tt.backups))
+			}
+
+			if got := kept(ds); !slices.Equal(got, tt.want) {
+				t.Errorf("Apply() kept %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicy_IsEmpty(t *testing.T) {
+	if !(retention.Policy{}).IsEmpty() {
+		t.Error("Expected empty policy")
+	}
+
+	if (retention.Policy{Weekly: 1}).IsEmpty() {
+		t.Error("Expected not empty policy")
+	}
+}
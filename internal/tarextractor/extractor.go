@@ -3,37 +3,61 @@ package tarextractor
 import (
 	"archive/tar"
 	"errors"
-	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 
+	"github.com/librun/ha-backup-tool/internal/backup"
+	"github.com/librun/ha-backup-tool/internal/logger"
 	"github.com/librun/ha-backup-tool/internal/options"
 )
 
 const (
 	UnpackDirMod = 0755
-	ExtTar       = ".tar"
-	ExtTarGz     = ".tar.gz"
+	ExtTar       = backup.ExtTar
+	ExtTarGz     = backup.ExtTarGz
 )
 
 type Extractor struct {
-	ops options.CmdExtractOptions
-	o   string
-	r   *tar.Reader
-	hl  map[string]string
-	fl  []string
-	fs  []string
+	ops  options.CmdExtractOptions
+	o    string
+	r    *tar.Reader
+	root *os.Root
+	hl   []link
+	sl   []link
+	fl   []string
+	fs   []string
+	j    *Journal
+	jn   string
+	dirs []dirMeta
+	n    int64
 }
 
 func New(outputDir string, ops *options.CmdExtractOptions) *Extractor {
-	return &Extractor{ops: *ops, o: outputDir}
+	e := &Extractor{ops: *ops, o: outputDir}
+	if e.ops.Log == nil {
+		e.ops.Log = logger.Discard()
+	}
+
+	return e
+}
+
+// WithJournal - write extracted entries to journal and skip entries already extracted by archive name.
+func (e *Extractor) WithJournal(j *Journal, archive string) *Extractor {
+	e.j = j
+	e.jn = archive
+
+	return e
 }
 
+// Run - extract tar archive, all writes are confined to output dir.
 func (e *Extractor) Run(r io.Reader) ([]string, []string, error) {
 	e.r = tar.NewReader(r)
-	e.hl = map[string]string{}
+	e.hl = make([]link, 0)
+	e.sl = make([]link, 0)
 	e.fl = make([]string, 0)
 	e.fs = make([]string, 0)
+	e.dirs = make([]dirMeta, 0)
 
 	if _, errS := os.Stat(e.o); os.IsNotExist(errS) {
 		if err := os.Mkdir(e.o, UnpackDirMod); err != nil {
@@ -41,6 +65,29 @@ func (e *Extractor) Run(r io.Reader) ([]string, []string, error) {
 		}
 	}
 
+	var err error
+	if e.root, err = os.OpenRoot(e.o); err != nil {
+		return nil, nil, err
+	}
+
+	err = e.run()
+	if errC := e.root.Close(); err == nil {
+		err = errC
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return e.fl, e.fs, nil
+}
+
+// Written - get count of bytes written to files.
+func (e *Extractor) Written() int64 {
+	return e.n
+}
+
+func (e *Extractor) run() error {
 	for {
 		header, err := e.r.Next()
 
@@ -49,90 +96,171 @@ func (e *Extractor) Run(r io.Reader) ([]string, []string, error) {
 		}
 
 		if err != nil {
-			return nil, nil, err
+			return err
 		}
 
 		if !e.checkIncludeOrExcludeFile(header.Name) {
 			continue
 		}
 
-		p, errS := SanitizeArchivePath(e.o, header.Name)
+		n, errS := SanitizeArchiveRelPath(header.Name)
 		if errS != nil {
-			return nil, nil, errS
+			return errS
 		}
 
-		if p == e.o {
+		if n == "." {
 			continue
 		}
 
-		if err = e.extractTarItem(header, p); err != nil {
-			return nil, nil, err
+		if err = e.ops.Limits.CheckEntry(n); err != nil {
+			return err
+		}
+
+		// dirs are not skipped, their metadata applied after all contents
+		if e.j != nil && header.Typeflag != tar.TypeDir && e.j.IsEntryDone(e.jn, header.Name) {
+			e.fl = append(e.fl, filepath.Join(e.o, n))
+
+			continue
+		}
+
+		if err = e.extractTarItem(header, n); err != nil {
+			return err
+		}
+
+		// links are written to journal after create
+		if e.j != nil && !isLinkType(header.Typeflag) {
+			if err = e.j.AddEntry(e.jn, header.Name); err != nil {
+				return err
+			}
 		}
 
-		e.fl = append(e.fl, p)
+		e.fl = append(e.fl, filepath.Join(e.o, n))
 	}
 
-	// create hard link after all extract
-	if err := e.createHardLinks(); err != nil {
-		return nil, nil, err
+	// create links after all extract, symlinks first because hard link can point to symlink
+	if err := e.createLinks(e.sl); err != nil {
+		return err
 	}
 
-	return e.fl, e.fs, nil
+	if err := e.removeUnsafeLinks(); err != nil {
+		return err
+	}
+
+	if err := e.createLinks(e.hl); err != nil {
+		return err
+	}
+
+	// names of extracted entries are flushed before archive is marked done,
+	// dirs are synced before their modes are applied, because mode can deny read
+	if e.j != nil {
+		if err := syncDirs(e.root); err != nil {
+			return err
+		}
+	}
+
+	if err := e.applyDirsMetadata(); err != nil {
+		return err
+	}
+
+	if e.j != nil {
+		return e.j.Done(e.jn)
+	}
+
+	return nil
 }
 
-func (e *Extractor) extractTarItem(header *tar.Header, fp string) error {
+func (e *Extractor) extractTarItem(header *tar.Header, name string) error {
+	// global pax header not have file
+	if header.Typeflag == tar.TypeXGlobalHeader {
+		return nil
+	}
+
+	// archive can have not entries for parent dirs
+	if err := e.root.MkdirAll(filepath.Dir(name), UnpackDirMod); err != nil {
+		return err
+	}
+
 	var err error
 	switch header.Typeflag {
 	case tar.TypeDir:
-		err = os.Mkdir(fp, UnpackDirMod)
-	case tar.TypeReg:
-		err = copyFile(fp, e.r, &e.ops)
+		err = e.createDir(name, header)
+	case tar.TypeReg, tar.TypeCont, tar.TypeGNUSparse:
+		err = e.createFile(name, header)
 	case tar.TypeLink:
 		if !e.ops.SkipCreateLinks {
-			e.hl[fp] = header.Linkname
+			e.hl = append(e.hl, link{name: name, header: header})
 		}
 	case tar.TypeSymlink:
 		if !e.ops.SkipCreateLinks {
-			err = os.Symlink(header.Linkname, fp)
+			e.sl = append(e.sl, link{name: name, header: header})
 		}
+	case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+		err = e.createSpecialFile(name, header)
 	default:
-		if e.ops.Verbose {
-			fmt.Printf("⚠️ ExtractTarGz: uknown type: %s in %s\n", string(header.Typeflag), header.Name)
-		}
+		e.skip(name, header)
+	}
 
-		e.fs = append(e.fs, fp)
+	return err
+}
+
+func (e *Extractor) createDir(name string, header *tar.Header) error {
+	err := e.root.Mkdir(name, UnpackDirMod)
+	if errors.Is(err, os.ErrExist) {
+		// archive can have duplicate dir entries
+		if s, errS := e.root.Lstat(name); errS == nil && s.IsDir() {
+			err = nil
+		} else if err = removeIfExists(e.root, name); err == nil {
+			err = e.root.Mkdir(name, UnpackDirMod)
+		}
 	}
 
 	if err != nil {
 		return err
 	}
 
+	e.dirs = append(e.dirs, dirMeta{name: name, header: header})
+
 	return nil
 }
 
-func (e *Extractor) createHardLinks() error {
-	if e.ops.SkipCreateLinks {
-		return nil
+func (e *Extractor) createFile(name string, header *tar.Header) error {
+	// not write through existing link or hard linked file
+	if err := removeIfExists(e.root, name); err != nil {
+		return err
 	}
 
-	for n, o := range e.hl {
-		p, err := SanitizeArchivePath(e.o, o)
-		if err != nil {
-			return err
-		}
+	n, err := copyFile(e.root, name, e.r, isSparse(header), e.j != nil, &e.ops)
+	e.n += n
 
-		if _, err = os.Stat(p); os.IsNotExist(err) {
-			e.fs = append(e.fs, n)
+	if err != nil {
+		return err
+	}
 
-			continue
-		}
+	return e.applyMetadata(name, header)
+}
 
-		if err = os.Link(p, n); err != nil {
-			return err
-		}
+func (e *Extractor) createSpecialFile(name string, header *tar.Header) error {
+	if !e.ops.CreateSpecial {
+		e.skip(name, header)
+
+		return nil
 	}
 
-	return nil
+	if err := removeIfExists(e.root, name); err != nil {
+		return err
+	}
+
+	if err := mknod(e.root, name, header); err != nil {
+		return err
+	}
+
+	return e.applyMetadata(name, header)
+}
+
+func (e *Extractor) skip(name string, header *tar.Header) {
+	e.ops.Log.Debug("Skip entry with unsupported type", "name", header.Name, "type", string(header.Typeflag))
+
+	e.fs = append(e.fs, filepath.Join(e.o, name))
 }
 
 func (e *Extractor) checkIncludeOrExcludeFile(fileName string) bool {
@@ -163,3 +291,7 @@ func (e *Extractor) checkIncludeOrExcludeFile(fileName string) bool {
 
 	return !fe
 }
+
+func isLinkType(t byte) bool {
+	return t == tar.TypeLink || t == tar.TypeSymlink
+}
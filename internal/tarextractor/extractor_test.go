@@ -0,0 +1,267 @@
+package tarextractor_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/librun/ha-backup-tool/internal/limits"
+	"github.com/librun/ha-backup-tool/internal/options"
+	"github.com/librun/ha-backup-tool/internal/tarextractor"
+)
+
+const testMaxArchiveSize = 1 << 20
+
+//nolint:gochecknoglobals // This is const varible
+var errAny = errors.New("any error")
+
+type testEntry struct {
+	header *tar.Header
+	data   string
+}
+
+func TestExtractor_ImplicitParentsAndDuplicateDirs(t *testing.T) {
+	b := buildTestTar(t, []testEntry{
+		{header: &tar.Header{Name: "data/deep/dir/file.txt", Typeflag: tar.TypeReg, Mode: 0644}, data: "deep"},
+		{header: &tar.Header{Name: "data/", Typeflag: tar.TypeDir, Mode: 0755}},
+		{header: &tar.Header{Name: "data/", Typeflag: tar.TypeDir, Mode: 0755}},
+		{header: &tar.Header{Name: "data/deep/", Typeflag: tar.TypeDir, Mode: 0755}},
+	})
+
+	o := runTestExtractor(t, b, testOptions())
+
+	assertFileData(t, filepath.Join(o, "data", "deep", "dir", "file.txt"), "deep")
+}
+
+func TestExtractor_SymlinkBeforeTarget(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need administrator rights on windows")
+	}
+
+	b := buildTestTar(t, []testEntry{
+		{header: &tar.Header{Name: "link.txt", Typeflag: tar.TypeSymlink, Linkname: "dir/target.txt"}},
+		{header: &tar.Header{Name: "hard.txt", Typeflag: tar.TypeLink, Linkname: "dir/target.txt"}},
+		{header: &tar.Header{Name: "dir/target.txt", Typeflag: tar.TypeReg, Mode: 0644}, data: "target"},
+	})
+
+	o := runTestExtractor(t, b, testOptions())
+
+	assertFileData(t, filepath.Join(o, "link.txt"), "target")
+	assertFileData(t, filepath.Join(o, "hard.txt"), "target")
+
+	l, err := os.Readlink(filepath.Join(o, "link.txt"))
+	if err != nil {
+		t.Fatalf("Readlink failed: %v", err)
+	}
+
+	if l != "dir/target.txt" {
+		t.Errorf("Expected link to dir/target.txt, got %s", l)
+	}
+}
+
+func TestExtractor_GNULongName(t *testing.T) {
+	n := strings.Repeat("long-dir-name/", 12) + "file.txt"
+
+	b := buildTestTar(t, []testEntry{
+		{header: &tar.Header{Name: n, Typeflag: tar.TypeReg, Mode: 0644, Format: tar.FormatGNU}, data: "long"},
+	})
+
+	o := runTestExtractor(t, b, testOptions())
+
+	assertFileData(t, filepath.Join(o, filepath.FromSlash(n)), "long")
+}
+
+func TestExtractor_GNUSparse(t *testing.T) {
+	// logical file: 8192 zero bytes, "data" and 4092 zero bytes
+	b := buildTestGNUSparseTar(t, "sparse.bin", "data", 8192, 4096*3)
+
+	o := runTestExtractor(t, b, testOptions())
+
+	got, err := os.ReadFile(filepath.Join(o, "sparse.bin"))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+
+	want := make([]byte, 4096*3)
+	copy(want[8192:], "data")
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("Sparse file content not valid, got len %d", len(got))
+	}
+}
+
+func TestExtractor_SpecialFiles(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("special files support only on linux")
+	}
+
+	b := buildTestTar(t, []testEntry{
+		{header: &tar.Header{Name: "pipe", Typeflag: tar.TypeFifo, Mode: 0644}},
+	})
+
+	ops := testOptions()
+	e := tarextractor.New(filepath.Join(t.TempDir(), "out"), ops)
+
+	_, fs, err := e.Run(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(fs) != 1 {
+		t.Errorf("Expected fifo skipped by default, got skipped %v", fs)
+	}
+
+	ops.CreateSpecial = true
+	o := runTestExtractor(t, b, ops)
+
+	s, err := os.Lstat(filepath.Join(o, "pipe"))
+	if err != nil {
+		t.Fatalf("Lstat failed: %v", err)
+	}
+
+	if s.Mode()&os.ModeNamedPipe == 0 {
+		t.Errorf("Expected named pipe, got mode %s", s.Mode())
+	}
+}
+
+func TestExtractor_Limits(t *testing.T) {
+	es := []testEntry{
+		{header: &tar.Header{Name: "a/b/c.txt", Typeflag: tar.TypeReg, Mode: 0644}, data: "1234"},
+		{header: &tar.Header{Name: "d.txt", Typeflag: tar.TypeReg, Mode: 0644}, data: "5678"},
+	}
+
+	tests := []struct {
+		name    string
+		maxSize int64
+		l       *limits.Limits
+		wantErr error
+	}{
+		{name: "file size equal limit", maxSize: 4},
+		{name: "file size exceeds limit", maxSize: 3, wantErr: errAny},
+		{name: "total size equal limit", maxSize: 4, l: &limits.Limits{MaxTotalSize: 8}},
+		{
+			name: "total size exceeds limit", maxSize: 4,
+			l: &limits.Limits{MaxTotalSize: 7}, wantErr: limits.ErrTotalSizeExceeded,
+		},
+		{
+			name: "entries exceeds limit", maxSize: 4,
+			l: &limits.Limits{MaxEntries: 1}, wantErr: limits.ErrEntriesExceeded,
+		},
+		{
+			name: "path depth exceeds limit", maxSize: 4,
+			l: &limits.Limits{MaxPathDepth: 2}, wantErr: limits.ErrPathDepthExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops := testOptions()
+			ops.MaxArchiveSize = tt.maxSize
+			ops.Limits = tt.l.Clone()
+
+			e := tarextractor.New(filepath.Join(t.TempDir(), "out"), ops)
+			_, _, err := e.Run(bytes.NewReader(buildTestTar(t, es)))
+
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Errorf("Run failed: %v", err)
+			case tt.wantErr == errAny && err == nil:
+				t.Error("Expected error, got nil")
+			case tt.wantErr != nil && tt.wantErr != errAny && !errors.Is(err, tt.wantErr):
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func testOptions() *options.CmdExtractOptions {
+	return &options.CmdExtractOptions{GlobalOptions: options.GlobalOptions{MaxArchiveSize: testMaxArchiveSize}}
+}
+
+func runTestExtractor(t *testing.T, b []byte, ops *options.CmdExtractOptions) string {
+	t.Helper()
+
+	o := filepath.Join(t.TempDir(), "out")
+
+	_, fs, err := tarextractor.New(o, ops).Run(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(fs) > 0 {
+		t.Errorf("Expected no skipped files, got %v", fs)
+	}
+
+	return o
+}
+
+func buildTestTar(t *testing.T, es []testEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	for _, e := range es {
+		e.header.Size = int64(len(e.data))
+		if err := tw.WriteHeader(e.header); err != nil {
+			t.Fatalf("WriteHeader %s failed: %v", e.header.Name, err)
+		}
+
+		if _, err := tw.Write([]byte(e.data)); err != nil {
+			t.Fatalf("Write %s failed: %v", e.header.Name, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+// buildTestGNUSparseTar - build archive with old GNU sparse entry, golang tar writer can't write it.
+func buildTestGNUSparseTar(t *testing.T, name, data string, offset, size int64) []byte {
+	t.Helper()
+
+	b := buildTestTar(t, []testEntry{
+		{header: &tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Format: tar.FormatGNU}, data: data},
+	})
+
+	h := b[:512]
+	h[156] = tar.TypeGNUSparse
+
+	// first sparse entry: offset and size of data
+	copy(h[386:398], fmt.Sprintf("%011o\x00", offset))
+	copy(h[398:410], fmt.Sprintf("%011o\x00", len(data)))
+	// real size of file
+	copy(h[483:495], fmt.Sprintf("%011o\x00", size))
+
+	copy(h[148:156], "        ")
+	var sum int64
+	for _, c := range h {
+		sum += int64(c)
+	}
+	copy(h[148:156], fmt.Sprintf("%06s\x00 ", strconv.FormatInt(sum, 8)))
+
+	return b
+}
+
+func assertFileData(t *testing.T, p, data string) {
+	t.Helper()
+
+	b, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatalf("ReadFile %s failed: %v", p, err)
+	}
+
+	if string(b) != data {
+		t.Errorf("File %s expected %q, got %q", p, data, b)
+	}
+}
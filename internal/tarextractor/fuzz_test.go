@@ -0,0 +1,167 @@
+package tarextractor_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/librun/ha-backup-tool/internal/tarextractor"
+)
+
+const testOutsideData = "outside"
+
+func TestSanitizeArchivePath(t *testing.T) {
+	var td = []struct {
+		Dir   string
+		Name  string
+		Valid bool
+	}{
+		{Dir: "/out", Name: "data/file.txt", Valid: true},
+		{Dir: "/out", Name: "./data/../file.txt", Valid: true},
+		{Dir: "/out", Name: "/etc/passwd", Valid: true},
+		{Dir: "/out", Name: "../out-evil/file.txt", Valid: false},
+		{Dir: "/out", Name: "data/../../file.txt", Valid: false},
+		{Dir: "out", Name: "..", Valid: false},
+	}
+
+	for _, v := range td {
+		p, err := tarextractor.SanitizeArchivePath(v.Dir, v.Name)
+		if (err == nil) != v.Valid {
+			t.Errorf("For %s in %s got error %v wait valid %t", v.Name, v.Dir, err, v.Valid)
+
+			continue
+		}
+
+		if v.Valid && !strings.HasPrefix(p, filepath.Clean(v.Dir)+string(filepath.Separator)) {
+			t.Errorf("For %s in %s got path %s outside dir", v.Name, v.Dir, p)
+		}
+	}
+}
+
+func TestExtractor_UnsafeSymlinks(t *testing.T) {
+	b := buildTestTar(t, []testEntry{
+		{header: &tar.Header{Name: "abs", Typeflag: tar.TypeSymlink, Linkname: "/etc"}},
+		{header: &tar.Header{Name: "dir/up", Typeflag: tar.TypeSymlink, Linkname: "../.."}},
+		{header: &tar.Header{Name: "a/b/self", Typeflag: tar.TypeSymlink, Linkname: "."}},
+		{header: &tar.Header{Name: "a/b/chain", Typeflag: tar.TypeSymlink, Linkname: "self/../../.."}},
+		{header: &tar.Header{Name: "a/b/ok", Typeflag: tar.TypeSymlink, Linkname: "../../dir"}},
+	})
+
+	o := filepath.Join(t.TempDir(), "out")
+
+	_, fs, err := tarextractor.New(o, testOptions()).Run(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(fs) != 3 {
+		t.Errorf("Expected 3 unsafe links skipped, got %v", fs)
+	}
+
+	for _, n := range []string{"abs", "dir/up", "a/b/chain"} {
+		if _, errS := os.Lstat(filepath.Join(o, n)); !os.IsNotExist(errS) {
+			t.Errorf("Expected unsafe link %s not created, got %v", n, errS)
+		}
+	}
+
+	if _, errS := os.Lstat(filepath.Join(o, "a", "b", "ok")); errS != nil {
+		t.Errorf("Expected safe link created, got %v", errS)
+	}
+}
+
+// FuzzExtractor - extract hostile archives with traversal names and links, nothing must be written outside dir.
+func FuzzExtractor(f *testing.F) {
+	f.Add("../evil", "", uint8(0), "file", "", uint8(0), "evil")
+	f.Add("link", "..", uint8(2), "link/evil", "", uint8(0), "evil")
+	f.Add("link", "/tmp", uint8(2), "link/evil", "", uint8(0), "evil")
+	f.Add("link", "../outside", uint8(2), "link", "", uint8(0), "link")
+	f.Add("hard", "../outside", uint8(3), "hard", "", uint8(0), "hard")
+	f.Add("dir/self", ".", uint8(2), "dir/up", "self/../..", uint8(2), "dir/up/outside")
+	f.Add("a", "b", uint8(2), "b", "a", uint8(2), "a/evil")
+	f.Add("/abs/file", "", uint8(1), "abs", "../../..", uint8(3), "abs/x")
+
+	f.Fuzz(func(t *testing.T, n1, l1 string, t1 uint8, n2, l2 string, t2 uint8, n3 string) {
+		d := t.TempDir()
+		o := filepath.Join(d, "out")
+		outside := filepath.Join(d, "outside")
+
+		if err := os.WriteFile(outside, []byte(testOutsideData), 0600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		writeFuzzEntry(tw, n1, l1, t1)
+		writeFuzzEntry(tw, n2, l2, t2)
+		writeFuzzEntry(tw, n3, "", 0)
+		_ = tw.Close()
+
+		// errors are allowed, archive can be not valid
+		_, _, _ = tarextractor.New(o, testOptions()).Run(bytes.NewReader(buf.Bytes()))
+
+		es, err := os.ReadDir(d)
+		if err != nil {
+			t.Fatalf("ReadDir failed: %v", err)
+		}
+
+		for _, e := range es {
+			if e.Name() != "out" && e.Name() != "outside" {
+				t.Fatalf("File %s written outside output dir", e.Name())
+			}
+		}
+
+		if b, _ := os.ReadFile(outside); string(b) != testOutsideData {
+			t.Fatalf("File outside output dir was changed: %q", b)
+		}
+
+		assertLinksInDir(t, o)
+	})
+}
+
+func writeFuzzEntry(tw *tar.Writer, name, linkname string, typ uint8) {
+	types := []byte{tar.TypeReg, tar.TypeDir, tar.TypeSymlink, tar.TypeLink}
+	h := &tar.Header{Name: name, Linkname: linkname, Typeflag: types[int(typ)%len(types)], Mode: 0644}
+
+	if h.Typeflag == tar.TypeReg {
+		h.Size = int64(len(name))
+	}
+
+	if err := tw.WriteHeader(h); err != nil {
+		return
+	}
+
+	if h.Typeflag == tar.TypeReg {
+		_, _ = tw.Write([]byte(name))
+	}
+}
+
+// assertLinksInDir - check that all created symlinks which can be resolved point inside dir.
+func assertLinksInDir(t *testing.T, dir string) {
+	t.Helper()
+
+	rd, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return
+	}
+
+	_ = filepath.WalkDir(dir, func(p string, d fs.DirEntry, errW error) error {
+		if errW != nil || d.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+
+		r, errE := filepath.EvalSymlinks(p)
+		if errE != nil {
+			return nil
+		}
+
+		if r != rd && !strings.HasPrefix(r, rd+string(filepath.Separator)) {
+			t.Fatalf("Symlink %s point outside output dir: %s", p, r)
+		}
+
+		return nil
+	})
+}
@@ -4,22 +4,39 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 
+	"github.com/librun/ha-backup-tool/internal/limits"
 	"github.com/librun/ha-backup-tool/internal/options"
 )
 
+var (
+	ErrTaintedPath = errors.New("content filepath is tainted")
+)
+
 // Sanitize archive file pathing from "G305: Zip Slip vulnerability"
 func SanitizeArchivePath(d, t string) (string, error) {
-	v := filepath.Join(d, t)
-	if strings.HasPrefix(v, filepath.Clean(d)) {
-		return v, nil
+	r, err := SanitizeArchiveRelPath(t)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(d, r), nil
+}
+
+// SanitizeArchiveRelPath - get clean path of archive entry relative to output dir,
+// leading slashes are removed and paths escaped output dir are rejected.
+func SanitizeArchiveRelPath(t string) (string, error) {
+	r := filepath.Clean(strings.TrimLeft(filepath.FromSlash(t), `/\`))
+	if r == "." || filepath.IsLocal(r) {
+		return r, nil
 	}
 
-	//nolint:err113 // Dynamic error
-	return "", fmt.Errorf("%s: %s", "content filepath is tainted", t)
+	return "", fmt.Errorf("%w: %s", ErrTaintedPath, t)
 }
 
 // GetBaseNameArchive - get base archive name without ext and location.
@@ -31,20 +48,76 @@ func GetBaseNameArchive(fpath string) string {
 	return fn
 }
 
-func copyFile(fpath string, r io.Reader, ops *options.CmdExtractOptions) error {
-	outFile, err := os.Create(fpath)
-	if err != nil {
+// removeIfExists - remove file for replace it, like tar replace earlier entries with same name.
+func removeIfExists(root *os.Root, name string) error {
+	if err := root.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
 		return err
 	}
 
-	defer outFile.Close()
+	return nil
+}
 
-	written, errW := io.CopyN(outFile, r, ops.MaxArchiveSize)
-	if errW != nil && !errors.Is(errW, io.EOF) {
-		return errW
-	} else if written == ops.MaxArchiveSize {
-		return fmt.Errorf("size of decoded data exceeds allowed size %d", ops.MaxArchiveSize) //nolint:err113 // Dynamic error
+// copyFile - write file from archive, return count of written bytes.
+// With sync data of file is flushed to disk before return, so file recorded in journal is not lost on crash.
+func copyFile(root *os.Root, name string, r io.Reader, sparse, sync bool,
+	ops *options.CmdExtractOptions) (int64, error) {
+	outFile, err := root.Create(name)
+	if err != nil {
+		return 0, err
 	}
 
-	return nil
+	// error of write can be reported only on close, so file is not extracted without successful close
+	written, err := writeFile(outFile, r, sparse, sync, ops)
+
+	return written, errors.Join(err, outFile.Close())
+}
+
+// writeFile - write content into created file, size of file is checked by limits.
+func writeFile(outFile *os.File, r io.Reader, sparse, sync bool, ops *options.CmdExtractOptions) (int64, error) {
+	var w io.Writer = outFile
+	if sparse {
+		w = &sparseWriter{f: outFile}
+	}
+
+	// read one byte more than allowed for detect that file exceeds allowed size
+	written, err := io.CopyN(ops.Limits.Writer(w), r, ops.MaxArchiveSize+1)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return written, err
+	} else if written > ops.MaxArchiveSize {
+		return written, fmt.Errorf("%w %d", limits.ErrFileSizeExceeded, ops.MaxArchiveSize)
+	}
+
+	// file can end with hole, which is skipped by seek
+	if sparse {
+		if err = outFile.Truncate(written); err != nil {
+			return written, err
+		}
+	}
+
+	if sync {
+		return written, outFile.Sync()
+	}
+
+	return written, nil
+}
+
+// syncDirs - flush all dirs of output to disk, so names of synced files are not lost on crash.
+func syncDirs(root *os.Root) error {
+	// dirs can not be synced on windows, entries are flushed by file system
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	return fs.WalkDir(root.FS(), ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+
+		f, err := root.Open(name)
+		if err != nil {
+			return err
+		}
+
+		return errors.Join(f.Sync(), f.Close())
+	})
 }
@@ -0,0 +1,149 @@
+package tarextractor
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+)
+
+const (
+	journalFileMod = 0600
+)
+
+// Journal - progress journal with extracted archives and entries, used for resume extract.
+// Every record is written as one json line, so journal stays readable after interrupt.
+// Entry is recorded only after data of file is synced to disk, so record which survived crash
+// never points to file without data. Records are synced with archive done, lost records only
+// make resume extract entries again.
+type Journal struct {
+	mu      sync.Mutex
+	f       *os.File
+	done    map[string]bool
+	entries map[string]map[string]bool
+}
+
+type journalRecord struct {
+	Archive string `json:"archive"`
+	Entry   string `json:"entry,omitempty"`
+	Done    bool   `json:"done,omitempty"`
+}
+
+// OpenJournal - open journal file, if resume is true records from previous run are loaded.
+func OpenJournal(fpath string, resume bool) (*Journal, error) {
+	j := &Journal{
+		done:    map[string]bool{},
+		entries: map[string]map[string]bool{},
+	}
+
+	fl := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if resume {
+		if err := j.load(fpath); err != nil {
+			return nil, err
+		}
+	} else {
+		fl |= os.O_TRUNC
+	}
+
+	var err error
+	if j.f, err = os.OpenFile(fpath, fl, journalFileMod); err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// IsDone - check that archive was fully extracted.
+func (j *Journal) IsDone(archive string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.done[archive]
+}
+
+// IsEntryDone - check that entry from archive was extracted.
+func (j *Journal) IsEntryDone(archive, entry string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.entries[archive][entry]
+}
+
+// AddEntry - mark entry from archive as extracted.
+func (j *Journal) AddEntry(archive, entry string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.addEntry(archive, entry)
+
+	return j.write(journalRecord{Archive: archive, Entry: entry})
+}
+
+// Done - mark archive as fully extracted.
+func (j *Journal) Done(archive string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.done[archive] = true
+
+	if err := j.write(journalRecord{Archive: archive, Done: true}); err != nil {
+		return err
+	}
+
+	return j.f.Sync()
+}
+
+// Close - close journal file.
+func (j *Journal) Close() error {
+	return j.f.Close()
+}
+
+func (j *Journal) load(fpath string) error {
+	f, err := os.Open(fpath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var r journalRecord
+
+		// last line can be broken after interrupt, skip it
+		if errU := json.Unmarshal(s.Bytes(), &r); errU != nil {
+			continue
+		}
+
+		if r.Done {
+			j.done[r.Archive] = true
+
+			continue
+		}
+
+		j.addEntry(r.Archive, r.Entry)
+	}
+
+	return s.Err()
+}
+
+func (j *Journal) addEntry(archive, entry string) {
+	if _, ok := j.entries[archive]; !ok {
+		j.entries[archive] = map[string]bool{}
+	}
+
+	j.entries[archive][entry] = true
+}
+
+func (j *Journal) write(r journalRecord) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = j.f.Write(append(b, '\n'))
+
+	return err
+}
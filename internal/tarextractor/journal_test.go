@@ -0,0 +1,83 @@
+package tarextractor_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/librun/ha-backup-tool/internal/tarextractor"
+)
+
+func TestJournal_Resume(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "journal")
+
+	j, err := tarextractor.OpenJournal(p, false)
+	if err != nil {
+		t.Fatalf("OpenJournal failed: %v", err)
+	}
+
+	if err = j.AddEntry("backup.tar", "backup.json"); err != nil {
+		t.Fatalf("AddEntry failed: %v", err)
+	}
+	if err = j.Done("backup.tar"); err != nil {
+		t.Fatalf("Done failed: %v", err)
+	}
+	if err = j.AddEntry("homeassistant.tar.gz", "data/configuration.yaml"); err != nil {
+		t.Fatalf("AddEntry failed: %v", err)
+	}
+	if err = j.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// emulate interrupt in the middle of write record
+	f, err := os.OpenFile(p, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	if _, err = f.WriteString(`{"archive":"homeassistant.tar.gz","ent`); err != nil {
+		t.Fatalf("WriteString failed: %v", err)
+	}
+	f.Close()
+
+	j, err = tarextractor.OpenJournal(p, true)
+	if err != nil {
+		t.Fatalf("OpenJournal resume failed: %v", err)
+	}
+	defer j.Close()
+
+	if !j.IsDone("backup.tar") {
+		t.Error("Expected backup.tar done")
+	}
+	if j.IsDone("homeassistant.tar.gz") {
+		t.Error("Expected homeassistant.tar.gz not done")
+	}
+	if !j.IsEntryDone("homeassistant.tar.gz", "data/configuration.yaml") {
+		t.Error("Expected data/configuration.yaml done")
+	}
+	if j.IsEntryDone("homeassistant.tar.gz", "data/secrets.yaml") {
+		t.Error("Expected data/secrets.yaml not done")
+	}
+}
+
+func TestJournal_WithoutResume(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "journal")
+
+	j, err := tarextractor.OpenJournal(p, false)
+	if err != nil {
+		t.Fatalf("OpenJournal failed: %v", err)
+	}
+	if err = j.Done("backup.tar"); err != nil {
+		t.Fatalf("Done failed: %v", err)
+	}
+	j.Close()
+
+	j, err = tarextractor.OpenJournal(p, false)
+	if err != nil {
+		t.Fatalf("OpenJournal failed: %v", err)
+	}
+	defer j.Close()
+
+	if j.IsDone("backup.tar") {
+		t.Error("Expected journal is empty without resume")
+	}
+}
@@ -0,0 +1,185 @@
+package tarextractor
+
+import (
+	"archive/tar"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// maxLinksResolve - limit of symlinks resolve in one path, same as in linux.
+	maxLinksResolve = 40
+)
+
+// link - symlink or hard link created after all entries, when link target already exists.
+type link struct {
+	name   string
+	header *tar.Header
+}
+
+func (e *Extractor) createLinks(ls []link) error {
+	if e.ops.SkipCreateLinks {
+		return nil
+	}
+
+	for _, l := range ls {
+		ok, err := e.createLink(l)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			continue
+		}
+
+		if e.j != nil {
+			if err = e.j.AddEntry(e.jn, l.header.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// createLink - create link in root, return false if link skipped.
+func (e *Extractor) createLink(l link) (bool, error) {
+	if err := e.root.MkdirAll(filepath.Dir(l.name), UnpackDirMod); err != nil {
+		return false, err
+	}
+
+	if l.header.Typeflag == tar.TypeSymlink {
+		if !e.ops.AllowUnsafeLinks && !e.isSafeLink(l.name, l.header.Linkname) {
+			e.skipUnsafeLink(l)
+
+			return false, nil
+		}
+
+		if err := removeIfExists(e.root, l.name); err != nil {
+			return false, err
+		}
+
+		if err := e.root.Symlink(l.header.Linkname, l.name); err != nil {
+			return false, err
+		}
+
+		return true, e.applyMetadata(l.name, l.header)
+	}
+
+	t, err := SanitizeArchiveRelPath(l.header.Linkname)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err = e.root.Lstat(t); errors.Is(err, os.ErrNotExist) {
+		e.fs = append(e.fs, filepath.Join(e.o, l.name))
+
+		return false, nil
+	}
+
+	if err = removeIfExists(e.root, l.name); err != nil {
+		return false, err
+	}
+
+	return true, e.root.Link(t, l.name)
+}
+
+// removeUnsafeLinks - check created symlinks again, because link can point outside root through other symlink
+// created after it.
+func (e *Extractor) removeUnsafeLinks() error {
+	if e.ops.SkipCreateLinks || e.ops.AllowUnsafeLinks {
+		return nil
+	}
+
+	for _, l := range e.sl {
+		s, err := e.root.Lstat(l.name)
+		if err != nil || s.Mode()&fs.ModeSymlink == 0 {
+			continue
+		}
+
+		if e.isInRoot(l.name) {
+			continue
+		}
+
+		if err = e.root.Remove(l.name); err != nil {
+			return err
+		}
+
+		e.skipUnsafeLink(l)
+	}
+
+	return nil
+}
+
+func (e *Extractor) skipUnsafeLink(l link) {
+	e.ops.Log.Debug("Skip unsafe symlink, use --allow-unsafe-links for create it",
+		"name", l.header.Name, "target", l.header.Linkname)
+
+	e.fs = append(e.fs, filepath.Join(e.o, l.name))
+}
+
+// isSafeLink - check that symlink target is relative and not point outside root.
+func (e *Extractor) isSafeLink(name, target string) bool {
+	if isAbsLink(target) {
+		return false
+	}
+
+	// path not cleaned, because ".." after symlink must be resolved from symlink target
+	return e.isInRoot(filepath.ToSlash(filepath.Dir(name)) + "/" + filepath.ToSlash(target))
+}
+
+// isInRoot - resolve path with symlinks already created in root and check that it not escapes root.
+func (e *Extractor) isInRoot(name string) bool {
+	var r []string
+	var n int
+
+	ps := strings.Split(filepath.ToSlash(name), "/")
+	for len(ps) > 0 {
+		c := ps[0]
+		ps = ps[1:]
+
+		switch c {
+		case "", ".":
+			continue
+		case "..":
+			if len(r) == 0 {
+				return false
+			}
+
+			r = r[:len(r)-1]
+
+			continue
+		}
+
+		cp := path.Join(append(r, c)...)
+
+		s, err := e.root.Lstat(filepath.FromSlash(cp))
+		if err != nil || s.Mode()&fs.ModeSymlink == 0 {
+			// not exists part of path is checked as is
+			r = append(r, c)
+
+			continue
+		}
+
+		if n++; n > maxLinksResolve {
+			return false
+		}
+
+		t, err := e.root.Readlink(filepath.FromSlash(cp))
+		if err != nil || isAbsLink(t) {
+			return false
+		}
+
+		ps = append(strings.Split(filepath.ToSlash(t), "/"), ps...)
+	}
+
+	return true
+}
+
+func isAbsLink(t string) bool {
+	return filepath.IsAbs(t) || filepath.VolumeName(t) != "" || strings.HasPrefix(t, "/") || strings.HasPrefix(t, `\`)
+}
@@ -0,0 +1,100 @@
+package tarextractor
+
+import (
+	"archive/tar"
+	"io/fs"
+	"strings"
+)
+
+const (
+	paxXattrPrefix = "SCHILY.xattr."
+)
+
+// dirMeta - directory with header, metadata applied after all contents are written.
+type dirMeta struct {
+	name   string
+	header *tar.Header
+}
+
+// applyMetadata - apply permissions, ownership, xattrs and times from header to extracted item.
+func (e *Extractor) applyMetadata(name string, h *tar.Header) error {
+	if e.ops.NoPreserve {
+		return nil
+	}
+
+	if h.Typeflag == tar.TypeSymlink {
+		if e.ops.SameOwner {
+			return e.root.Lchown(name, h.Uid, h.Gid)
+		}
+
+		return nil
+	}
+
+	if e.ops.SameOwner {
+		if err := e.root.Chown(name, h.Uid, h.Gid); err != nil {
+			return err
+		}
+	}
+
+	// open of fifo or device can block or have side effects, so xattrs are set only for files and dirs
+	if !isSpecialType(h.Typeflag) {
+		e.applyXattrs(name, h)
+	}
+
+	if err := e.root.Chmod(name, headerMode(h)); err != nil {
+		return err
+	}
+
+	return e.root.Chtimes(name, h.AccessTime, h.ModTime)
+}
+
+// applyDirsMetadata - apply metadata to dirs from deepest, so read only dirs and times not break write contents.
+func (e *Extractor) applyDirsMetadata() error {
+	for i := len(e.dirs) - 1; i >= 0; i-- {
+		if err := e.applyMetadata(e.dirs[i].name, e.dirs[i].header); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *Extractor) applyXattrs(name string, h *tar.Header) {
+	var xs [][2]string
+	for k, v := range h.PAXRecords {
+		if n, ok := strings.CutPrefix(k, paxXattrPrefix); ok {
+			xs = append(xs, [2]string{n, v})
+		}
+	}
+
+	if len(xs) == 0 {
+		return
+	}
+
+	f, err := e.root.Open(name)
+	if err != nil {
+		e.warnXattr(name, "", err)
+
+		return
+	}
+	defer f.Close()
+
+	for _, x := range xs {
+		// xattrs not supported by all file systems and os, so it is not fatal
+		if err = setXattr(f, x[0], x[1]); err != nil {
+			e.warnXattr(name, x[0], err)
+		}
+	}
+}
+
+func (e *Extractor) warnXattr(name, xattr string, err error) {
+	e.ops.Log.Debug("Failed set xattr", "name", name, "xattr", xattr, "error", err)
+}
+
+func isSpecialType(t byte) bool {
+	return t == tar.TypeFifo || t == tar.TypeChar || t == tar.TypeBlock
+}
+
+func headerMode(h *tar.Header) fs.FileMode {
+	return h.FileInfo().Mode() & (fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky)
+}
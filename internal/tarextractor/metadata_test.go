@@ -0,0 +1,143 @@
+//go:build linux || darwin
+
+package tarextractor_test
+
+import (
+	"archive/tar"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const testXattr = "user.ha-backup-tool"
+
+func TestExtractor_Metadata(t *testing.T) {
+	dt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	st := dt.Add(time.Hour)
+	ft := dt.Add(2 * time.Hour)
+
+	// dirs are before contents, so their times are changed by writes and must be applied after
+	b := buildTestTar(t, []testEntry{
+		{header: &tar.Header{Name: "data/", Typeflag: tar.TypeDir, Mode: 0750, ModTime: dt}},
+		{header: &tar.Header{Name: "data/sub/", Typeflag: tar.TypeDir, Mode: 0500, ModTime: st}},
+		{header: &tar.Header{Name: "data/sub/file.txt", Typeflag: tar.TypeReg, Mode: 0640, ModTime: ft}, data: "a"},
+		{header: &tar.Header{Name: "data/run.sh", Typeflag: tar.TypeReg, Mode: 0755, ModTime: ft}, data: "b"},
+	})
+
+	tests := []struct {
+		name       string
+		noPreserve bool
+		want       map[string]os.FileMode
+	}{
+		{
+			name: "preserve",
+			want: map[string]os.FileMode{"data": 0750, "data/sub": 0500, "data/sub/file.txt": 0640, "data/run.sh": 0755},
+		},
+		{
+			name:       "no preserve",
+			noPreserve: true,
+			want:       map[string]os.FileMode{"data": 0755, "data/sub": 0755},
+		},
+	}
+
+	times := map[string]time.Time{"data": dt, "data/sub": st, "data/sub/file.txt": ft, "data/run.sh": ft}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops := testOptions()
+			ops.NoPreserve = tt.noPreserve
+
+			o := runTestExtractor(t, b, ops)
+			t.Cleanup(func() { _ = os.Chmod(filepath.Join(o, "data", "sub"), 0755) })
+
+			for n, m := range tt.want {
+				s, err := os.Stat(filepath.Join(o, n))
+				if err != nil {
+					t.Fatalf("Stat %s failed: %v", n, err)
+				}
+
+				if s.Mode().Perm() != m {
+					t.Errorf("Mode of %s got %v wait %v", n, s.Mode().Perm(), m)
+				}
+			}
+
+			for n, mt := range times {
+				s, err := os.Stat(filepath.Join(o, n))
+				if err != nil {
+					t.Fatalf("Stat %s failed: %v", n, err)
+				}
+
+				if s.ModTime().Equal(mt) == tt.noPreserve {
+					t.Errorf("Time of %s got %v, time from archive %v, preserve %v", n, s.ModTime(), mt,
+						!tt.noPreserve)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractor_Xattrs(t *testing.T) {
+	b := buildTestTar(t, []testEntry{
+		{header: &tar.Header{Name: "file.txt", Typeflag: tar.TypeReg, Mode: 0644,
+			PAXRecords: map[string]string{"SCHILY.xattr." + testXattr: "value"}}, data: "a"},
+	})
+
+	for _, noPreserve := range []bool{false, true} {
+		ops := testOptions()
+		ops.NoPreserve = noPreserve
+
+		p := filepath.Join(runTestExtractor(t, b, ops), "file.txt")
+
+		buf := make([]byte, 64)
+
+		n, err := unix.Getxattr(p, testXattr, buf)
+		if errors.Is(err, unix.ENOTSUP) {
+			t.Skip("xattrs not supported by file system of temp dir")
+		}
+
+		switch {
+		case noPreserve && err == nil:
+			t.Errorf("Expected xattr not set with no preserve, got %q", buf[:n])
+		case !noPreserve && err != nil:
+			t.Errorf("Getxattr failed: %v", err)
+		case !noPreserve && string(buf[:n]) != "value":
+			t.Errorf("Xattr got %q wait %q", buf[:n], "value")
+		}
+	}
+}
+
+func TestExtractor_SameOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("owner can be changed only by root")
+	}
+
+	const id = 4321
+
+	b := buildTestTar(t, []testEntry{
+		{header: &tar.Header{Name: "file.txt", Typeflag: tar.TypeReg, Mode: 0644, Uid: id, Gid: id}, data: "a"},
+	})
+
+	for _, sameOwner := range []bool{false, true} {
+		ops := testOptions()
+		ops.SameOwner = sameOwner
+
+		s, err := os.Stat(filepath.Join(runTestExtractor(t, b, ops), "file.txt"))
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+
+		st, ok := s.Sys().(*syscall.Stat_t)
+		if !ok {
+			t.Fatal("Stat_t not available")
+		}
+
+		if (st.Uid == id && st.Gid == id) != sameOwner {
+			t.Errorf("With same owner %v got uid %d gid %d", sameOwner, st.Uid, st.Gid)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package tarextractor
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	sparseBlockSize = 4096
+	paxSparsePrefix = "GNU.sparse."
+)
+
+// sparseWriter - writer for sparse files, blocks with zeros are skipped by seek and not allocate disk space.
+type sparseWriter struct {
+	f *os.File
+}
+
+func (w *sparseWriter) Write(p []byte) (int, error) {
+	var n int
+
+	for len(p) > 0 {
+		b := p[:min(len(p), sparseBlockSize)]
+
+		if isZeroBlock(b) {
+			if _, err := w.f.Seek(int64(len(b)), io.SeekCurrent); err != nil {
+				return n, err
+			}
+		} else if _, err := w.f.Write(b); err != nil {
+			return n, err
+		}
+
+		n += len(b)
+		p = p[len(b):]
+	}
+
+	return n, nil
+}
+
+// isSparse - check that entry is sparse file in old GNU or PAX format.
+func isSparse(h *tar.Header) bool {
+	if h.Typeflag == tar.TypeGNUSparse {
+		return true
+	}
+
+	for k := range h.PAXRecords {
+		if strings.HasPrefix(k, paxSparsePrefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isZeroBlock(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+
+	return true
+}
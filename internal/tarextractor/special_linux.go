@@ -0,0 +1,36 @@
+package tarextractor
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// mknod - create fifo, char or block device relative to parent dir opened in root.
+func mknod(root *os.Root, name string, h *tar.Header) error {
+	d, err := root.Open(filepath.Dir(name))
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	fd := int(d.Fd()) //nolint:gosec // file descriptor fit in int
+	n := filepath.Base(name)
+	m := uint32(headerMode(h).Perm()) //nolint:gosec // permission bits fit in uint32
+
+	switch h.Typeflag {
+	case tar.TypeFifo:
+		return unix.Mkfifoat(fd, n, m)
+	case tar.TypeChar:
+		m |= unix.S_IFCHR
+	case tar.TypeBlock:
+		m |= unix.S_IFBLK
+	}
+
+	//nolint:gosec // device numbers from tar header fit in uint32
+	dev := unix.Mkdev(uint32(h.Devmajor), uint32(h.Devminor))
+
+	return unix.Mknodat(fd, n, m, int(dev)) //nolint:gosec // device number fit in int
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package tarextractor
+
+import (
+	"archive/tar"
+	"errors"
+	"os"
+)
+
+// errSpecialFileNotSupported - darwin not have mknodat, so special files can not be created confined to output dir.
+var errSpecialFileNotSupported = errors.New("special files not support on this os")
+
+func mknod(_ *os.Root, _ string, _ *tar.Header) error {
+	return errSpecialFileNotSupported
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+
+package tarextractor
+
+import (
+	"errors"
+	"os"
+)
+
+var errXattrNotSupported = errors.New("xattrs not support on this os")
+
+func setXattr(_ *os.File, _, _ string) error {
+	return errXattrNotSupported
+}
@@ -0,0 +1,13 @@
+//go:build linux || darwin
+
+package tarextractor
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func setXattr(f *os.File, name, value string) error {
+	return unix.Fsetxattr(int(f.Fd()), name, []byte(value), 0) //nolint:gosec // file descriptor fit in int
+}
@@ -2,14 +2,18 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"os"
 
 	"github.com/urfave/cli/v3"
 	// "github.com/urfave/cli-docs/v3"
 
 	"github.com/librun/ha-backup-tool/internal/commands"
+	"github.com/librun/ha-backup-tool/internal/exitcode"
 	"github.com/librun/ha-backup-tool/internal/flags"
+	"github.com/librun/ha-backup-tool/internal/limits"
+	"github.com/librun/ha-backup-tool/internal/logger"
+	"github.com/librun/ha-backup-tool/internal/output"
+	"github.com/librun/ha-backup-tool/internal/progress"
 )
 
 // AppVersion displays service version in semantic versioning (http://semver.org/).
@@ -24,35 +28,124 @@ func main() {
 		EnableShellCompletion: true,
 		Version:               AppVersion,
 		Flags: []cli.Flag{
-			&cli.StringFlag{
+			&cli.StringSliceFlag{
 				Name:    flags.GlobalEmergency,
 				Aliases: []string{"e"},
-				Usage:   "Filepath for emergency text file",
+				Usage:   "Filepath for emergency text file, can be set several times",
 			},
-			&cli.StringFlag{
+			&cli.StringSliceFlag{
 				Name:    flags.GlobalPassword,
 				Aliases: []string{"p"},
-				Usage:   "Password for decrypt backup",
+				Usage:   "Password for decrypt backup, can be set several times, visible in process list, prefer other sources",
+			},
+			&cli.StringFlag{
+				Name:  flags.GlobalPasswordFile,
+				Usage: "Filepath for file with password for decrypt backup in first line",
+			},
+			&cli.StringFlag{
+				Name:  flags.GlobalKeyring,
+				Usage: "Dir with emergency kits or files with keys, all keys are tried for every archive",
+			},
+			&cli.BoolFlag{
+				Name:  flags.GlobalPasswordRaw,
+				Usage: "Use free-form password as is, without check of emergency kit key format",
+			},
+			&cli.StringFlag{
+				Name:  flags.GlobalKeyStore,
+				Usage: "Filepath for encrypted key store (default key store is used if no other key is set)",
+			},
+			&cli.BoolFlag{
+				Name:  flags.GlobalKeyCache,
+				Usage: "Save keys derived from password on disk for next runs, files of cache have key material",
+			},
+			&cli.IntFlag{
+				Name:  flags.GlobalPasswordFD,
+				Usage: "File descriptor for read password for decrypt backup",
 			},
 			&cli.StringFlag{
 				Name:  flags.GlobalMaxArchiveSize,
 				Usage: "Max size for extract archive",
 			},
+			&cli.StringFlag{
+				Name:  flags.GlobalMaxTotalSize,
+				Usage: "Max total size of extracted data for one backup",
+			},
+			&cli.Int64Flag{
+				Name:  flags.GlobalMaxEntries,
+				Value: limits.DefaultMaxEntries,
+				Usage: "Max count of extracted entries for one backup, 0 for without limit",
+			},
+			&cli.IntFlag{
+				Name:  flags.GlobalMaxPathDepth,
+				Value: limits.DefaultMaxPathDepth,
+				Usage: "Max path depth of extracted entry, 0 for without limit",
+			},
+			&cli.Int64Flag{
+				Name:  flags.GlobalMaxRatio,
+				Value: limits.DefaultMaxRatio,
+				Usage: "Max decompression ratio of archive inside backup, 0 for without limit",
+			},
+			&cli.StringFlag{
+				Name:  flags.GlobalFormat,
+				Value: output.FormatTextString,
+				Usage: "Output format: text or json (json report is written to stdout, messages to stderr)",
+			},
+			&cli.StringFlag{
+				Name:  flags.GlobalLogLevel,
+				Value: logger.LevelInfo,
+				Usage: "Log level: debug, info, warn, error",
+			},
+			&cli.StringFlag{
+				Name:  flags.GlobalLogFormat,
+				Value: logger.FormatText,
+				Usage: "Log format: text or json",
+			},
+			&cli.StringFlag{
+				Name:  flags.GlobalLogFile,
+				Usage: "Write log to file instead of stderr",
+			},
+			&cli.StringFlag{
+				Name:  flags.GlobalProgress,
+				Value: progress.ModeAutoString,
+				Usage: "Show progress: auto, bar, line or none (auto is bar on terminal and log lines otherwise)",
+			},
+			&cli.StringFlag{
+				Name:  flags.GlobalConfig,
+				Usage: "Filepath for config file with default values of flags (default config.yaml in user config dir)",
+			},
+			&cli.StringFlag{
+				Name:  flags.GlobalProfile,
+				Usage: "Name of profile in config file, values of profile are used before default values",
+			},
+			&cli.StringFlag{
+				Name:  flags.GlobalCatalog,
+				Usage: "Filepath for catalog of backups (default catalog.db in user cache dir)",
+			},
 			&cli.BoolFlag{
 				Name:  flags.GlobalVerbose,
-				Usage: "Verbose mode for output more information",
+				Usage: "Verbose mode for output more information (same as --log-level=debug)",
 			},
 		},
+		OnUsageError: commands.OnUsageError,
 		Commands: []*cli.Command{
 			commands.Extract(),
+			commands.Keygen(),
+			commands.Keys(),
+			commands.Catalog(),
+			commands.Find(),
+			commands.History(),
+			commands.Diff(),
+			commands.Prune(),
+			commands.Repack(),
 		},
 	}
 
 	// generateDocs(app)
 
 	if err := app.Run(context.Background(), os.Args); err != nil {
-		fmt.Printf("\n🛑 Running command exited with error: %s\n", err)
-		os.Exit(1)
+		f, _ := output.ParseFromString(app.String(flags.GlobalFormat))
+		output.NewPrinter(f).Printf("\n🛑 Running command exited with error: %s\n", err)
+		os.Exit(int(exitcode.Get(err)))
 	}
 }
 